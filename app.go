@@ -16,10 +16,12 @@ package main
 
 import (
 	"fmt"
+	"log"
 	"os"
 	"path/filepath"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/veandco/go-sdl2/sdl"
 )
@@ -45,9 +47,18 @@ const (
 	Submode_Change   Submode = "change"
 	Submode_FindNext Submode = "find next"
 	Submode_FindPrev Submode = "find prev"
+	Submode_Mark     Submode = "mark"
+	Submode_MarkJump Submode = "jump to mark"
+	Submode_MarkLine Submode = "jump to mark line"
+	Submode_Record   Submode = "record"
+	Submode_Play     Submode = "play"
+	Submode_Scroll   Submode = "scroll"
 	Submode_None     Submode = "none"
 )
 
+// Width of the file search, the command palette, the search and the prompt at the font size in the settings
+const popupWidth int32 = 500
+
 type App struct {
 	RegularFont14 Font
 	RegularFont12 Font
@@ -56,60 +67,141 @@ type App struct {
 	WindowRect sdl.Rect
 	Theme      Theme
 	LineHeight int32
+	FontSize   int32   // Size of the loaded fonts in pixels
+	Zoom       int32   // Points added to the font size in the settings
+	Scale      float32 // Pixels in a point of the window, more than 1 on displays with a high pixel density
 	Icon       *sdl.Surface
 
+	Settings       Settings          // In effect, the user settings with the project and buffer overrides on top
+	UserSettings   Settings          // Defaults with the settings file on top
+	SettingsPath   string            // Saving this file reloads the settings
+	BufferSettings map[string]string // Set with :set, until another file is opened
+
 	StatusBar      StatusBar
 	Buffer         Buffer
 	FileSearch     FileSearch
 	CommandPalette CommandPalette
 	Search         Search
-	Commands       map[string]func(app *App)
+	Prompt         Prompt
+	Watcher        FileWatcher
+	Swapper        Swapper
+	Commands       map[string]func(app *App, args []string)
+	Keymap         Keymap
+	CommandResults chan CommandResult // Tasks and formatters that finished in the background
 
 	Mode               Mode
 	Submode            Submode
 	AmountModifier     strings.Builder
 	Project            Project
+	Symbols            *SymbolIndex
 	Cache              Cache
+	Registers          map[byte]string
+	SearchHistory      []string
+	RecentFiles        []string // Most recent first
 	FileSearchOpen     bool
+	FileSearchIndexed  bool // The file search lists the project files and follows the index as it is built
 	CommandPaletteOpen bool
 	SearchOpen         bool
+	PromptOpen         bool
+	MacroRegister      byte // Register the macro is recorded into, 0 when not recording
 	CapsOn             bool
+	Damaged            bool // Something changed since the last frame. Frames where nothing did are not drawn
+
+	macroKeys  []Input
+	lastMacro  byte
+	macroDepth int // Macros playing right now, macros can play other macros
+
+	LastChange    []Input // Keys of the last complete change, repeated by .
+	pendingChange []Input
+	pendingEdits  int
+	changeDepth   int
+
+	heldKeys Input
+	dragging bool // The left button went down in the buffer and has not come up yet
 }
 
 // ==============================================================
 // PUBLIC FUNCTIONS
 // ==============================================================
 
-// @TODO is there a way to avoid passing a renderer here?
-func Init(renderer *sdl.Renderer, windowWidth int32, windowHeight int32) (result App) {
-	result.RegularFont14 = LoadFont("./assets/fonts/consola.ttf", 14)
-	result.RegularFont12 = LoadFont("./assets/fonts/consola.ttf", 12)
-	result.BoldFont14 = LoadFont("./assets/fonts/consolab.ttf", 14)
+// Init creates the editor for a window of the size in pixels, with scale pixels in a point of the window
+func Init(windowWidth int32, windowHeight int32, scale float32, settings Settings) (result App) {
+	result.Scale = scale
+	result.FontSize = result.fontSize(&settings)
+	regular14, regular12, bold14, ok := loadSettingsFonts(&settings, result.FontSize)
+	if !ok {
+		log.Fatalf("Could not load the fonts that come with the editor")
+	}
+	result.RegularFont14 = regular14
+	result.RegularFont12 = regular12
+	result.BoldFont14 = bold14
 
 	result.WindowRect = sdl.Rect{X: 0, Y: 0, W: windowWidth, H: windowHeight}
-	result.Theme = ParseTheme("./default_theme.atheme")
-	result.LineHeight = 18
-	result.Icon = LoadIcon("./assets/images/icon.png")
+	result.LineHeight = result.scaleToFont(settings.LineHeight, &settings)
+	result.Icon = LoadIcon(ResolveAssetPath("./assets/images/icon.png"))
 
-	result.StatusBar = CreateStatusBar(renderer, &result.WindowRect)
+	result.StatusBar = CreateStatusBar(&result.WindowRect)
 	result.Buffer = CreateBuffer(result.LineHeight, &result.RegularFont14, sdl.Rect{X: 0, Y: 0, W: windowWidth, H: windowHeight - result.StatusBar.Rect.H})
 	result.FileSearch = CreateFileSearch(result.LineHeight, &result.RegularFont14, &result.RegularFont12)
 	result.CommandPalette = CreateCommandPalette(result.LineHeight, &result.RegularFont14)
 	result.Search = CreateSearch(result.LineHeight, &result.RegularFont14)
-	result.Commands = map[string]func(app *App){}
+	result.Prompt = CreatePrompt(result.LineHeight, &result.RegularFont14)
+	result.Watcher = CreateFileWatcher()
+	result.Commands = map[string]func(app *App, args []string){}
+	result.registerCommands()
+
+	// Bindings in the user keymap replace the default ones bound to the same keys
+	result.Keymap = CreateKeymap()
+	result.Keymap.Load(ResolveAssetPath("./default_keymap.akeys"))
+	configDir, err := os.UserConfigDir()
+	if err == nil {
+		result.Keymap.Load(filepath.Join(configDir, "agurkas", "keymap.akeys"))
+	}
 
 	cacheDir, _ := os.UserCacheDir()
 	result.Cache = ParseCache(fmt.Sprintf("%s/agurkas", cacheDir))
+	result.Swapper = CreateSwapper(filepath.Join(filepath.Dir(result.Cache.Path), "swap"))
+	result.Registers = map[byte]string{}
+
+	result.Settings = settings
+	result.UserSettings = settings
+	result.SettingsPath = UserSettingsPath()
+	result.BufferSettings = map[string]string{}
+	result.setFonts(&result.RegularFont14, &result.RegularFont12, &settings)
+	result.applyBufferSettings()
+
+	themePath, themeErrors := result.loadTheme()
+	for _, err := range themeErrors {
+		log.Printf("%s %s", GetFileNameFromPath(themePath), err.Error())
+	}
 
 	result.startNormalMode()
 	result.Submode = Submode_None
+	result.Damaged = true
 
 	return
 }
 
 func (app *App) Close() {
+	app.saveSession()
+
+	// Unsaved changes stay in the swap file and will be offered for recovery on the next start
+	if app.Buffer.Dirty {
+		text := app.Buffer.GetText()
+		app.Swapper.Write(app.Buffer.Filepath, text)
+	} else {
+		app.Swapper.Remove(app.Buffer.Filepath)
+	}
+	app.Swapper.Close()
+	app.Watcher.Close()
+	app.Project.Close()
+	if app.Symbols != nil {
+		app.Symbols.Close()
+	}
 	app.RegularFont14.Unload()
+	app.RegularFont12.Unload()
 	app.BoldFont14.Unload()
+	app.StatusBar.TriangeImage.Unload()
 }
 
 func (app *App) Resized(windowWidth int32, windowHeight int32) {
@@ -118,11 +210,41 @@ func (app *App) Resized(windowWidth int32, windowHeight int32) {
 	app.Buffer.Rect.W = windowWidth
 	app.Buffer.Rect.H = windowHeight - app.StatusBar.Rect.H
 	app.StatusBar.Update(&app.WindowRect)
+	app.Damaged = true
 }
 
 func (app *App) Tick(input Input) {
 	app.CapsOn = input.CapsLock
 
+	// Modifiers and caps lock are held across frames, so only a change to them needs a new frame
+	held := Input{Ctrl: input.Ctrl, Alt: input.Alt, Shift: input.Shift, CapsLock: input.CapsLock}
+	if input.IsKey() || held != app.heldKeys {
+		app.Damaged = true
+	}
+	app.heldKeys = held
+
+	app.handleWatchEvents()
+	app.syncProjectFiles()
+	app.maybeWriteSwapFile()
+	app.handleCommandResults()
+
+	if app.Keymap.Expired(time.Now()) {
+		app.resolveKeys(true)
+		app.Damaged = true
+	}
+
+	app.handleInput(input)
+}
+
+// handleInput passes the keys to whatever has the focus. Macros are recorded and played back here, so they work everywhere
+func (app *App) handleInput(input Input) {
+	app.recordInput(input)
+
+	if app.PromptOpen {
+		app.Prompt.Tick(input)
+		return
+	}
+
 	if app.FileSearchOpen {
 		app.FileSearch.Tick(input)
 		return
@@ -138,35 +260,10 @@ func (app *App) Tick(input Input) {
 		return
 	}
 
-	if input.Ctrl {
-		if input.Alt {
-			// Save workspace
-			if input.TypedCharacter == 's' {
-				app.saveProject()
-			} else if input.TypedCharacter == 'o' {
-				app.openProject("")
-			} else if input.TypedCharacter == 'p' {
-				app.openProjectSearch()
-				app.CommandPaletteOpen = false
-			}
-
-			return
-		}
-
-		if input.TypedCharacter == 'p' && !app.FileSearchOpen {
-			app.openFileSearch()
-			app.CommandPaletteOpen = false
-		} else if input.TypedCharacter == 's' && app.Buffer.Dirty {
-			app.saveSourceFile()
-		} else if input.TypedCharacter == 'o' {
-			app.openSourceFile("")
-		} else if input.TypedCharacter == 'O' {
-			app.showFileInExplorer()
-		}
-
-		return
-	}
+	app.handleMappedInput(input)
+}
 
+func (app *App) handleModeInput(input Input) {
 	switch app.Mode {
 	case Mode_Visual:
 		fallthrough
@@ -177,10 +274,10 @@ func (app *App) Tick(input Input) {
 	}
 }
 
-func (app *App) Render(renderer *sdl.Renderer) {
-	cc := app.Theme.Buffer.BackgroundColor
-	renderer.SetDrawColor(cc.R, cc.G, cc.B, cc.A)
-	renderer.Clear()
+func (app *App) Render(renderer Canvas) {
+	app.Damaged = false
+
+	renderer.Clear(app.Theme.Buffer.BackgroundColor)
 
 	app.Buffer.Render(renderer, app.Mode, &app.Theme)
 
@@ -189,11 +286,17 @@ func (app *App) Render(renderer *sdl.Renderer) {
 	app.StatusBar.RenderSubmode(renderer, app.Submode, &app.RegularFont14, &app.Theme.StatusBar)
 	app.StatusBar.RenderProject(renderer, app.Project.Name, GetFileNameFromPath(app.Buffer.Filepath), app.Buffer.Dirty, &app.RegularFont14, &app.Theme.StatusBar)
 	app.StatusBar.RenderLineCount(renderer, fmt.Sprintf("Lines: %d", app.Buffer.TotalLines), &app.RegularFont14, &app.Theme.StatusBar)
+	app.StatusBar.RenderFileFormat(renderer, app.Buffer.Format.String(), &app.RegularFont14, &app.Theme.StatusBar)
+	if app.MacroRegister != 0 {
+		app.StatusBar.RenderMacro(renderer, fmt.Sprintf("recording @%c", app.MacroRegister), &app.RegularFont14, &app.Theme.StatusBar)
+	}
 	if app.CapsOn {
 		app.StatusBar.RenderCaps(renderer, "CAPS ON", &app.RegularFont14, &app.Theme.StatusBar)
 	}
 
-	if app.FileSearchOpen {
+	if app.PromptOpen {
+		app.Prompt.Render(renderer, &app.Buffer.Rect, &app.Theme)
+	} else if app.FileSearchOpen {
 		app.FileSearch.Render(renderer, &app.Buffer.Rect, &app.Theme.FileSearch)
 	} else if app.CommandPaletteOpen {
 		app.CommandPalette.Render(renderer, &app.Buffer.Rect, &app.Theme.FileSearch)
@@ -244,6 +347,21 @@ func (app *App) handleInputNormal(input Input) {
 		return
 	}
 
+	if app.Submode == Submode_Mark || app.Submode == Submode_MarkJump || app.Submode == Submode_MarkLine {
+		app.handleInputSubmodeMark(input)
+		return
+	}
+
+	if app.Submode == Submode_Scroll {
+		app.handleInputSubmodeScroll(input)
+		return
+	}
+
+	if app.Submode == Submode_Record || app.Submode == Submode_Play {
+		app.handleInputSubmodeMacro(input)
+		return
+	}
+
 	if input.Escape {
 		app.AmountModifier.Reset()
 		app.startNormalMode()
@@ -314,7 +432,15 @@ func (app *App) handleInputNormal(input Input) {
 		}
 	case 'x':
 		if app.Mode == Mode_Normal {
-			app.Buffer.RemoveAfter()
+			amount := 1
+			if app.AmountModifier.Len() > 0 {
+				amount, _ = strconv.Atoi(app.AmountModifier.String())
+				app.AmountModifier.Reset()
+			}
+
+			for i := 0; i < amount; i += 1 {
+				app.Buffer.RemoveAfter()
+			}
 		} else if app.Mode == Mode_Visual {
 			app.Buffer.RemoveSelection()
 			app.startNormalMode()
@@ -337,7 +463,10 @@ func (app *App) handleInputNormal(input Input) {
 		app.Buffer.MoveLeftToWordStart(true) // Ignore punctuation
 	case 'g':
 		app.Submode = Submode_Goto
+	case 'z':
+		app.Submode = Submode_Scroll
 	case 'G':
+		app.Buffer.PushJump()
 		if app.AmountModifier.Len() > 0 {
 			amount, _ := strconv.Atoi(app.AmountModifier.String())
 			app.AmountModifier.Reset()
@@ -373,11 +502,15 @@ func (app *App) handleInputNormal(input Input) {
 		}
 	case 'm':
 		if app.Mode == Mode_Normal {
-			app.Buffer.MarkCurrentPosition()
+			app.Submode = Submode_Mark
 		}
 	case '`':
 		if app.Mode == Mode_Normal {
-			app.Buffer.MoveToBookmark()
+			app.Submode = Submode_MarkJump
+		}
+	case '\'':
+		if app.Mode == Mode_Normal {
+			app.Submode = Submode_MarkLine
 		}
 	case 'f':
 		if app.Mode == Mode_Normal {
@@ -416,22 +549,39 @@ func (app *App) handleInputNormal(input Input) {
 		}
 	case 'y':
 		text := app.Buffer.GetSelectionText()
+		app.Registers['"'] = text
 		err := sdl.SetClipboardText(text)
 		checkError(err)
 
 		app.startNormalMode()
 	case 'Y':
 		text := app.Buffer.GetCurrentLineText()
+		app.Registers['"'] = text
 		err := sdl.SetClipboardText(text)
 		checkError(err)
 
 		app.startNormalMode()
 	case ':':
-		app.openCommandPalette()
+		app.openCommandPalette("")
 	case '/':
 		app.openSearch()
 	case 'n':
+		app.Buffer.PushJump()
 		app.Buffer.MoveToNextFindResult()
+	case '.':
+		if app.Mode == Mode_Normal {
+			app.repeatChange()
+		}
+	case 'q':
+		if app.MacroRegister != 0 {
+			app.stopRecording()
+		} else if app.Mode == Mode_Normal {
+			app.Submode = Submode_Record
+		}
+	case '@':
+		if app.Mode == Mode_Normal {
+			app.Submode = Submode_Play
+		}
 	}
 }
 
@@ -484,10 +634,59 @@ func (app *App) handleInputSubmodeGoto(input Input) {
 	}
 
 	if input.TypedCharacter == 'g' {
+		app.Buffer.PushJump()
 		app.Buffer.MoveToBufferStart()
 		app.Submode = Submode_None
 		return
 	}
+
+	// gj and gk move by display row, through the segments of wrapped lines
+	if input.TypedCharacter == 'j' || input.TypedCharacter == 'k' {
+		amount := 1
+		if app.AmountModifier.Len() > 0 {
+			amount, _ = strconv.Atoi(app.AmountModifier.String())
+			app.AmountModifier.Reset()
+		}
+
+		for i := 0; i < amount; i += 1 {
+			if input.TypedCharacter == 'j' {
+				app.Buffer.MoveDownDisplayLine()
+			} else {
+				app.Buffer.MoveUpDisplayLine()
+			}
+		}
+
+		app.Submode = Submode_None
+		return
+	}
+
+	if input.TypedCharacter == ';' || input.TypedCharacter == ',' {
+		var position Position
+		var ok bool
+		if input.TypedCharacter == ';' {
+			position, ok = app.Buffer.ChangeList.Older()
+		} else {
+			position, ok = app.Buffer.ChangeList.Newer()
+		}
+
+		if ok {
+			app.Buffer.MoveToPosition(position.Line, position.Column)
+		}
+		app.Submode = Submode_None
+		return
+	}
+}
+
+func (app *App) handleInputSubmodeScroll(input Input) {
+	if input.Ctrl || input.Alt {
+		return
+	}
+
+	if input.TypedCharacter == 't' || input.TypedCharacter == 'z' || input.TypedCharacter == 'b' {
+		app.Buffer.ScrollCursorTo(input.TypedCharacter)
+	}
+
+	app.Submode = Submode_None
 }
 
 func (app *App) handleInputSubmodeReplace(input Input) {
@@ -524,7 +723,14 @@ func (app *App) handleInputSubmodeDelete(input Input) {
 
 	switch input.TypedCharacter {
 	case 'd':
-		app.Buffer.RemoveCurrentLine()
+		if app.AmountModifier.Len() > 0 {
+			amount, _ := strconv.Atoi(app.AmountModifier.String())
+			app.AmountModifier.Reset()
+
+			app.Buffer.RemoveLines(Direction_Down, amount-1)
+		} else {
+			app.Buffer.RemoveCurrentLine()
+		}
 		app.Submode = Submode_None
 	case 'j':
 		amount := 1
@@ -566,13 +772,59 @@ func (app *App) handleInputSubmodeFind(input Input) {
 	}
 }
 
+func (app *App) handleInputSubmodeMark(input Input) {
+	if input.Ctrl || input.Alt {
+		return
+	}
+
+	if input.Escape {
+		app.Submode = Submode_None
+		return
+	}
+
+	if input.TypedCharacter == 0 {
+		return
+	}
+
+	submode := app.Submode
+	app.Submode = Submode_None
+
+	if submode == Submode_Mark {
+		if !app.Buffer.SetMark(input.TypedCharacter) {
+			log.Printf("Can't set mark %c", input.TypedCharacter)
+		}
+		return
+	}
+
+	app.jumpToMark(input.TypedCharacter, submode == Submode_MarkJump)
+}
+
+// jumpToMark goes to the mark, to its column when exact and to the first non blank character of its line otherwise
+func (app *App) jumpToMark(name byte, exact bool) {
+	position, ok := app.Buffer.Marks.Get(name, app.Buffer.Filepath)
+	if !ok {
+		log.Printf("Mark %c is not set", name)
+		return
+	}
+
+	app.Buffer.PushJump()
+	app.goToPosition(position)
+
+	if !exact && app.Buffer.Filepath == position.Filepath {
+		app.Buffer.MoveToFirstNonWhitespace()
+	}
+}
+
 func (app *App) createProject(dirPath string) {
-	var sb strings.Builder
-	fmt.Fprintf(&sb, "root: %s", dirPath)
+	lines := []string{
+		fmt.Sprintf("version: %d", ProjectVersion),
+		"root: .",
+		"exclude: .git",
+	}
 
 	dirName := filepath.Base(dirPath)
 
-	finalPath, saveSuccess := SaveFile(filepath.Join(dirPath, fmt.Sprintf("%s.aproject", dirName)), []string{sb.String()})
+	finalPath, saveSuccess := SaveFile(filepath.Join(dirPath, fmt.Sprintf("%s.aproject", dirName)), lines)
 	if !saveSuccess {
 		return
 	}
@@ -589,111 +841,707 @@ func (app *App) saveProject() {
 }
 
 func (app *App) openProject(path string) {
-	data, _, success := OpenFile(path)
+	data, path, success := OpenFile(path)
 	if success {
-		app.Project = ParseProject(string(data))
+		app.saveSession()
+		app.Project.Close()
+		if app.Symbols != nil {
+			app.Symbols.Close()
+		}
+
+		project, errors := ParseProject(string(data), path)
+		app.Project = project
+		app.Symbols = CreateSymbolIndex()
+		app.updateSettings()
+		app.watchProjectTree()
+
+		app.restoreSession()
+
+		if len(errors) > 0 {
+			app.reportParseErrors(path, errors)
+		}
+	}
+}
+
+// reportParseErrors logs the errors found in a file and offers to open the file at the first one
+func (app *App) reportParseErrors(path string, errors []ParseError) {
+	name := GetFileNameFromPath(path)
+	for _, err := range errors {
+		log.Printf("%s %s", name, err.Error())
+	}
+
+	message := fmt.Sprintf("%s %s", name, errors[0].Error())
+	if len(errors) > 1 {
+		message = fmt.Sprintf("%s (and %d more)", message, len(errors)-1)
+	}
+
+	// Errors that are not in a file have nothing to edit
+	choices := []PromptChoice{{Key: 'i', Label: "ignore"}}
+	if path != "" {
+		choices = append([]PromptChoice{{Key: 'e', Label: "edit file"}}, choices...)
+	}
+
+	app.PromptOpen = true
+	app.Prompt.Open(message, choices, func(choice byte) {
+		app.PromptOpen = false
+
+		if choice == 'e' && path != "" && !app.Buffer.Dirty {
+			app.openSourceFile(path)
+			app.Buffer.MoveToPosition(int32(errors[0].Line-1), 0)
+		}
+	})
+}
+
+func (app *App) sessionDir() string {
+	return filepath.Join(filepath.Dir(app.Cache.Path), "sessions")
+}
+
+func (app *App) saveSession() {
+	if app.Project.Root == "" {
+		return
+	}
+
+	session := LoadSession(app.sessionDir(), app.Project.Root)
+	session.Buffers = nil
+	if app.Buffer.Filepath != "" {
+		session.Buffers = append(session.Buffers, SessionBuffer{
+			Filepath: app.Buffer.Filepath,
+			Line:     app.Buffer.Cursor.Line,
+			Column:   app.Buffer.Cursor.Column,
+			ScrollY:  app.Buffer.ScrollY,
+			ScrollX:  app.Buffer.ScrollX,
+		})
+	}
+	session.SearchHistory = app.SearchHistory
+	session.RecentFiles = app.RecentFiles
+	session.Commands = app.CommandPalette.Input.History
+	session.Registers = app.Registers
+	session.Marks = app.Buffer.Marks.List("")
+
+	session.Save()
+}
+
+func (app *App) restoreSession() {
+	session := LoadSession(app.sessionDir(), app.Project.Root)
+
+	app.SearchHistory = session.SearchHistory
+	app.RecentFiles = session.RecentFiles
+	app.CommandPalette.Input.History = session.Commands
+	for name, value := range session.Registers {
+		app.Registers[name] = value
+	}
+
+	app.Buffer.Marks = CreateMarks()
+	for _, mark := range session.Marks {
+		app.Buffer.Marks.Set(mark.Name, mark.Position)
+	}
+
+	// Never throw away unsaved changes just to restore the previous session
+	if app.Buffer.Dirty || len(session.Buffers) == 0 {
+		return
+	}
+
+	buffer := session.Buffers[0]
+	app.openSourceFile(buffer.Filepath)
+	if app.Buffer.Filepath != buffer.Filepath {
+		return
+	}
+
+	app.Buffer.MoveToPosition(buffer.Line, buffer.Column)
+	app.Buffer.ScrollY = buffer.ScrollY
+	if app.Buffer.wrapWidth() == 0 {
+		app.Buffer.ScrollX = buffer.ScrollX
 	}
 }
 
-func (app *App) openFileSearch() {
+// openFileSearch lists the project files. Starting the query with @ lists the symbols in the buffer and with # the symbols in the project
+func (app *App) openFileSearch(query string) {
 	app.FileSearchOpen = true
-	app.FileSearch.Open(PathsToFileSearchEntries(app.Project.Files), func(path string) {
+	app.FileSearchIndexed = true
+	app.FileSearch.Indexing = !app.Project.IsIndexed()
+	app.FileSearch.Recent = app.RecentFiles
+	app.FileSearch.Symbols = app.symbolEntries
+	app.FileSearch.OpenWithQuery(PathsToFileSearchEntries(app.Project.Files, app.Project.Root), query, func(entry *FileSearchEntry) {
 		app.FileSearchOpen = false
-		if path == "" {
+		if entry == nil {
 			return
 		}
 
-		app.openSourceFile(path)
+		// Symbols in the open buffer don't need the file to be read again
+		isSymbol := entry.Detail != ""
+		if !isSymbol || entry.FullPath != app.Buffer.Filepath {
+			app.openSourceFile(entry.FullPath)
+		} else {
+			app.Buffer.PushJump()
+		}
+
+		if isSymbol && entry.FullPath == app.Buffer.Filepath {
+			app.startNormalMode()
+			app.Buffer.MoveToPosition(entry.Line, entry.Column)
+		}
 	})
 }
 
-func (app *App) openCommandPalette() {
+func (app *App) symbolEntries(scope byte) []FileSearchEntry {
+	if scope == '@' {
+		text := app.Buffer.GetText()
+		symbols := ExtractSymbols(app.Buffer.Filepath, []byte(strings.Join(text, "\n")))
+
+		return SymbolsToFileSearchEntries(symbols, app.Project.Root)
+	}
+
+	if app.Symbols == nil {
+		return nil
+	}
+
+	return SymbolsToFileSearchEntries(app.Symbols.Symbols(), app.Project.Root)
+}
+
+// openCommandPalette opens the palette with the text already typed, like the name of a command
+func (app *App) openCommandPalette(text string) {
 	app.CommandPaletteOpen = true
-	app.CommandPalette.Open(func(command string) {
+	app.CommandPalette.OpenWithText(text, func(command string) {
 		app.CommandPaletteOpen = false
-		if command == "" {
+		app.runCommand(command)
+	})
+}
+
+// runCommand runs a command the same as if it was typed into the command palette
+func (app *App) runCommand(command string) {
+	args := strings.Fields(command)
+	if len(args) == 0 {
+		return
+	}
+
+	com := app.Commands[args[0]]
+	if com != nil {
+		com(app, args[1:])
+	} else {
+		log.Printf("Unknown command: %s", args[0])
+	}
+}
+
+func (app *App) openProjectSearch() {
+	app.FileSearchOpen = true
+	app.FileSearchIndexed = false
+	app.FileSearch.Indexing = false
+	app.FileSearch.Recent = nil
+	app.FileSearch.Symbols = nil
+	app.FileSearch.Open(PathsToFileSearchEntries(app.Cache.Projects, ""), func(entry *FileSearchEntry) {
+		app.FileSearchOpen = false
+		if entry == nil {
 			return
 		}
 
-		com := app.Commands[command]
-		if com != nil {
-			com(app)
-		}
+		app.openProject(entry.FullPath)
 	})
 }
 
-func (app *App) openProjectSearch() {
+func (app *App) openThemeSearch() {
 	app.FileSearchOpen = true
-	app.FileSearch.Open(PathsToFileSearchEntries(app.Cache.Projects), func(path string) {
+	app.FileSearchIndexed = false
+	app.FileSearch.Indexing = false
+	app.FileSearch.Recent = nil
+	app.FileSearch.Symbols = nil
+	app.FileSearch.Open(ThemeEntries(), func(entry *FileSearchEntry) {
 		app.FileSearchOpen = false
-		if path == "" {
+		if entry == nil {
 			return
 		}
 
-		app.openProject(path)
+		app.setTheme(entry.Name)
 	})
 }
 
 func (app *App) openSearch() {
 	app.SearchOpen = true
+	app.Search.Input.History = app.SearchHistory
 	app.Search.Open(func(value string) {
 		app.SearchOpen = false
 		if value == "" {
 			return
 		}
 
+		app.addSearchHistory(value)
+
+		app.Buffer.PushJump()
 		app.Buffer.Find(value)
 	})
 }
 
+func (app *App) addSearchHistory(query string) {
+	history := []string{}
+	for _, item := range app.SearchHistory {
+		if item != query {
+			history = append(history, item)
+		}
+	}
+
+	history = append(history, query)
+	if len(history) > 50 {
+		history = history[len(history)-50:]
+	}
+
+	app.SearchHistory = history
+}
+
+func (app *App) addRecentFile(path string) {
+	recent := []string{path}
+	for _, item := range app.RecentFiles {
+		if item != path {
+			recent = append(recent, item)
+		}
+	}
+
+	if len(recent) > 50 {
+		recent = recent[:50]
+	}
+
+	app.RecentFiles = recent
+}
+
 func (app *App) saveSourceFile() {
-	text, _ := app.Buffer.GetText()
-	filepath, success := SaveFile(app.Buffer.Filepath, text)
+	if app.Buffer.Filepath != "" && FileChangedOnDisk(app.Buffer.Filepath, app.Buffer.Stamp) {
+		message := fmt.Sprintf("%s has changed on disk since it was opened. Overwrite it?", GetFileNameFromPath(app.Buffer.Filepath))
+		if !ConfirmDialog("File changed", message) {
+			return
+		}
+	}
+
+	if app.Settings.Backup && app.Buffer.Filepath != "" {
+		if !BackupFile(app.Buffer.Filepath, filepath.Join(filepath.Dir(app.Cache.Path), "backups")) {
+			return
+		}
+	}
+
+	text := app.Buffer.GetText()
+	if r, found := FindUnencodable(app.Buffer.Format, text); found {
+		message := fmt.Sprintf("%s can't store %q and characters like it, they will be saved as '?'. Save anyway?", app.Buffer.Format.Encoding, r)
+		if !ConfirmDialog("Unsupported characters", message) {
+			return
+		}
+	}
+
+	if app.Buffer.Format.Invalid {
+		message := fmt.Sprintf("%s had bytes that are not valid %s. They were replaced when it was opened and will be lost. Save anyway?", GetFileNameFromPath(app.Buffer.Filepath), app.Buffer.Format.Encoding)
+		if !ConfirmDialog("Invalid characters", message) {
+			return
+		}
+	}
+
+	data := EncodeFileData(app.Buffer.Format, text)
+	path, success := WriteFile(app.Buffer.Filepath, data)
 	if success {
-		app.Buffer.Filepath = filepath
+		app.Swapper.Remove(app.Buffer.Filepath)
+		if path != app.Buffer.Filepath {
+			app.Watcher.UnwatchFile(app.Buffer.Filepath)
+			app.Watcher.WatchFile(path)
+			app.Swapper.Remove(path)
+		}
+
+		app.Buffer.Filepath = path
+		app.Buffer.Stamp = CreateFileStamp(path, data)
+		app.Buffer.BaseLines = text
 		app.Buffer.Dirty = false
+		app.Buffer.Format.Invalid = false
+
+		app.runFormatter()
+
+		if path == app.SettingsPath {
+			app.reloadSettings()
+		}
+		if app.isThemeFile(path) {
+			app.reloadTheme()
+		}
+
+		if app.Symbols != nil && app.Project.Contains(path) {
+			text := app.Buffer.GetText()
+			app.Symbols.Set(path, []byte(strings.Join(text, "\n")))
+		}
+	}
+}
+
+// runFormatter starts the project's formatter on the saved file. The result is picked up when it exits
+func (app *App) runFormatter() {
+	command, ok := SplitCommandLine(app.Project.Formatter)
+	if !ok {
+		log.Printf("The formatter has an unclosed quote: %s", app.Project.Formatter)
+		return
+	}
+
+	if len(command) == 0 || !app.Project.Contains(app.Buffer.Filepath) {
+		return
+	}
+
+	app.startCommand("Formatter", app.Buffer.Filepath, append(command, app.Buffer.Filepath))
+}
+
+func (app *App) startCommand(label string, path string, command []string) {
+	if app.CommandResults == nil {
+		app.CommandResults = make(chan CommandResult, 16)
+	}
+
+	StartCommand(app.CommandResults, label, path, app.Project.Root, command)
+}
+
+// handleCommandResults reports the commands that finished. Results wait while a prompt is open, so they don't replace it
+func (app *App) handleCommandResults() {
+	for !app.PromptOpen {
+		select {
+		case result := <-app.CommandResults:
+			app.Damaged = true
+			app.reportCommandResult(result)
+		default:
+			return
+		}
+	}
+}
+
+func (app *App) reportCommandResult(result CommandResult) {
+	output := strings.Split(strings.TrimRight(result.Output, "\n"), "\n")
+	if result.Output == "" {
+		output = nil
+	}
+
+	if result.Err != nil {
+		var preview []DiffOp
+		for _, line := range output[Max(len(output)-15, 0):] {
+			preview = append(preview, DiffOp{Type: DiffOp_Equal, Line: line})
+		}
+
+		app.PromptOpen = true
+		app.Prompt.Open(fmt.Sprintf("%s failed: %s", result.Label, result.Err), []PromptChoice{{Key: 'o', Label: "ok"}}, func(choice byte) {
+			app.PromptOpen = false
+		})
+		app.Prompt.Preview = preview
+		return
+	}
+
+	log.Printf("%s finished", result.Label)
+	for _, line := range output {
+		log.Printf("%s", line)
+	}
+
+	// The formatted file is only reloaded when that doesn't throw away changes made while the formatter was running
+	if result.Path != "" && result.Path == app.Buffer.Filepath && !app.Buffer.Dirty && FileChangedOnDisk(result.Path, app.Buffer.Stamp) {
+		app.reloadSourceFile(false)
 	}
 }
 
 func (app *App) openSourceFile(path string) {
 	data, filepath, success := OpenFile(path)
 	if success {
-		app.Mode = Mode_Normal
-		app.Buffer.SetData(data, filepath)
+		if app.Buffer.Filepath != "" {
+			app.Buffer.PushJump()
+		}
+
+		app.showSourceFile(data, filepath)
+	}
+}
+
+func (app *App) showSourceFile(data []byte, filepath string) {
+	app.Watcher.UnwatchFile(app.Buffer.Filepath)
+	app.Watcher.WatchFile(filepath)
+
+	app.Mode = Mode_Normal
+	app.Buffer.SetData(data, filepath)
+	app.Buffer.Stamp = CreateFileStamp(filepath, data)
+	app.addRecentFile(filepath)
+
+	// Settings changed with :set belong to the file they were set for
+	if len(app.BufferSettings) > 0 {
+		app.BufferSettings = map[string]string{}
+		app.updateSettings()
 	}
 }
 
+func (app *App) jumpBack() {
+	position, ok := app.Buffer.JumpList.Back(app.Buffer.CurrentPosition())
+	if ok {
+		app.goToPosition(position)
+	}
+}
+
+func (app *App) jumpForward() {
+	position, ok := app.Buffer.JumpList.Forward()
+	if ok {
+		app.goToPosition(position)
+	}
+}
+
+// goToPosition moves through the jump list, so unlike openSourceFile it doesn't add a jump of its own
+func (app *App) goToPosition(position Position) {
+	if position.Filepath != app.Buffer.Filepath {
+		if position.Filepath == "" {
+			log.Printf("The position is in a buffer that was never saved")
+			return
+		}
+
+		if app.Buffer.Dirty {
+			log.Printf("%s has unsaved changes", GetFileNameFromPath(app.Buffer.Filepath))
+			return
+		}
+
+		data, filepath, success := OpenFile(position.Filepath)
+		if !success {
+			return
+		}
+
+		app.showSourceFile(data, filepath)
+	}
+
+	app.startNormalMode()
+	app.Buffer.MoveToPosition(position.Line, position.Column)
+}
+
+func (app *App) handleWatchEvents() {
+	indexChanged := false
+	bufferChanged := false
+
+	pending := true
+	for pending {
+		select {
+		case event := <-app.Watcher.Events():
+			app.Damaged = true
+			if event.Type == WatchEvent_Overflow {
+				bufferChanged = app.Buffer.Filepath != ""
+				indexChanged = true
+				continue
+			}
+
+			if event.Path == app.Buffer.Filepath && event.Type != WatchEvent_Removed {
+				bufferChanged = true
+			}
+
+			if app.Project.Index != nil && app.Project.Contains(event.Path) {
+				if IsIgnoreFile(event.Path) {
+					indexChanged = true
+				} else if event.Type != WatchEvent_Modified {
+					app.Project.Index.Update(event.Path)
+				} else if app.Symbols != nil {
+					app.Symbols.Update(event.Path)
+				}
+			}
+		default:
+			pending = false
+		}
+	}
+
+	// Changed ignore rules can affect any file and lost events can hide changes to any file, so the whole index is built again
+	if indexChanged && app.Project.Index != nil {
+		app.Project.Refresh()
+		app.watchProjectTree()
+	}
+
+	if bufferChanged && !app.PromptOpen {
+		app.handleBufferChangedOnDisk()
+	}
+}
+
+func (app *App) watchProjectTree() {
+	app.Watcher.WatchTree(app.Project.Root, app.Project.ExcludeFilter())
+}
+
+// syncProjectFiles picks up the files and symbols indexed in the background and shows them in the open file search
+func (app *App) syncProjectFiles() {
+	filesChanged := app.Project.Sync()
+	if filesChanged && app.Symbols != nil {
+		app.Symbols.Sync(app.Project.Files)
+	}
+
+	symbolsChanged := app.Symbols != nil && app.Symbols.Changed()
+	if !filesChanged && !symbolsChanged {
+		return
+	}
+	app.Damaged = true
+
+	if app.FileSearchOpen && app.FileSearchIndexed {
+		app.FileSearch.Indexing = !app.Project.IsIndexed()
+		app.FileSearch.SetEntries(PathsToFileSearchEntries(app.Project.Files, app.Project.Root))
+	}
+}
+
+func (app *App) handleBufferChangedOnDisk() {
+	if !FileChangedOnDisk(app.Buffer.Filepath, app.Buffer.Stamp) {
+		return
+	}
+
+	if !app.Buffer.Dirty {
+		app.reloadSourceFile(false)
+		return
+	}
+
+	message := fmt.Sprintf("%s has changed on disk, but has unsaved changes", GetFileNameFromPath(app.Buffer.Filepath))
+	choices := []PromptChoice{{Key: 'm', Label: "merge"}, {Key: 'k', Label: "keep mine"}, {Key: 'r', Label: "reload"}}
+
+	app.PromptOpen = true
+	app.Prompt.Open(message, choices, func(choice byte) {
+		app.PromptOpen = false
+
+		switch choice {
+		case 'm':
+			app.reloadSourceFile(true)
+		case 'r':
+			app.reloadSourceFile(false)
+		case 'k':
+			// Saving will overwrite the file without asking again
+			data, _, success := OpenFile(app.Buffer.Filepath)
+			if success {
+				app.Buffer.Stamp = CreateFileStamp(app.Buffer.Filepath, data)
+			}
+		}
+	})
+}
+
+func (app *App) maybeWriteSwapFile() {
+	if !app.Buffer.Dirty || time.Since(app.Swapper.LastSwap) < 4*time.Second {
+		return
+	}
+
+	text := app.Buffer.GetText()
+	app.Swapper.Write(app.Buffer.Filepath, text)
+}
+
+// RecoverSwapFiles offers to restore the unsaved changes of editors that did not exit cleanly, one file at a time
+func (app *App) RecoverSwapFiles() {
+	swaps := FindOrphanedSwapFiles(app.Swapper.Dir)
+	app.recoverNextSwapFile(swaps)
+}
+
+func (app *App) recoverNextSwapFile(swaps []SwapFile) {
+	if len(swaps) == 0 {
+		return
+	}
+
+	swap := swaps[0]
+	remaining := swaps[1:]
+
+	name := GetFileNameFromPath(swap.Filepath)
+	if name == "" {
+		name = "[untitled]"
+	}
+
+	var diskLines []string
+	if swap.Filepath != "" {
+		data, _, success := OpenFile(swap.Filepath)
+		if success {
+			_, decoded := DecodeFileData(data)
+			diskLines = strings.Split(string(cleanText(decoded)), "\n")
+		}
+	}
+
+	message := fmt.Sprintf("Found unsaved changes to %s from %s", name, swap.Time.Format("2006-01-02 15:04"))
+	choices := []PromptChoice{{Key: 'r', Label: "recover"}, {Key: 'd', Label: "discard"}, {Key: 'l', Label: "later"}}
+
+	app.PromptOpen = true
+	app.Prompt.Open(message, choices, func(choice byte) {
+		app.PromptOpen = false
+
+		switch choice {
+		case 'r':
+			app.recoverSwapFile(swap, diskLines)
+			remaining = nil // Only one buffer can be open, the rest will be offered again next time
+		case 'd':
+			swap.Discard()
+		}
+
+		app.recoverNextSwapFile(remaining)
+	})
+	app.Prompt.Preview = DiffPreview(DiffLines(diskLines, swap.Lines), 1, 15)
+}
+
+func (app *App) recoverSwapFile(swap SwapFile, diskLines []string) {
+	if swap.Filepath != "" {
+		app.openSourceFile(swap.Filepath)
+	}
+
+	format := CreateFileFormat()
+	if app.Buffer.Filepath == swap.Filepath {
+		format = app.Buffer.Format
+	}
+
+	app.Buffer.SetData(EncodeFileData(format, swap.Lines), swap.Filepath)
+	app.Buffer.BaseLines = diskLines
+	app.Buffer.Dirty = true
+
+	// The recovered text now lives in this editor's swap file
+	swap.Discard()
+	app.Swapper.Write(app.Buffer.Filepath, swap.Lines)
+}
+
+// reloadSourceFile reads the file from disk again while keeping the cursor where it was. If merge is set, unsaved changes are merged into the new contents
+func (app *App) reloadSourceFile(merge bool) {
+	data, path, success := OpenFile(app.Buffer.Filepath)
+	if !success {
+		return
+	}
+
+	line := app.Buffer.Cursor.Line
+	column := app.Buffer.Cursor.Column
+	scroll := app.Buffer.ScrollY
+	changes := app.Buffer.ChangeList
+
+	stamp := CreateFileStamp(path, data)
+
+	if merge {
+		ours := app.Buffer.GetText()
+		format, decoded := DecodeFileData(data)
+		theirs := strings.Split(string(cleanText(decoded)), "\n")
+
+		merged, conflict := Merge3(app.Buffer.BaseLines, ours, theirs)
+		if conflict {
+			log.Printf("Conflicting changes in %s, look for the conflict markers", path)
+		}
+
+		app.Buffer.SetData(EncodeFileData(format, merged), path)
+		app.Buffer.BaseLines = theirs
+		app.Buffer.Dirty = true
+	} else {
+		app.Buffer.SetData(data, path)
+	}
+
+	app.Buffer.Stamp = stamp
+	app.Buffer.ChangeList = changes
+	app.Buffer.ScrollY = scroll
+	app.Buffer.MoveToPosition(line, column)
+	app.startNormalMode()
+}
+
 func (app *App) showFileInExplorer() {
 	RunCommand("explorer", "", "/select,", app.Buffer.Filepath)
 }
 
 func (app *App) startInsertMode() {
 	app.Mode = Mode_Insert
-	if app.Theme.Buffer.CursorColorMatchModeColor {
-		app.Buffer.Cursor.Color = app.Theme.StatusBar.InsertColor
-	} else {
-		app.Buffer.Cursor.Color = app.Theme.Buffer.CursorColor
-	}
+	app.updateCursorColor()
 }
 
 func (app *App) startNormalMode() {
 	app.Mode = Mode_Normal
-	if app.Theme.Buffer.CursorColorMatchModeColor {
-		app.Buffer.Cursor.Color = app.Theme.StatusBar.NormalColor
-	} else {
-		app.Buffer.Cursor.Color = app.Theme.Buffer.CursorColor
-	}
+	app.updateCursorColor()
 
 	app.Buffer.StopSelection()
 }
 
 func (app *App) startVisualMode() {
 	app.Mode = Mode_Visual
-	if app.Theme.Buffer.CursorColorMatchModeColor {
-		app.Buffer.Cursor.Color = app.Theme.StatusBar.VisualColor
-	} else {
+	app.updateCursorColor()
+
+	app.Buffer.StartSelection()
+}
+
+func (app *App) updateCursorColor() {
+	if !app.Theme.Buffer.CursorColorMatchModeColor {
 		app.Buffer.Cursor.Color = app.Theme.Buffer.CursorColor
+		return
 	}
 
-	app.Buffer.StartSelection()
+	switch app.Mode {
+	case Mode_Insert:
+		app.Buffer.Cursor.Color = app.Theme.StatusBar.InsertColor
+	case Mode_Visual, Mode_VisualLine:
+		app.Buffer.Cursor.Color = app.Theme.StatusBar.VisualColor
+	default:
+		app.Buffer.Cursor.Color = app.Theme.StatusBar.NormalColor
+	}
 }
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// Assets ship next to the executable. Paths that are not found there are taken from the working directory, which is
+// where they are when the editor is started with go run
+
+// ResolveAssetPath returns where the file or folder is. Absolute paths and files that are found nowhere are returned as
+// they are
+func ResolveAssetPath(path string) string {
+	if path == "" || filepath.IsAbs(path) {
+		return path
+	}
+
+	executable, err := os.Executable()
+	if err == nil {
+		resolved := filepath.Join(filepath.Dir(executable), path)
+		if _, err := os.Stat(resolved); err == nil {
+			return resolved
+		}
+	}
+
+	return path
+}
+
+// FindFont returns the path of the first font in the list that is found. Fonts are separated by commas, and every font
+// is either a path to a .ttf or .otf file or the name of a font installed on the system, like "Cascadia Mono"
+func FindFont(fonts string) (string, bool) {
+	for _, font := range strings.Split(fonts, ",") {
+		font = strings.TrimSpace(font)
+		if font == "" {
+			continue
+		}
+
+		if isFontPath(font) {
+			path := ResolveAssetPath(font)
+			if fileExists(path) {
+				return path, true
+			}
+			continue
+		}
+
+		path, ok := findSystemFont(font)
+		if ok {
+			return path, true
+		}
+	}
+
+	return "", false
+}
+
+func isFontPath(font string) bool {
+	extension := strings.ToLower(filepath.Ext(font))
+	return extension == ".ttf" || extension == ".otf" || strings.ContainsAny(font, "/\\")
+}
+
+// errFontFound stops the walk through the font folders
+var errFontFound = errors.New("font found")
+
+// systemFonts remembers the fonts findSystemFont looked for, so the font folders are walked once per name and not on
+// every change of the settings. Fonts that were not found are remembered too, they show up after a restart
+var (
+	systemFonts      = map[string]string{}
+	systemFontsMutex sync.Mutex
+)
+
+// findSystemFont looks for a font file named like the font. Spaces, dashes and case don't matter, so "Cascadia Mono"
+// finds CascadiaMono.ttf
+func findSystemFont(name string) (string, bool) {
+	wanted := normalizeFontName(name)
+
+	systemFontsMutex.Lock()
+	defer systemFontsMutex.Unlock()
+
+	path, ok := systemFonts[wanted]
+	if !ok || (path != "" && !fileExists(path)) {
+		path = walkFontDirectories(wanted)
+		systemFonts[wanted] = path
+	}
+
+	return path, path != ""
+}
+
+func walkFontDirectories(wanted string) string {
+	for _, dir := range systemFontDirectories() {
+		found := ""
+		filepath.WalkDir(dir, func(path string, entry os.DirEntry, err error) error {
+			if err != nil {
+				return nil // Folders that can't be read are skipped
+			}
+
+			if !entry.IsDir() && isFontPath(entry.Name()) {
+				fileName := strings.TrimSuffix(entry.Name(), filepath.Ext(entry.Name()))
+				if normalizeFontName(fileName) == wanted {
+					found = path
+					return errFontFound
+				}
+			}
+
+			return nil
+		})
+
+		if found != "" {
+			return found
+		}
+	}
+
+	return ""
+}
+
+func normalizeFontName(name string) string {
+	return strings.ToLower(strings.NewReplacer(" ", "", "-", "", "_", "").Replace(name))
+}
+
+func systemFontDirectories() (result []string) {
+	home, _ := os.UserHomeDir()
+
+	switch runtime.GOOS {
+	case "windows":
+		result = append(result, filepath.Join(os.Getenv("WINDIR"), "Fonts"))
+		if localAppData := os.Getenv("LOCALAPPDATA"); localAppData != "" {
+			result = append(result, filepath.Join(localAppData, "Microsoft", "Windows", "Fonts"))
+		}
+	case "darwin":
+		result = append(result, "/System/Library/Fonts", "/Library/Fonts", filepath.Join(home, "Library", "Fonts"))
+	default:
+		result = append(result, "/usr/share/fonts", "/usr/local/share/fonts", filepath.Join(home, ".local", "share", "fonts"), filepath.Join(home, ".fonts"))
+	}
+
+	return
+}
+
+func fileExists(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && !info.IsDir()
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestResolveAssetPath(t *testing.T) {
+	absolute := filepath.Join(t.TempDir(), "font.ttf")
+	FailIfFalse(ResolveAssetPath(absolute) == absolute, "Absolute paths should not change", t)
+	FailIfFalse(ResolveAssetPath("./missing.png") == "./missing.png", "Files found nowhere should not change", t)
+	FailIfFalse(fileExists(ResolveAssetPath(bundledFont)), "Expected the bundled font to be found", t)
+}
+
+func TestFindFont(t *testing.T) {
+	dir := t.TempDir()
+	font := filepath.Join(dir, "Mono.otf")
+	FailNowIfFalse(os.WriteFile(font, []byte{}, 0644) == nil, "Could not write the font", t)
+
+	tests := []struct {
+		Name  string
+		Fonts string
+		Path  string
+		Found bool
+	}{
+		{"Path", font, font, true},
+		{"First font that is found", filepath.Join(dir, "missing.ttf") + ", " + font, font, true},
+		{"Bundled font", "No Such Font Anywhere," + bundledFont, bundledFont, true},
+		{"Nothing found", "No Such Font Anywhere, ./missing.ttf", "", false},
+		{"Empty list", " , ", "", false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			path, found := FindFont(test.Fonts)
+			FailIfFalse(found == test.Found && path == test.Path, fmt.Sprintf("Expected %q %v, got %q %v", test.Path, test.Found, path, found), t)
+		})
+	}
+
+	t.Run("Names and paths", func(t *testing.T) {
+		FailIfFalse(!isFontPath("Cascadia Mono"), "A name is not a path", t)
+		FailIfFalse(isFontPath("mono.TTF") && isFontPath("fonts/mono"), "Expected paths", t)
+		FailIfFalse(normalizeFontName("Cascadia Mono-Bold") == normalizeFontName("cascadiamono_bold"), "Expected the names to match", t)
+	})
+	t.Run("System fonts are looked up once", func(t *testing.T) {
+		if runtime.GOOS != "linux" {
+			t.Skip("The font folder in the home directory is only known on linux")
+		}
+
+		home := t.TempDir()
+		t.Setenv("HOME", home)
+		fonts := filepath.Join(home, ".fonts")
+		FailNowIfFalse(os.MkdirAll(fonts, 0755) == nil, "Could not create the font folder", t)
+
+		installed := filepath.Join(fonts, "AgurkasTestMono.ttf")
+		FailNowIfFalse(os.WriteFile(installed, []byte{}, 0644) == nil, "Could not write the font", t)
+		path, found := FindFont("Agurkas Test Mono")
+		FailIfFalse(found && path == installed, fmt.Sprintf("Expected %q, got %q", installed, path), t)
+
+		late := filepath.Join(fonts, "AgurkasTestLate.ttf")
+		_, found = FindFont("Agurkas Test Late")
+		FailIfFalse(!found, "Expected the font to be missing", t)
+		FailNowIfFalse(os.WriteFile(late, []byte{}, 0644) == nil, "Could not write the font", t)
+		_, found = FindFont("Agurkas Test Late")
+		FailIfFalse(!found, "Expected the missing font to be remembered", t)
+
+		moved := filepath.Join(fonts, "agurkas-test-mono.otf")
+		FailNowIfFalse(os.Rename(installed, moved) == nil, "Could not move the font", t)
+		path, found = FindFont("Agurkas Test Mono")
+		FailIfFalse(found && path == moved, fmt.Sprintf("Expected a font that is gone to be looked up again, got %q", path), t)
+	})
+}
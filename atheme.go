@@ -13,6 +13,7 @@ func HighlightLineTheme(line []byte, theme *SyntaxTheme) (result []TokenInfo) {
 			result = append(result, TokenInfo{Value: " "})
 			index += 1
 		} else if symbol == '#' {
+			start := index
 			for index < len(line) {
 				sb.WriteByte(symbol)
 				index += 1
@@ -25,9 +26,12 @@ func HighlightLineTheme(line []byte, theme *SyntaxTheme) (result []TokenInfo) {
 			value := sb.String()
 			sb.Reset()
 
+			// Colors are shown in their own color, a # at the start of the line starts a comment
 			color := theme.BaseColor
-			if len(value) == 7 {
-				color = hexStringToColor(value)
+			if parsed, ok := ParseColor(value); ok {
+				color = parsed
+			} else if strings.TrimSpace(string(line[:start])) == "" {
+				color = theme.CommentColor
 			}
 
 			result = append(result, TokenInfo{Value: value, Color: color})
@@ -46,7 +50,7 @@ func HighlightLineTheme(line []byte, theme *SyntaxTheme) (result []TokenInfo) {
 			sb.Reset()
 
 			color := theme.BaseColor
-			if value == "true" || value == "false" {
+			if value == "true" || value == "false" || value == "base" {
 				color = theme.KeywordColor
 			}
 
@@ -21,6 +21,8 @@ type Selection struct {
 	End   int32
 }
 
+const bufferGutterWidth int32 = 48 // Default width of the gutter, the settings can change it
+
 type CursorPoint struct {
 	Column      int32
 	Line        int32
@@ -32,22 +34,38 @@ type Buffer struct {
 	Data                []byte
 	GapStart            int
 	GapEnd              int
+	Lines               LineIndex
+	Rows                WrapRows
 	SelectionStartPoint CursorPoint
 	FindResults         []CursorPoint
 	TotalLines          int
 
 	Font *Font
 
-	Cursor       BufferCursor
-	Rect         sdl.Rect
-	ScrollY      int32
-	ScrollOffset int32
-	Dirty        bool
+	Cursor           BufferCursor
+	Rect             sdl.Rect
+	GutterWidth      int32
+	ScrollY          int32
+	ScrollX          int32
+	ScrollOffset     int32 // Rows kept between the cursor and the top and bottom of the view
+	SideScrollOffset int32 // Columns kept between the cursor and the sides of the view
+	IndentWidth      int32
+	Wrap             bool
+	WrapColumn       int32 // Lines are wrapped at the window width when this is 0
+	Dirty            bool
+	EditCount        int // Goes up with every edit, for telling whether a command changed the text
 
-	BookmarkLine  int32
 	LineFindQuery byte
 
+	// The buffer is also the window, so the jump list and the marks are kept when another file is opened. The change list belongs to the text
+	JumpList   PositionList
+	ChangeList PositionList
+	Marks      Marks
+
 	Filepath        string
+	Format          FileFormat
+	Stamp           FileStamp
+	BaseLines       []string // Text as it was when last read from or written to disk
 	HighlighterFunc func(line []byte, theme *SyntaxTheme) []TokenInfo
 }
 
@@ -55,21 +73,25 @@ func CreateBuffer(lineHeight int32, font *Font, rect sdl.Rect) (result Buffer) {
 	result.Data = make([]byte, 16)
 	result.GapStart = 0
 	result.GapEnd = 15
+	result.Lines = CreateLineIndex(result.Data, result.GapEnd)
 	result.SelectionStartPoint = CursorPoint{Column: -1, Line: -1, OffsetLeft: 0, OffsetRight: 0}
 	result.TotalLines = 1
 
-	result.Font = font
-
 	result.Cursor = CreateBufferCursor(lineHeight, int32(font.CharacterWidth))
+	result.SetFont(lineHeight, font)
 	result.Rect = rect
+	result.GutterWidth = bufferGutterWidth
 	result.ScrollY = 0
-	result.ScrollOffset = 8 // Line count
+	result.ScrollOffset = 8
+	result.SideScrollOffset = 5
+	result.IndentWidth = 4
 	result.Dirty = false
 
-	result.BookmarkLine = 0
+	result.Marks = CreateMarks()
 	result.LineFindQuery = 0
 
 	result.Filepath = ""
+	result.Format = CreateFileFormat()
 	result.HighlighterFunc = nil
 
 	return
@@ -79,25 +101,38 @@ func CreateBuffer(lineHeight int32, font *Font, rect sdl.Rect) (result Buffer) {
 // PUBLIC
 // =============================================================
 
+// SetFont changes the font and the height of the rows. The view stays on the same first row
+func (buffer *Buffer) SetFont(lineHeight int32, font *Font) {
+	firstRow := buffer.firstRow()
+
+	buffer.Font = font
+	buffer.Cursor.Height = lineHeight
+	buffer.Cursor.Advance = int32(font.CharacterWidth)
+	buffer.ScrollY = -firstRow * lineHeight
+}
+
 func (buffer *Buffer) SetData(data []byte, filepath string) {
-	cleaned := cleanText(data)
+	format, decoded := DecodeFileData(data)
+	cleaned := cleanText(decoded)
 
 	buffer.Data = make([]byte, len(cleaned)+16) // 16 symbols for the gap
 	buffer.GapStart = 0
 	buffer.GapEnd = 15
-	buffer.BookmarkLine = 0
+	buffer.ChangeList = PositionList{}
 	buffer.Filepath = filepath
+	buffer.Format = format
 	buffer.Dirty = false
 	buffer.Cursor.Column = 0
 	buffer.Cursor.Line = 0
 	buffer.ScrollY = 0
+	buffer.ScrollX = 0
 
-	for i := 16; i < len(buffer.Data); i += 1 {
-		buffer.Data[i] = cleaned[i-16]
-	}
+	copy(buffer.Data[16:], cleaned)
+	buffer.Lines = CreateLineIndex(buffer.Data, buffer.GapEnd)
+	buffer.Rows = WrapRows{}
 
-	text, _ := buffer.GetText()
-	buffer.TotalLines = len(text)
+	buffer.TotalLines = buffer.Lines.Count()
+	buffer.BaseLines = buffer.GetText()
 
 	buffer.HighlighterFunc = nil
 	if strings.HasSuffix(buffer.Filepath, ".go") {
@@ -126,10 +161,8 @@ func (buffer *Buffer) StopSelection() {
 func (buffer *Buffer) Find(phrase string) {
 	buffer.FindResults = make([]CursorPoint, 0)
 
-	text, _ := buffer.GetText()
-
-	for index, line := range text {
-		columns := findSubstrAll(line, phrase)
+	for index := 0; index < buffer.TotalLines; index += 1 {
+		columns := findSubstrAll(buffer.LineText(int32(index)), phrase)
 
 		if len(columns) > 0 {
 			for _, column := range columns {
@@ -145,9 +178,11 @@ func (buffer *Buffer) Insert(char byte) {
 	prevChar := buffer.prevCharacter()
 	nextChar := buffer.nextCharacter()
 
+	buffer.recordChange()
+
 	if char == '\t' {
 		// @TODO (!important) write tests for this
-		count := 4 - buffer.Cursor.Column%4
+		count := buffer.IndentWidth - buffer.Cursor.Column%buffer.IndentWidth
 		// @TODO (!important) temporary, should correctly handle tabs
 		for i := 0; i < int(count); i += 1 {
 			buffer.Insert(' ')
@@ -160,22 +195,26 @@ func (buffer *Buffer) Insert(char byte) {
 		buffer.Cursor.Column += 1
 
 		if char == '\n' {
+			buffer.Lines.PushBefore(buffer.GapStart)
 			buffer.Cursor.Column = 0
 			buffer.Cursor.Line += 1
 			buffer.TotalLines += 1
+			buffer.shiftLines(buffer.Cursor.Line, 1)
 
 			pair := getSymbolPair(prevChar)
 			if pair != 0 && pair != '"' && pair != '\'' && nextChar == pair {
 				buffer.Data[buffer.GapStart] = char
 				buffer.GapStart += 1
+				buffer.Lines.PushBefore(buffer.GapStart)
 
 				buffer.Cursor.Line += 1
 				buffer.TotalLines += 1
+				buffer.shiftLines(buffer.Cursor.Line, 1)
 
 				buffer.MoveUp()
 			}
 
-			buffer.maybeScrollDown()
+			buffer.ScrollToCursor()
 		} else {
 			pair := getSymbolPair(char)
 			if pair != 0 {
@@ -194,10 +233,13 @@ func (buffer *Buffer) Insert(char byte) {
 
 // @TODO (!important) write tests for this
 func (buffer *Buffer) ReplaceCurrentCharacter(char byte) {
-	if char == '\n' {
+	// New lines are not replaced either, the line index would have to change with them
+	next := buffer.nextCharacter()
+	if char == '\n' || next == '\n' || next == 0 {
 		return
 	}
 
+	buffer.recordChange()
 	buffer.Data[buffer.GapEnd+1] = char
 
 	buffer.Dirty = true
@@ -211,7 +253,10 @@ func (buffer *Buffer) RemoveBefore() {
 	char := buffer.prevCharacter()
 	nextChar := buffer.nextCharacter()
 
+	buffer.recordChange()
+
 	if char == '\n' {
+		buffer.shiftLines(buffer.Cursor.Line, -1)
 		buffer.Cursor.Line -= 1
 
 		buffer.moveLeftInternal()
@@ -220,6 +265,7 @@ func (buffer *Buffer) RemoveBefore() {
 
 		buffer.TotalLines -= 1
 		buffer.GapStart -= 1
+		buffer.Lines.PopBefore()
 	} else {
 		buffer.Cursor.Column -= 1
 
@@ -239,8 +285,12 @@ func (buffer *Buffer) RemoveAfter() {
 		return
 	}
 
+	buffer.recordChange()
+
 	if buffer.nextCharacter() == '\n' {
 		buffer.TotalLines -= 1
+		buffer.Lines.PopAfter()
+		buffer.shiftLines(buffer.Cursor.Line+1, -1)
 	}
 
 	buffer.Data[buffer.GapEnd] = '_' // @TODO (!important) only useful for debug, remove when buffer implementation is stable
@@ -282,14 +332,14 @@ func (buffer *Buffer) ChangeCurrentLine() {
 
 func (buffer *Buffer) Indent() {
 	buffer.MoveToStartOfLine()
-	for i := 0; i < 4; i += 1 {
+	for i := 0; i < int(buffer.IndentWidth); i += 1 {
 		buffer.Insert(' ')
 	}
 }
 
 func (buffer *Buffer) Outdent() {
 	buffer.MoveToStartOfLine()
-	for i := 0; i < 4; i += 1 {
+	for i := 0; i < int(buffer.IndentWidth); i += 1 {
 		buffer.RemoveAfter()
 	}
 }
@@ -311,36 +361,11 @@ func (buffer *Buffer) MoveRight() {
 }
 
 func (buffer *Buffer) MoveUp() {
-	endColumn := int32(Max(int(buffer.Cursor.Column), int(buffer.Cursor.LastColumn)))
-
 	if buffer.Cursor.Line == 0 {
 		return
 	}
 
-	for buffer.Cursor.Column > 0 {
-		buffer.moveLeftInternal()
-	}
-
-	if buffer.Cursor.Line > 0 {
-		buffer.moveLeftInternal() // Move over new line symbol
-		// @TODO (!important) this is probably not needed, get rid of this
-		char := buffer.prevCharacter()
-		if char != 0 && char != '\n' {
-			buffer.moveLeftInternal() // Move into the previous line to get its size correctly, unless the line is empty
-		}
-
-		// @TODO (!important) do something better here
-		buffer.Cursor.Column = int32(Max(int(buffer.currentLineSize()-1), 0))
-		buffer.Cursor.Line -= 1
-
-		for buffer.Cursor.Column > endColumn {
-			buffer.moveLeftInternal()
-		}
-
-		buffer.Cursor.LastColumn = endColumn
-
-		buffer.maybeScrollUp()
-	}
+	buffer.moveToLine(buffer.Cursor.Line - 1)
 }
 
 func (buffer *Buffer) MoveDown() {
@@ -348,43 +373,7 @@ func (buffer *Buffer) MoveDown() {
 		return
 	}
 
-	endColumn := Max(int(buffer.Cursor.Column), int(buffer.Cursor.LastColumn))
-	lineSize := buffer.currentLineSize() // @TODO (!important) this might not be needed, we can just check if the next symbol will be newline
-
-	for buffer.Cursor.Column < lineSize {
-		buffer.moveRightInternal()
-	}
-
-	if buffer.GapEnd != len(buffer.Data)-1 {
-		buffer.moveRightInternal() // Move over the new line symbol
-
-		buffer.Cursor.Column = 0
-		buffer.Cursor.Line += 1
-
-		// @TODO (!important) when the next line is shorter than the current column, this will unnecessarily try moving right
-		for i := 0; i < int(endColumn); i += 1 {
-			buffer.MoveRight()
-		}
-
-		buffer.Cursor.LastColumn = int32(endColumn)
-
-		buffer.maybeScrollDown()
-	}
-}
-
-// @TODO (!important) write tests for this
-func (buffer *Buffer) MoveToBookmark() {
-	if buffer.BookmarkLine < buffer.Cursor.Line {
-		for buffer.Cursor.Line != buffer.BookmarkLine {
-			buffer.MoveUp()
-		}
-
-		return
-	}
-
-	for buffer.Cursor.Line != buffer.BookmarkLine {
-		buffer.MoveDown()
-	}
+	buffer.moveToLine(buffer.Cursor.Line + 1)
 }
 
 func (buffer *Buffer) MoveToNextFindResult() {
@@ -441,36 +430,51 @@ func (buffer *Buffer) MoveToPrevFindResult() {
 	}
 }
 
-func (buffer *Buffer) MarkCurrentPosition() {
-	buffer.BookmarkLine = buffer.Cursor.Line
+// SetMark puts the named mark at the cursor and returns false if the name can't be used as a mark
+func (buffer *Buffer) SetMark(name byte) bool {
+	return buffer.Marks.Set(name, buffer.CurrentPosition())
 }
 
-func (buffer *Buffer) GetText() (lines []string, selection []Selection) {
-	// @TODO (!important) it is possible to cache the text lines if the text did not change between frames
+func (buffer *Buffer) CurrentPosition() Position {
+	return Position{Filepath: buffer.Filepath, Line: buffer.Cursor.Line, Column: buffer.Cursor.Column}
+}
+
+// PushJump remembers the cursor position before a large motion or before opening another file
+func (buffer *Buffer) PushJump() {
+	buffer.JumpList.Push(buffer.CurrentPosition())
+}
+
+// GetText returns the whole text split into lines. Drawing and searching read single lines with LineText instead
+func (buffer *Buffer) GetText() []string {
 	var sb strings.Builder
+	sb.Grow(buffer.textSize())
+	sb.Write(buffer.Data[:buffer.GapStart])
+	sb.Write(buffer.Data[buffer.GapEnd+1:])
 
-	for i := 0; i < buffer.GapStart; i += 1 {
-		sb.WriteByte(buffer.Data[i])
-	}
+	return strings.Split(sb.String(), "\n")
+}
 
-	for i := buffer.GapEnd + 1; i < len(buffer.Data); i += 1 {
-		sb.WriteByte(buffer.Data[i])
-	}
+// LineText returns the text of a single line without the new line symbol
+func (buffer *Buffer) LineText(line int32) string {
+	start := buffer.lineStart(line)
+	return buffer.textRange(start, start+int(buffer.lineSize(line)))
+}
 
-	lines = strings.Split(sb.String(), "\n")
+func (buffer *Buffer) GetSelection() (selection []Selection) {
+	if buffer.SelectionStartPoint.Column == -1 {
+		return
+	}
 
-	if buffer.SelectionStartPoint.Column > -1 {
-		start, end := buffer.sortSelectionEnds(buffer.SelectionStartPoint, buffer.cursorToCursorPoint())
+	start, end := buffer.sortSelectionEnds(buffer.SelectionStartPoint, buffer.cursorToCursorPoint())
 
-		if start.Line != end.Line {
-			selection = append(selection, Selection{Line: start.Line, Start: start.Column, End: int32(len(lines[start.Line]))})
-			for i := start.Line + 1; i < end.Line; i += 1 {
-				selection = append(selection, Selection{Line: i, Start: 0, End: int32(len(lines[i]))})
-			}
-			selection = append(selection, Selection{Line: end.Line, Start: 0, End: end.Column})
-		} else {
-			selection = append(selection, Selection{Line: start.Line, Start: start.Column, End: end.Column})
+	if start.Line != end.Line {
+		selection = append(selection, Selection{Line: start.Line, Start: start.Column, End: buffer.lineSize(start.Line)})
+		for i := start.Line + 1; i < end.Line; i += 1 {
+			selection = append(selection, Selection{Line: i, Start: 0, End: buffer.lineSize(i)})
 		}
+		selection = append(selection, Selection{Line: end.Line, Start: 0, End: end.Column})
+	} else {
+		selection = append(selection, Selection{Line: start.Line, Start: start.Column, End: end.Column})
 	}
 
 	return
@@ -495,65 +499,64 @@ func (buffer *Buffer) GetSelectionText() string {
 }
 
 func (buffer *Buffer) GetCurrentLineText() string {
-	start := buffer.GapStart - 1
-	for start >= 0 && buffer.Data[start] != '\n' {
-		start -= 1
-	}
-
-	end := buffer.GapEnd + 1
-	for end != len(buffer.Data) && buffer.Data[end] != '\n' {
-		end += 1
-	}
-
-	var sb strings.Builder
-	sb.WriteString(string(buffer.Data[start+1 : buffer.GapStart]))
-	sb.WriteString(string(buffer.Data[buffer.GapEnd+1 : end]))
-
-	return sb.String()
+	return buffer.LineText(buffer.Cursor.Line)
 }
 
-func (buffer *Buffer) Render(renderer *sdl.Renderer, mode Mode, theme *Theme) {
+func (buffer *Buffer) Render(renderer Canvas, mode Mode, theme *Theme) {
 	gutterRect := sdl.Rect{
 		X: 0,
 		Y: 0,
-		W: 48,
+		W: buffer.GutterWidth,
 		H: buffer.Rect.H,
 	}
 	DrawRect(renderer, &gutterRect, theme.Gutter.BackgroundColor)
 
-	text, selection := buffer.GetText()
+	selection := buffer.GetSelection()
+	marks := buffer.Marks.ByLine(buffer.Filepath)
 
 	buffer.renderSelection(renderer, gutterRect.W+5, selection, theme.Buffer.SelectionColor)
-	buffer.Cursor.Render(renderer, mode, gutterRect.W, buffer.Rect.W, buffer.ScrollY, len(selection) == 0)
 
-	for index, line := range text {
-		y := int32(index)*buffer.Cursor.Height + (buffer.Cursor.Height-int32(buffer.Font.Size))/2 + buffer.ScrollY
+	// The cursor is drawn on the display row of the segment it is in
+	cursor := buffer.Cursor
+	cursor.Line, cursor.Column = buffer.cursorDisplay()
+	cursor.Column -= buffer.firstColumn()
+	cursor.Render(renderer, mode, gutterRect.W, buffer.Rect.W, buffer.ScrollY, len(selection) == 0, theme.Buffer.LineHighlightColor)
+
+	// Only the lines in view are read from the buffer
+	index, row := buffer.lineAtRow(int32(Max(int(-buffer.ScrollY/buffer.Cursor.Height)-1, 0)))
+	for ; index < int32(buffer.TotalLines); index += 1 {
+		y := row*buffer.Cursor.Height + (buffer.Cursor.Height-int32(buffer.Font.Size))/2 + buffer.ScrollY
+
+		if y > buffer.Rect.Y+buffer.Rect.H {
+			break
+		}
+
+		segments := buffer.lineSegments(index)
+		lineRow := row
+		row += int32(len(segments))
 
-		if y > buffer.Rect.Y+buffer.Rect.H || y+int32(buffer.Font.Size) < buffer.Rect.Y {
+		if y+int32(len(segments)-1)*buffer.Cursor.Height+int32(buffer.Font.Size) < buffer.Rect.Y {
 			continue
 		}
 
-		buffer.renderLineNumber(renderer, &gutterRect, index, theme)
+		buffer.renderLineNumber(renderer, &gutterRect, int(index), lineRow, theme)
+		if names, ok := marks[index]; ok {
+			buffer.renderMarks(renderer, &gutterRect, lineRow, names, theme)
+		}
 
+		line := buffer.LineText(index)
 		if len(line) == 0 {
 			continue
 		}
 
-		lineWidth := buffer.Font.GetStringWidth(line)
-		x := gutterRect.W + 5
-
+		var tokens []TokenInfo
 		if buffer.HighlighterFunc != nil {
-			buffer.renderLine(renderer, line, x, y, &theme.Syntax)
+			tokens = buffer.HighlighterFunc([]byte(line), &theme.Syntax)
 		} else {
-			rect := sdl.Rect{ // @TODO (!important) rect could be reused between iterations to decrease garbage produced by the loop
-				X: x,
-				Y: y,
-				W: lineWidth,
-				H: int32(buffer.Font.Size),
-			}
-
-			DrawText(renderer, buffer.Font, line, &rect, theme.Buffer.TextColor)
+			tokens = []TokenInfo{{Value: line, Color: theme.Buffer.TextColor}}
 		}
+
+		buffer.renderTokens(renderer, tokens, segments, gutterRect.W+5, y)
 	}
 }
 
@@ -561,7 +564,7 @@ func (buffer *Buffer) Render(renderer *sdl.Renderer, mode Mode, theme *Theme) {
 // PRIVATE
 // =============================================================
 
-func (buffer *Buffer) renderLineNumber(renderer *sdl.Renderer, gutterRect *sdl.Rect, index int, theme *Theme) {
+func (buffer *Buffer) renderLineNumber(renderer Canvas, gutterRect *sdl.Rect, index int, row int32, theme *Theme) {
 	lineNumber := Abs(int(buffer.Cursor.Line) - index)
 	lineNumberColor := theme.Gutter.LineNumberInactiveColor
 	lineNumberOffset := 0
@@ -576,7 +579,7 @@ func (buffer *Buffer) renderLineNumber(renderer *sdl.Renderer, gutterRect *sdl.R
 
 		numberHighlightRect := sdl.Rect{
 			X: gutterRect.X,
-			Y: buffer.Cursor.Line*buffer.Cursor.Height + buffer.ScrollY,
+			Y: row*buffer.Cursor.Height + buffer.ScrollY,
 			W: gutterRect.W,
 			H: buffer.Cursor.Height,
 		}
@@ -588,41 +591,102 @@ func (buffer *Buffer) renderLineNumber(renderer *sdl.Renderer, gutterRect *sdl.R
 	// @TODO (!important) rect could be reused between iterations to decrease garbage produced by the loop
 	lineNumberRect := sdl.Rect{
 		X: gutterRect.X + gutterRect.W - 10 - width - int32(lineNumberOffset),
-		Y: int32(index)*buffer.Cursor.Height + (buffer.Cursor.Height-int32(buffer.Font.Size))/2 + buffer.ScrollY,
+		Y: row*buffer.Cursor.Height + (buffer.Cursor.Height-int32(buffer.Font.Size))/2 + buffer.ScrollY,
 		W: width,
 		H: int32(buffer.Font.Size),
 	}
 	DrawText(renderer, buffer.Font, lineNumberStr, &lineNumberRect, lineNumberColor)
 }
 
-func (buffer *Buffer) renderLine(renderer *sdl.Renderer, line string, leftStart int32, y int32, theme *SyntaxTheme) {
-	tokens := buffer.HighlighterFunc([]byte(line), theme)
+func (buffer *Buffer) renderMarks(renderer Canvas, gutterRect *sdl.Rect, row int32, names string, theme *Theme) {
+	// Only the first two fit next to the line number
+	if len(names) > 2 {
+		names = names[:2]
+	}
+
+	rect := sdl.Rect{
+		X: gutterRect.X + 3,
+		Y: row*buffer.Cursor.Height + (buffer.Cursor.Height-int32(buffer.Font.Size))/2 + buffer.ScrollY,
+		W: buffer.Font.GetStringWidth(names),
+		H: int32(buffer.Font.Size),
+	}
+	DrawText(renderer, buffer.Font, names, &rect, theme.Gutter.MarkColor)
+}
 
-	left := leftStart
+// renderTokens draws the tokens of a line one after another, splitting the ones that cross into the next segment.
+// Text scrolled out of view to the left is not drawn, so it does not end up over the gutter
+func (buffer *Buffer) renderTokens(renderer Canvas, tokens []TokenInfo, segments []WrapSegment, leftStart int32, y int32) {
+	column := int32(0)
+	index := 0
+	firstColumn := buffer.firstColumn()
 
 	for _, token := range tokens {
-		width := buffer.Font.GetStringWidth(token.Value)
-		rect := sdl.Rect{
-			X: left,
-			Y: y,
-			W: width,
-			H: int32(buffer.Font.Size),
-		}
-		DrawText(renderer, buffer.Font, token.Value, &rect, token.Color)
+		text := token.Value
+		for len(text) > 0 {
+			index = segmentAt(segments, column)
+			segment := segments[index]
+
+			size := int32(len(text))
+			if index < len(segments)-1 {
+				size = int32(Min(int(size), int(segment.End-column)))
+			}
+
+			piece := text[:size]
+			x := column - segment.Start + segment.Indent - firstColumn
+			if x < 0 {
+				piece = piece[Min(int(-x), len(piece)):]
+				x = 0
+			}
 
-		left += width
+			if len(piece) > 0 {
+				rect := sdl.Rect{
+					X: leftStart + x*int32(buffer.Font.CharacterWidth),
+					Y: y + int32(index)*buffer.Cursor.Height,
+					W: buffer.Font.GetStringWidth(piece),
+					H: int32(buffer.Font.Size),
+				}
+				DrawText(renderer, buffer.Font, piece, &rect, token.Color)
+			}
+
+			text = text[size:]
+			column += size
+		}
 	}
 }
 
-func (buffer *Buffer) renderSelection(renderer *sdl.Renderer, left int32, selection []Selection, color sdl.Color) {
+func (buffer *Buffer) renderSelection(renderer Canvas, left int32, selection []Selection, color sdl.Color) {
+	if len(selection) == 0 {
+		return
+	}
+
+	// Selected lines follow each other, so only the row of the first one has to be looked up
+	row := buffer.displayRow(selection[0].Line)
+	firstColumn := buffer.firstColumn()
 	for _, sel := range selection {
-		rect := sdl.Rect{
-			X: left + sel.Start*int32(buffer.Font.CharacterWidth),
-			Y: int32(sel.Line)*buffer.Cursor.Height + buffer.ScrollY,
-			W: (sel.End - sel.Start) * int32(buffer.Font.CharacterWidth),
-			H: buffer.Cursor.Height,
+		segments := buffer.lineSegments(sel.Line)
+
+		for index, segment := range segments {
+			start := int32(Max(int(sel.Start), int(segment.Start)))
+			end := sel.End
+			if index < len(segments)-1 {
+				end = int32(Min(int(end), int(segment.End)))
+			}
+
+			start = int32(Max(int(start), int(segment.Start+firstColumn-segment.Indent)))
+			if end < start || (start == end && index > 0) {
+				continue
+			}
+
+			rect := sdl.Rect{
+				X: left + (start-segment.Start+segment.Indent-firstColumn)*int32(buffer.Font.CharacterWidth),
+				Y: (row+int32(index))*buffer.Cursor.Height + buffer.ScrollY,
+				W: (end - start) * int32(buffer.Font.CharacterWidth),
+				H: buffer.Cursor.Height,
+			}
+			DrawRect(renderer, &rect, color)
 		}
-		DrawRect(renderer, &rect, color)
+
+		row += int32(len(segments))
 	}
 }
 
@@ -652,6 +716,11 @@ func (buffer *Buffer) moveLeftInternal() {
 	buffer.GapStart -= 1
 	buffer.GapEnd -= 1
 
+	if char == '\n' {
+		buffer.Lines.PopBefore()
+		buffer.Lines.PushAfter(len(buffer.Data) - buffer.GapEnd - 2)
+	}
+
 	buffer.Cursor.Column -= 1
 	buffer.Cursor.LastColumn = 0
 }
@@ -664,32 +733,111 @@ func (buffer *Buffer) moveRightInternal() {
 	buffer.GapStart += 1
 	buffer.GapEnd += 1
 
+	if char == '\n' {
+		buffer.Lines.PopAfter()
+		buffer.Lines.PushBefore(buffer.GapStart)
+	}
+
 	buffer.Cursor.Column += 1
 	buffer.Cursor.LastColumn = 0
 }
 
-func (buffer *Buffer) currentLineSize() (result int32) {
-	preIndex := buffer.GapStart - 1
-	for preIndex >= 0 && buffer.Data[preIndex] != '\n' {
-		result += 1
-		preIndex -= 1
+func (buffer *Buffer) currentLineSize() int32 {
+	return buffer.lineSize(buffer.Cursor.Line)
+}
+
+func (buffer *Buffer) gapSize() int {
+	return buffer.GapEnd - buffer.GapStart + 1
+}
+
+func (buffer *Buffer) textSize() int {
+	return len(buffer.Data) - buffer.gapSize()
+}
+
+// lineStart returns the offset of the line in the text without the gap
+func (buffer *Buffer) lineStart(line int32) int {
+	return buffer.Lines.Start(int(line), len(buffer.Data), buffer.gapSize())
+}
+
+// lineSize returns the length of the line without the new line symbol
+func (buffer *Buffer) lineSize(line int32) int32 {
+	end := buffer.textSize()
+	if int(line)+1 < buffer.Lines.Count() {
+		end = buffer.lineStart(line+1) - 1
+	}
+
+	return int32(end - buffer.lineStart(line))
+}
+
+// lineAt returns the line of an offset in the text without the gap
+func (buffer *Buffer) lineAt(offset int) int32 {
+	return int32(buffer.Lines.Line(offset, len(buffer.Data), buffer.gapSize()))
+}
+
+func (buffer *Buffer) textRange(start int, end int) string {
+	gapSize := buffer.gapSize()
+
+	if end <= buffer.GapStart {
+		return string(buffer.Data[start:end])
 	}
 
-	postIndex := buffer.GapEnd + 1
-	for postIndex != len(buffer.Data) && buffer.Data[postIndex] != '\n' {
-		result += 1
-		postIndex += 1
+	if start >= buffer.GapStart {
+		return string(buffer.Data[start+gapSize : end+gapSize])
 	}
 
-	return result
+	return string(buffer.Data[start:buffer.GapStart]) + string(buffer.Data[buffer.GapEnd+1:end+gapSize])
 }
 
-func cleanText(data []byte) (result []byte) {
-	for _, b := range data {
-		if b == 13 { // Remove \r symbol
-			continue
+// moveGapTo moves the gap, and the cursor with it, to an offset in the text without the gap. The text between is moved
+// in one go and only the new lines in it are moved from one side of the line index to the other
+func (buffer *Buffer) moveGapTo(offset int) {
+	gapSize := buffer.gapSize()
+
+	if offset < buffer.GapStart {
+		for buffer.Lines.Before[len(buffer.Lines.Before)-1] > offset {
+			start := buffer.Lines.PopBefore()
+			buffer.Lines.PushAfter(len(buffer.Data) - start - gapSize)
 		}
 
+		count := buffer.GapStart - offset
+		copy(buffer.Data[buffer.GapEnd+1-count:buffer.GapEnd+1], buffer.Data[offset:buffer.GapStart])
+		buffer.GapStart -= count
+		buffer.GapEnd -= count
+	} else if offset > buffer.GapStart {
+		for len(buffer.Lines.After) > 0 && len(buffer.Data)-buffer.Lines.After[len(buffer.Lines.After)-1]-gapSize <= offset {
+			distance := buffer.Lines.PopAfter()
+			buffer.Lines.PushBefore(len(buffer.Data) - distance - gapSize)
+		}
+
+		count := offset - buffer.GapStart
+		copy(buffer.Data[buffer.GapStart:buffer.GapStart+count], buffer.Data[buffer.GapEnd+1:buffer.GapEnd+1+count])
+		buffer.GapStart += count
+		buffer.GapEnd += count
+	}
+
+	buffer.Cursor.Line = int32(len(buffer.Lines.Before) - 1)
+	buffer.Cursor.Column = int32(offset - buffer.Lines.Before[len(buffer.Lines.Before)-1])
+}
+
+// moveToLine moves the cursor straight to another line and keeps the column the same way moving a line at a time would.
+// Moving down the cursor can stop after the last character of the line, moving up it stops on the last character
+func (buffer *Buffer) moveToLine(line int32) {
+	endColumn := int32(Max(int(buffer.Cursor.Column), int(buffer.Cursor.LastColumn)))
+	up := line < buffer.Cursor.Line
+
+	size := buffer.lineSize(line)
+	if up {
+		size = int32(Max(int(size)-1, 0))
+	}
+
+	buffer.moveGapTo(buffer.lineStart(line) + Min(int(endColumn), int(size)))
+	buffer.Cursor.LastColumn = endColumn
+
+	buffer.ScrollToCursor()
+}
+
+func cleanText(data []byte) (result []byte) {
+	for _, b := range data {
 		// @TODO (!important) temporary, should correctly handle tabs
 		if b == 9 { // Turn tabs into spaces
 			for i := 0; i < 4; i += 1 {
@@ -705,6 +853,19 @@ func cleanText(data []byte) (result []byte) {
 	return
 }
 
+func (buffer *Buffer) recordChange() {
+	buffer.EditCount += 1
+	buffer.Rows.Forget(buffer.Cursor.Line - 1) // Removing a new line changes the line before
+	buffer.ChangeList.Record(buffer.CurrentPosition())
+}
+
+// shiftLines keeps the remembered lines pointing at the same text after lines are added or removed at the line
+func (buffer *Buffer) shiftLines(from int32, delta int32) {
+	buffer.Marks.ShiftLines(buffer.Filepath, from, delta)
+	buffer.JumpList.ShiftLines(buffer.Filepath, from, delta)
+	buffer.ChangeList.ShiftLines(buffer.Filepath, from, delta)
+}
+
 func (buffer *Buffer) prevCharacter() byte {
 	if buffer.GapStart == 0 {
 		return 0
@@ -721,22 +882,6 @@ func (buffer *Buffer) nextCharacter() byte {
 	return buffer.Data[buffer.GapEnd+1]
 }
 
-func (buffer *Buffer) maybeScrollDown() {
-	cursorBottom := buffer.Cursor.GetBottom() + buffer.ScrollY
-	diff := cursorBottom - (buffer.Rect.Y + buffer.Rect.H - buffer.ScrollOffset*buffer.Cursor.Height)
-	if diff > 0 {
-		buffer.ScrollY -= diff
-	}
-}
-
-func (buffer *Buffer) maybeScrollUp() {
-	cursorTop := buffer.Cursor.GetTop() + buffer.ScrollY
-	diff := cursorTop - (buffer.Rect.Y + buffer.ScrollOffset*buffer.Cursor.Height)
-	if diff < 0 {
-		buffer.ScrollY = int32(Min(int(buffer.ScrollY-diff), 0))
-	}
-}
-
 func (buffer *Buffer) sortSelectionEnds(point1 CursorPoint, point2 CursorPoint) (start CursorPoint, end CursorPoint) {
 	if point1.Line > point2.Line || (point1.Line == point2.Line && point1.Column > point2.Column) {
 		return point2, point1
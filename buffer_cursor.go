@@ -30,7 +30,7 @@ func CreateBufferCursor(height int32, advance int32) (result BufferCursor) {
 	return
 }
 
-func (cursor *BufferCursor) Render(renderer *sdl.Renderer, mode Mode, gutterWidth int32, windowWidth int32, scrollOffsetY int32, renderHighlight bool) {
+func (cursor *BufferCursor) Render(renderer Canvas, mode Mode, gutterWidth int32, windowWidth int32, scrollOffsetY int32, renderHighlight bool, highlightColor sdl.Color) {
 	if renderHighlight {
 		lineHighlightRect := sdl.Rect{
 			X: gutterWidth,
@@ -38,7 +38,7 @@ func (cursor *BufferCursor) Render(renderer *sdl.Renderer, mode Mode, gutterWidt
 			W: windowWidth - gutterWidth,
 			H: cursor.Height,
 		}
-		DrawRect(renderer, &lineHighlightRect, sdl.Color{R: 34, G: 35, B: 38, A: 255})
+		DrawRect(renderer, &lineHighlightRect, highlightColor)
 	}
 
 	width := cursor.WidthWide
@@ -54,11 +54,3 @@ func (cursor *BufferCursor) Render(renderer *sdl.Renderer, mode Mode, gutterWidt
 	}
 	DrawRect(renderer, &cursorRect, cursor.Color)
 }
-
-func (cursor *BufferCursor) GetBottom() int32 {
-	return cursor.Line*cursor.Height + cursor.Height
-}
-
-func (cursor *BufferCursor) GetTop() int32 {
-	return cursor.Line * cursor.Height
-}
@@ -7,14 +7,6 @@ import (
 	"github.com/veandco/go-sdl2/sdl"
 )
 
-func GetFakeFont() Font {
-	return Font{
-		Data:           nil,
-		Size:           14,
-		CharacterWidth: 8,
-	}
-}
-
 func TestCreateBuffer(t *testing.T) {
 	fakeFont := GetFakeFont()
 	result := CreateBuffer(16, &fakeFont, sdl.Rect{})
@@ -1,7 +1,5 @@
 package main
 
-import "fmt"
-
 // @TODO (!important) write tests for this
 func (buffer *Buffer) InsertNewLineBelow() {
 	buffer.MoveToEndOfLine()
@@ -113,33 +111,28 @@ func (buffer *Buffer) OutdentSelection() {
 }
 
 func (buffer *Buffer) MoveUpByLines(lines int) {
-	for i := 0; i < lines; i += 1 {
-		buffer.MoveUp()
-	}
+	buffer.MoveToLine(buffer.Cursor.Line + 1 - int32(lines))
 }
 
 func (buffer *Buffer) MoveDownByLines(lines int) {
-	for i := 0; i < lines; i += 1 {
-		buffer.MoveDown()
-	}
+	buffer.MoveToLine(buffer.Cursor.Line + 1 + int32(lines))
 }
 
 func (buffer *Buffer) MoveToLine(line int32) {
 	// Line - 1 because line starts at 1, but cursor line starts at 0
-	if buffer.Cursor.Line > line-1 {
-		for buffer.Cursor.Line > line-1 {
-			buffer.MoveUp()
-		}
-	} else {
-		if line-1 > int32(buffer.TotalLines) {
-			line = int32(buffer.TotalLines)
-		}
+	line = int32(Clamp(int(line-1), 0, buffer.TotalLines-1))
+	if line != buffer.Cursor.Line {
+		buffer.moveToLine(line)
+	}
+}
 
-		fmt.Println(line)
+// MoveToPosition moves as close to the line and column as the text allows. Both start at 0
+func (buffer *Buffer) MoveToPosition(line int32, column int32) {
+	buffer.MoveToLine(line + 1)
+	buffer.MoveToStartOfLine()
 
-		for buffer.Cursor.Line < line-1 {
-			buffer.MoveDown()
-		}
+	for buffer.Cursor.Column < column && buffer.GapEnd != len(buffer.Data)-1 && buffer.nextCharacter() != '\n' {
+		buffer.MoveRight()
 	}
 }
 
@@ -150,6 +143,14 @@ func (buffer *Buffer) MoveToStartOfLine() {
 	}
 }
 
+func (buffer *Buffer) MoveToFirstNonWhitespace() {
+	buffer.MoveToStartOfLine()
+
+	for buffer.GapEnd != len(buffer.Data)-1 && buffer.nextCharacter() == ' ' {
+		buffer.MoveRight()
+	}
+}
+
 // @TODO (!important) write tests for this
 func (buffer *Buffer) MoveToEndOfLine() {
 	for buffer.GapEnd != len(buffer.Data)-1 && buffer.nextCharacter() != '\n' {
@@ -159,16 +160,12 @@ func (buffer *Buffer) MoveToEndOfLine() {
 
 // @TODO (!important) write tests for this
 func (buffer *Buffer) MoveToBufferStart() {
-	for buffer.Cursor.Line > 0 {
-		buffer.MoveUp()
-	}
+	buffer.MoveToLine(1)
 }
 
 // @TODO (!important) write tests for this
 func (buffer *Buffer) MoveToBufferEnd() {
-	for buffer.Cursor.Line < int32(buffer.TotalLines)-1 {
-		buffer.MoveDown()
-	}
+	buffer.MoveToLine(int32(buffer.TotalLines))
 }
 
 // @TODO (!important) write tests for this
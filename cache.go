@@ -13,8 +13,9 @@ type Cache struct {
 func ParseCache(dir string) (result Cache) {
 	data, path, success := OpenFile(fmt.Sprintf("%s/cache.acache", dir))
 	if !success {
-		CreateDirectory(dir)
-		SaveFile(path, make([]string, 0))
+		if CreateDirectory(dir) {
+			SaveFile(path, make([]string, 0))
+		}
 		result.Path = path
 		return
 	}
@@ -29,8 +30,8 @@ func ParseCache(dir string) (result Cache) {
 			continue
 		}
 
-		key, value := getKeyValue(l, " ")
-		if key == "project" {
+		key, value, ok := getKeyValue(l, " ")
+		if ok && key == "project" {
 			result.Projects = append(result.Projects, value)
 		}
 	}
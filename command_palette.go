@@ -1,15 +1,12 @@
 package main
 
 import (
-	"strings"
-
 	"github.com/veandco/go-sdl2/sdl"
 )
 
 type CommandPalette struct {
-	Input strings.Builder
+	Input TextInput
 
-	Cursor      InputCursor
 	Width       int32
 	LineHeight  int32
 	LineSpacing int32
@@ -19,18 +16,27 @@ type CommandPalette struct {
 }
 
 func CreateCommandPalette(lineHeight int32, font *Font) (result CommandPalette) {
-	result.Cursor = CreateInputCursor(lineHeight, int32(font.CharacterWidth))
-	result.Width = 500
-	result.LineHeight = lineHeight
-	result.LineSpacing = (lineHeight - int32(font.Size)) / 2
-	result.Font = font
+	result.Input = CreateTextInput(lineHeight, int32(font.CharacterWidth))
+	result.Width = popupWidth
+	result.SetFont(lineHeight, font)
 
 	return
 }
 
+func (cp *CommandPalette) SetFont(lineHeight int32, font *Font) {
+	cp.Input.SetFont(lineHeight, int32(font.CharacterWidth))
+	cp.LineHeight = lineHeight
+	cp.LineSpacing = (lineHeight - int32(font.Size)) / 2
+	cp.Font = font
+}
+
 func (cp *CommandPalette) Open(onClose func(string)) {
-	cp.Cursor.Column = 0
+	cp.OpenWithText("", onClose)
+}
+
+func (cp *CommandPalette) OpenWithText(text string, onClose func(string)) {
 	cp.Input.Reset()
+	cp.Input.SetText(text)
 
 	cp.CloseCallback = onClose
 }
@@ -40,7 +46,9 @@ func (cp *CommandPalette) Close() {
 }
 
 func (cp *CommandPalette) Submit() {
-	cp.CloseCallback(cp.Input.String())
+	command := cp.Input.String()
+	cp.Input.AddHistory(command)
+	cp.CloseCallback(command)
 }
 
 func (cp *CommandPalette) Tick(input Input) {
@@ -49,35 +57,15 @@ func (cp *CommandPalette) Tick(input Input) {
 		return
 	}
 
-	if input.Backspace {
-		if input.Ctrl {
-			cp.Cursor.Column = 0
-			cp.Input.Reset()
-		} else if cp.Cursor.Column > 0 {
-			cp.Cursor.Column -= 1
-			str := cp.Input.String()
-			cp.Input.Reset()
-
-			str = str[:len(str)-1]
-			cp.Input.WriteString(str)
-		}
-
+	if input.TypedCharacter == '\n' {
+		cp.Submit()
 		return
 	}
 
-	if input.TypedCharacter != 0 {
-		if input.TypedCharacter == '\n' {
-			cp.Submit()
-		} else {
-			cp.Input.WriteByte(input.TypedCharacter)
-			cp.Cursor.Column += 1
-		}
-
-		return
-	}
+	cp.Input.Tick(input)
 }
 
-func (cp *CommandPalette) Render(renderer *sdl.Renderer, parentRect *sdl.Rect, theme *FileSearchTheme) {
+func (cp *CommandPalette) Render(renderer Canvas, parentRect *sdl.Rect, theme *FileSearchTheme) {
 	inputRect := sdl.Rect{
 		X: parentRect.W/2 - cp.Width/2,
 		Y: parentRect.Y + int32(float32(parentRect.H)*0.15),
@@ -89,21 +77,17 @@ func (cp *CommandPalette) Render(renderer *sdl.Renderer, parentRect *sdl.Rect, t
 
 	DrawRect(renderer, &borderRect, theme.BorderColor)
 	DrawRect(renderer, &inputRect, theme.InputBackgroundColor)
-	cp.Cursor.Render(renderer, inputRect, theme.CursorColor)
 
-	command := cp.Input.String()
-	color := theme.InputTextColor
-	if len(command) == 0 {
-		command = "Command"
-		color = theme.ResultPathColor
+	if cp.Input.Len() == 0 {
+		placeholder := "Command"
+		placeholderRect := sdl.Rect{
+			X: inputRect.X + 5,
+			Y: inputRect.Y + (inputRect.H-int32(cp.Font.Size))/2,
+			W: cp.Font.GetStringWidth(placeholder),
+			H: int32(cp.Font.Size),
+		}
+		DrawText(renderer, cp.Font, placeholder, &placeholderRect, theme.ResultPathColor)
 	}
 
-	width := cp.Font.GetStringWidth(command)
-	commandRect := sdl.Rect{
-		X: inputRect.X + 5,
-		Y: inputRect.Y + (inputRect.H-int32(cp.Font.Size))/2,
-		W: width,
-		H: int32(cp.Font.Size),
-	}
-	DrawText(renderer, cp.Font, command, &commandRect, color)
+	cp.Input.Render(renderer, cp.Font, inputRect, theme.InputTextColor, theme.InputSelectionColor, theme.CursorColor)
 }
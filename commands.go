@@ -2,25 +2,99 @@ package main
 
 import (
 	"bytes"
-	"fmt"
-	"os"
 	"os/exec"
+	"strings"
 )
 
-func RunCommand(name string, cwd string, args ...string) {
-	var stderr bytes.Buffer
+// CommandResult is what a command started with StartCommand printed and how it exited
+type CommandResult struct {
+	Label  string
+	Path   string // File the command was run on, if any
+	Output string
+	Err    error
+}
+
+// RunCommand runs the command and waits for it to exit. Nothing is read from the standard input, so a command that
+// asks for input gets an end of file
+func RunCommand(name string, cwd string, args ...string) (string, error) {
+	var output bytes.Buffer
 
 	var cmd = exec.Command(name, args...)
-	cmd.Stdout = os.Stdout
-	cmd.Stdin = os.Stdin
-	cmd.Stderr = &stderr
+	cmd.Stdout = &output
+	cmd.Stderr = &output
 
 	if cwd != "" {
 		cmd.Dir = cwd
 	}
 
 	err := cmd.Run()
-	if err != nil {
-		fmt.Println("Error:", stderr.String())
+	return output.String(), err
+}
+
+// StartCommand runs the command in the background and sends the result when it exits
+func StartCommand(results chan<- CommandResult, label string, path string, cwd string, command []string) {
+	go func() {
+		output, err := RunCommand(command[0], cwd, command[1:]...)
+		results <- CommandResult{Label: label, Path: path, Output: output, Err: err}
+	}()
+}
+
+// SplitCommandLine splits the line into arguments at spaces like a shell would. Quotes keep the spaces inside them
+// and a backslash keeps the quote, space or backslash after it. Any other backslash is kept as it is, so Windows
+// paths don't need escaping. ok is false when a quote is never closed
+func SplitCommandLine(line string) (result []string, ok bool) {
+	var arg strings.Builder
+	inArg := false
+	var quote rune
+
+	escaped := false
+	for _, char := range line {
+		if escaped {
+			escaped = false
+			if strings.ContainsRune(" \t'\"\\", char) && (quote == 0 || char == quote || char == '\\') {
+				arg.WriteRune(char)
+				continue
+			}
+
+			arg.WriteRune('\\')
+		}
+
+		switch {
+		case char == '\\' && quote != '\'':
+			escaped = true
+			inArg = true
+		case quote != 0:
+			if char == quote {
+				quote = 0
+			} else {
+				arg.WriteRune(char)
+			}
+		case char == '\'' || char == '"':
+			quote = char
+			inArg = true
+		case char == ' ' || char == '\t':
+			if inArg {
+				result = append(result, arg.String())
+				arg.Reset()
+				inArg = false
+			}
+		default:
+			arg.WriteRune(char)
+			inArg = true
+		}
+	}
+
+	if quote != 0 {
+		return nil, false
+	}
+
+	if escaped {
+		arg.WriteRune('\\')
+	}
+
+	if inArg {
+		result = append(result, arg.String())
 	}
+
+	return result, true
 }
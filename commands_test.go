@@ -0,0 +1,30 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSplitCommandLine(t *testing.T) {
+	cases := [][]string{
+		{"go   test\t./...", "go|test|./..."},
+		{`gofmt -w "my file.go"`, "gofmt|-w|my file.go"},
+		{`echo 'a "quoted" word' ""`, `echo|a "quoted" word|`},
+		{`echo "say \"hi\"" it\'s`, `echo|say "hi"|it's`},
+		{`echo a\ b "c\d"`, `echo|a b|c\d`},
+		{`C:\tools\fmt.exe -w C:\src\`, `C:\tools\fmt.exe|-w|C:\src\`},
+		{`echo '\'`, `echo|\`},
+		{"", ""},
+	}
+
+	for _, c := range cases {
+		args, ok := SplitCommandLine(c[0])
+		FailIfFalse(ok, "Expected "+c[0]+" to be split", t)
+		FailIfFalse(strings.Join(args, "|") == c[1], "Expected "+c[0]+" to split into "+c[1]+", got "+strings.Join(args, "|"), t)
+	}
+
+	_, ok := SplitCommandLine(`echo "unclosed`)
+	FailIfFalse(!ok, "Expected an unclosed quote to fail", t)
+	_, ok = SplitCommandLine(`echo 'unclosed`)
+	FailIfFalse(!ok, "Expected an unclosed single quote to fail", t)
+}
@@ -0,0 +1,242 @@
+package main
+
+type DiffOpType uint8
+
+const (
+	DiffOp_Equal DiffOpType = iota
+	DiffOp_Insert
+	DiffOp_Delete
+)
+
+type DiffOp struct {
+	Type DiffOpType
+	Line string
+}
+
+// DiffLines finds the shortest edit script that turns a into b (Myers' algorithm)
+func DiffLines(a []string, b []string) (result []DiffOp) {
+	n := len(a)
+	m := len(b)
+	max := n + m
+	offset := max + 1
+
+	v := make([]int, 2*max+3)
+	var trace [][]int // Snapshot of v for every d, only the diagonals -d-1..d+1 are kept
+
+	found := false
+	for d := 0; d <= max && !found; d += 1 {
+		snapshot := make([]int, 2*d+3)
+		copy(snapshot, v[offset-d-1:offset+d+2])
+		trace = append(trace, snapshot)
+
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x += 1
+				y += 1
+			}
+
+			v[offset+k] = x
+
+			if x >= n && y >= m {
+				found = true
+				break
+			}
+		}
+	}
+
+	x := n
+	y := m
+	for d := len(trace) - 1; d > 0; d -= 1 {
+		snapshot := trace[d]
+		at := func(k int) int { return snapshot[k+d+1] }
+
+		k := x - y
+		var prevK int
+		if k == -d || (k != d && at(k-1) < at(k+1)) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+
+		prevX := at(prevK)
+		prevY := prevX - prevK
+
+		for x > prevX && y > prevY {
+			result = append(result, DiffOp{Type: DiffOp_Equal, Line: a[x-1]})
+			x -= 1
+			y -= 1
+		}
+
+		if x == prevX {
+			result = append(result, DiffOp{Type: DiffOp_Insert, Line: b[y-1]})
+			y -= 1
+		} else {
+			result = append(result, DiffOp{Type: DiffOp_Delete, Line: a[x-1]})
+			x -= 1
+		}
+	}
+
+	for x > 0 && y > 0 {
+		result = append(result, DiffOp{Type: DiffOp_Equal, Line: a[x-1]})
+		x -= 1
+		y -= 1
+	}
+
+	for i, j := 0, len(result)-1; i < j; i, j = i+1, j-1 {
+		result[i], result[j] = result[j], result[i]
+	}
+
+	return
+}
+
+// DiffPreview keeps only the changed lines and a few lines of context around them. Skipped parts are replaced with "..."
+func DiffPreview(ops []DiffOp, context int, maxLines int) (result []DiffOp) {
+	keep := make([]bool, len(ops))
+	for index, op := range ops {
+		if op.Type == DiffOp_Equal {
+			continue
+		}
+
+		for i := Max(index-context, 0); i <= Min(index+context, len(ops)-1); i += 1 {
+			keep[i] = true
+		}
+	}
+
+	skipped := false
+	for index, op := range ops {
+		if len(result) >= maxLines {
+			result = append(result, DiffOp{Type: DiffOp_Equal, Line: "..."})
+			break
+		}
+
+		if !keep[index] {
+			skipped = true
+			continue
+		}
+
+		if skipped && len(result) > 0 {
+			result = append(result, DiffOp{Type: DiffOp_Equal, Line: "..."})
+		}
+		skipped = false
+
+		result = append(result, op)
+	}
+
+	return
+}
+
+// Merge3 combines the changes made to base in ours and in theirs. Changes that overlap are wrapped in conflict markers
+func Merge3(base []string, ours []string, theirs []string) (result []string, conflict bool) {
+	keptOurs, insertedOurs := diffToChanges(len(base), DiffLines(base, ours))
+	keptTheirs, insertedTheirs := diffToChanges(len(base), DiffLines(base, theirs))
+
+	stable := func(index int) bool {
+		return index < len(base) && keptOurs[index] && keptTheirs[index] && len(insertedOurs[index]) == 0 && len(insertedTheirs[index]) == 0
+	}
+
+	collect := func(start int, end int, kept []bool, inserted [][]string) (chunk []string) {
+		for i := start; i < end; i += 1 {
+			chunk = append(chunk, inserted[i]...)
+			if kept[i] {
+				chunk = append(chunk, base[i])
+			}
+		}
+
+		if end == len(base) {
+			chunk = append(chunk, inserted[end]...)
+		}
+
+		return
+	}
+
+	index := 0
+	for index <= len(base) {
+		if stable(index) {
+			result = append(result, base[index])
+			index += 1
+			continue
+		}
+
+		end := index
+		for end < len(base) && (end == index || !stable(end)) {
+			end += 1
+		}
+
+		baseChunk := base[index:end]
+		oursChunk := collect(index, end, keptOurs, insertedOurs)
+		theirsChunk := collect(index, end, keptTheirs, insertedTheirs)
+
+		if linesEqual(oursChunk, baseChunk) {
+			result = append(result, theirsChunk...)
+		} else if linesEqual(theirsChunk, baseChunk) || linesEqual(oursChunk, theirsChunk) {
+			result = append(result, oursChunk...)
+		} else {
+			conflict = true
+			result = append(result, "<<<<<<< buffer")
+			result = append(result, oursChunk...)
+			result = append(result, "=======")
+			result = append(result, theirsChunk...)
+			result = append(result, ">>>>>>> disk")
+		}
+
+		if end == len(base) {
+			break
+		}
+
+		index = end
+	}
+
+	return
+}
+
+// diffToChanges returns whether every line of the original was kept and which lines were inserted before it. The last entry of inserted holds lines added at the end
+func diffToChanges(count int, ops []DiffOp) (kept []bool, inserted [][]string) {
+	kept = make([]bool, count)
+	inserted = make([][]string, count+1)
+
+	index := 0
+	changeStart := -1 // Lines that replace deleted lines belong to the first deleted line, not to the line after them
+	for _, op := range ops {
+		switch op.Type {
+		case DiffOp_Equal:
+			kept[index] = true
+			index += 1
+			changeStart = -1
+		case DiffOp_Delete:
+			if changeStart == -1 {
+				changeStart = index
+			}
+			index += 1
+		case DiffOp_Insert:
+			at := index
+			if changeStart != -1 {
+				at = changeStart
+			}
+			inserted[at] = append(inserted[at], op.Line)
+		}
+	}
+
+	return
+}
+
+func linesEqual(a []string, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
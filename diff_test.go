@@ -0,0 +1,89 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func applyDiff(ops []DiffOp) (before []string, after []string) {
+	for _, op := range ops {
+		if op.Type != DiffOp_Insert {
+			before = append(before, op.Line)
+		}
+		if op.Type != DiffOp_Delete {
+			after = append(after, op.Line)
+		}
+	}
+
+	return
+}
+
+func TestDiffLines(t *testing.T) {
+	cases := [][2]string{
+		{"a b c", "a b c"},
+		{"a b c", "a c"},
+		{"a c", "a b c"},
+		{"", "a b"},
+		{"a b", ""},
+		{"a b c a b b a", "c b a b a c"},
+		{"x y z", "a b c"},
+	}
+
+	for _, c := range cases {
+		a := strings.Fields(c[0])
+		b := strings.Fields(c[1])
+
+		ops := DiffLines(a, b)
+		before, after := applyDiff(ops)
+
+		FailIfFalse(linesEqual(before, a), fmt.Sprintf("Diff of %v and %v does not reproduce the original", a, b), t)
+		FailIfFalse(linesEqual(after, b), fmt.Sprintf("Diff of %v and %v does not reproduce the result", a, b), t)
+	}
+
+	ops := DiffLines(strings.Fields("a b c a b b a"), strings.Fields("c b a b a c"))
+	edits := 0
+	for _, op := range ops {
+		if op.Type != DiffOp_Equal {
+			edits += 1
+		}
+	}
+	FailIfFalse(edits == 5, fmt.Sprintf("Expected the shortest edit script of 5 edits, got %d", edits), t)
+}
+
+func TestMerge3(t *testing.T) {
+	t.Run("Changes in different places", func(t *testing.T) {
+		base := []string{"a", "b", "c", "d"}
+		ours := []string{"a", "B", "c", "d"}
+		theirs := []string{"a", "b", "c", "D", "e"}
+
+		result, conflict := Merge3(base, ours, theirs)
+		expected := []string{"a", "B", "c", "D", "e"}
+
+		FailIfFalse(!conflict, "Expected no conflict", t)
+		FailIfFalse(linesEqual(result, expected), fmt.Sprintf("Expected %v, got %v", expected, result), t)
+	})
+
+	t.Run("Same change on both sides", func(t *testing.T) {
+		base := []string{"a", "b"}
+		ours := []string{"a", "x", "b"}
+		theirs := []string{"a", "x", "b"}
+
+		result, conflict := Merge3(base, ours, theirs)
+
+		FailIfFalse(!conflict, "Expected no conflict", t)
+		FailIfFalse(linesEqual(result, ours), fmt.Sprintf("Expected %v, got %v", ours, result), t)
+	})
+
+	t.Run("Overlapping changes", func(t *testing.T) {
+		base := []string{"a", "b", "c"}
+		ours := []string{"a", "x", "c"}
+		theirs := []string{"a", "y", "c"}
+
+		result, conflict := Merge3(base, ours, theirs)
+		expected := []string{"a", "<<<<<<< buffer", "x", "=======", "y", ">>>>>>> disk", "c"}
+
+		FailIfFalse(conflict, "Expected a conflict", t)
+		FailIfFalse(linesEqual(result, expected), fmt.Sprintf("Expected %v, got %v", expected, result), t)
+	})
+}
@@ -0,0 +1,389 @@
+package main
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Commands typed into the command palette. The first word is the command name, the rest are passed as arguments
+
+func (app *App) registerCommands() {
+	app.Commands["lineending"] = commandLineEnding
+	app.Commands["encoding"] = commandEncoding
+	app.Commands["bom"] = commandBOM
+	app.Commands["eol"] = commandTrailingNewline
+	app.Commands["backup"] = commandBackup
+	app.Commands["task"] = commandTask
+	app.Commands["ignored"] = commandIgnored
+	app.Commands["open"] = commandOpen
+	app.Commands["marks"] = commandMarks
+	app.Commands["register"] = commandRegister
+	app.Commands["wrap"] = commandWrap
+	app.Commands["scrolloff"] = commandScrollOff
+	app.Commands["sidescrolloff"] = commandSideScrollOff
+	app.Commands["map"] = commandMap
+	app.Commands["unmap"] = commandUnmap
+	app.Commands["set"] = commandSet
+	app.Commands["settings"] = commandSettings
+	app.Commands["theme"] = commandTheme
+}
+
+// lineending lf|crlf|cr
+func commandLineEnding(app *App, args []string) {
+	if len(args) != 1 {
+		log.Printf("Usage: lineending lf|crlf|cr")
+		return
+	}
+
+	ending, ok := ParseLineEnding(args[0])
+	if !ok {
+		log.Printf("Unknown line ending: %s", args[0])
+		return
+	}
+
+	if app.Buffer.Format.LineEnding != ending {
+		app.Buffer.Format.LineEnding = ending
+		app.Buffer.Dirty = true
+	}
+}
+
+// encoding utf-8|latin-1|utf-16le|utf-16be
+func commandEncoding(app *App, args []string) {
+	if len(args) != 1 {
+		log.Printf("Usage: encoding utf-8|latin-1|utf-16le|utf-16be")
+		return
+	}
+
+	encoding, ok := ParseEncoding(args[0])
+	if !ok {
+		log.Printf("Unknown encoding: %s", args[0])
+		return
+	}
+
+	if app.Buffer.Format.Encoding != encoding {
+		app.Buffer.Format.Encoding = encoding
+		if encoding == Encoding_Latin1 {
+			app.Buffer.Format.BOM = false // Latin-1 has no byte order mark
+		}
+		app.Buffer.Dirty = true
+	}
+}
+
+// bom on|off
+func commandBOM(app *App, args []string) {
+	value, ok := parseOnOff(args)
+	if !ok {
+		log.Printf("Usage: bom on|off")
+		return
+	}
+
+	if app.Buffer.Format.Encoding == Encoding_Latin1 && value {
+		log.Printf("Latin-1 files cannot have a byte order mark")
+		return
+	}
+
+	if app.Buffer.Format.BOM != value {
+		app.Buffer.Format.BOM = value
+		app.Buffer.Dirty = true
+	}
+}
+
+// eol on|off
+func commandTrailingNewline(app *App, args []string) {
+	value, ok := parseOnOff(args)
+	if !ok {
+		log.Printf("Usage: eol on|off")
+		return
+	}
+
+	if app.Buffer.Format.TrailingNewline != value {
+		app.Buffer.Format.TrailingNewline = value
+		app.Buffer.Dirty = true
+	}
+}
+
+// backup on|off
+func commandBackup(app *App, args []string) {
+	_, ok := parseOnOff(args)
+	if !ok {
+		log.Printf("Usage: backup on|off")
+		return
+	}
+
+	app.setBufferSetting("backup", args[0])
+}
+
+// wrap on|off|<column>, wraps long lines at the window width or at the column
+func commandWrap(app *App, args []string) {
+	if len(args) == 1 {
+		column, err := strconv.Atoi(args[0])
+		if err == nil && column > 0 {
+			if app.setBufferSetting("wrapcolumn", args[0]) {
+				app.setBufferSetting("wrap", "on")
+			}
+			return
+		}
+	}
+
+	_, ok := parseOnOff(args)
+	if !ok {
+		log.Printf("Usage: wrap on|off|<column>")
+		return
+	}
+
+	app.setBufferSetting("wrapcolumn", "0")
+	app.setBufferSetting("wrap", args[0])
+}
+
+// scrolloff <rows>, rows kept between the cursor and the top and bottom of the view
+func commandScrollOff(app *App, args []string) {
+	if len(args) != 1 {
+		log.Printf("Usage: scrolloff <rows>")
+		return
+	}
+
+	app.setBufferSetting("scrolloff", args[0])
+}
+
+// sidescrolloff <columns>, columns kept between the cursor and the sides of the view
+func commandSideScrollOff(app *App, args []string) {
+	if len(args) != 1 {
+		log.Printf("Usage: sidescrolloff <columns>")
+		return
+	}
+
+	app.setBufferSetting("sidescrolloff", args[0])
+}
+
+// set [<name> [<value>]], changes a setting for the open file. Without a value logs the setting, without a name logs all of them
+func commandSet(app *App, args []string) {
+	switch len(args) {
+	case 0:
+		for _, line := range FormatSettings(&app.Settings) {
+			log.Printf("%s", line)
+		}
+	case 1:
+		value, ok := app.Settings.Get(args[0])
+		if !ok {
+			log.Printf("Unknown setting: %s", args[0])
+			return
+		}
+
+		log.Printf("%s: %s", args[0], value)
+	default:
+		app.setBufferSetting(args[0], strings.Join(args[1:], " "))
+	}
+}
+
+// settings, opens the settings file. It is written with every option set to its default the first time
+func commandSettings(app *App, args []string) {
+	if app.SettingsPath == "" {
+		log.Printf("There is no user config directory for the settings file")
+		return
+	}
+
+	if app.Buffer.Dirty {
+		log.Printf("%s has unsaved changes", GetFileNameFromPath(app.Buffer.Filepath))
+		return
+	}
+
+	if _, err := os.Stat(app.SettingsPath); err != nil {
+		defaults := DefaultSettings()
+		if !CreateDirectory(filepath.Dir(app.SettingsPath)) {
+			return
+		}
+		if _, success := WriteFile(app.SettingsPath, []byte(strings.Join(FormatSettings(&defaults), "\n")+"\n")); !success {
+			return
+		}
+	}
+
+	app.openSourceFile(app.SettingsPath)
+}
+
+// map <mode> <keys> <target>, binds the keys until the editor is closed. With only the mode, logs the bindings of the mode
+func commandMap(app *App, args []string) {
+	if len(args) != 1 && len(args) < 3 {
+		log.Printf("Usage: map <mode> [<keys> <target>]")
+		return
+	}
+
+	mode, ok := ParseKeymapMode(args[0])
+	if !ok {
+		log.Printf("Unknown mode: %s", args[0])
+		return
+	}
+
+	if len(args) == 1 {
+		for _, binding := range app.Keymap.Bindings[mode] {
+			log.Printf("%s %s", mode, binding.String())
+		}
+		return
+	}
+
+	binding, ok := ParseKeyBinding(args[1], strings.Join(args[2:], " "))
+	if !ok {
+		log.Printf("Invalid key binding: %s", strings.Join(args[1:], " "))
+		return
+	}
+
+	app.Keymap.Bind(mode, binding)
+}
+
+// unmap <mode> <keys>
+func commandUnmap(app *App, args []string) {
+	if len(args) != 2 {
+		log.Printf("Usage: unmap <mode> <keys>")
+		return
+	}
+
+	mode, ok := ParseKeymapMode(args[0])
+	if !ok {
+		log.Printf("Unknown mode: %s", args[0])
+		return
+	}
+
+	if !app.Keymap.Unbind(mode, normalizeKeys(DecodeKeys(args[1]))) {
+		log.Printf("Nothing is bound to %s in %s mode", args[1], mode)
+	}
+}
+
+// theme [<name>], switches to the theme. Without a name the theme is picked from the themes in the themes folders
+func commandTheme(app *App, args []string) {
+	if len(args) == 0 {
+		app.openThemeSearch()
+		return
+	}
+
+	app.setTheme(strings.Join(args, " "))
+}
+
+// task <name>, runs one of the tasks defined in the project file
+func commandTask(app *App, args []string) {
+	if len(args) != 1 {
+		log.Printf("Usage: task <name>")
+		return
+	}
+
+	task, ok := app.Project.Tasks[args[0]]
+	if !ok {
+		log.Printf("Unknown task: %s", args[0])
+		return
+	}
+
+	command, ok := SplitCommandLine(task)
+	if !ok {
+		log.Printf("Task %s has an unclosed quote", args[0])
+		return
+	}
+
+	if len(command) == 0 {
+		log.Printf("Task %s has no command", args[0])
+		return
+	}
+
+	app.startCommand("Task "+args[0], "", command)
+}
+
+func parseOnOff(args []string) (bool, bool) {
+	if len(args) != 1 {
+		return false, false
+	}
+
+	switch args[0] {
+	case "on", "true":
+		return true, true
+	case "off", "false":
+		return false, true
+	}
+
+	return false, false
+}
+
+// ignored [path], logs why the path, or every skipped path, was left out of the project files
+func commandIgnored(app *App, args []string) {
+	if len(args) > 1 {
+		log.Printf("Usage: ignored [path]")
+		return
+	}
+
+	if len(args) == 1 {
+		path := args[0]
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(app.Project.Root, path)
+		}
+
+		reason, ok := app.Project.Skipped[path]
+		if !ok {
+			reason = app.Project.ExcludeReason(path, false)
+		}
+
+		if reason == "" {
+			log.Printf("%s is not ignored", path)
+		} else {
+			log.Printf("%s is ignored: %s", path, reason)
+		}
+
+		return
+	}
+
+	paths := make([]string, 0, len(app.Project.Skipped))
+	for path := range app.Project.Skipped {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		log.Printf("%s: %s", path, app.Project.Skipped[path])
+	}
+}
+
+// open [path], without a path a file dialog is shown. Relative paths are relative to the project root
+func commandOpen(app *App, args []string) {
+	path := strings.Join(args, " ")
+	if path != "" && !filepath.IsAbs(path) {
+		path = filepath.Join(app.Project.Root, path)
+	}
+
+	app.openSourceFile(path)
+}
+
+// marks, lists the marks of the current file and the global marks
+func commandMarks(app *App, args []string) {
+	marks := app.Buffer.Marks.List(app.Buffer.Filepath)
+	for name, position := range app.Buffer.Marks.Global {
+		if position.Filepath != app.Buffer.Filepath {
+			marks = append(marks, NamedMark{Name: name, Position: position})
+		}
+	}
+	sort.Slice(marks, func(i, j int) bool { return marks[i].Name < marks[j].Name })
+
+	if len(marks) == 0 {
+		log.Printf("No marks set")
+		return
+	}
+
+	for _, mark := range marks {
+		position := mark.Position
+		log.Printf("%c %d:%d %s", mark.Name, position.Line+1, position.Column+1, position.Filepath)
+	}
+}
+
+// register name [text], shows or replaces the text of a register. Macros are edited this way, see macro.go for the notation
+func commandRegister(app *App, args []string) {
+	if len(args) == 0 || len(args[0]) != 1 {
+		log.Printf("Usage: register name [text]")
+		return
+	}
+
+	name := args[0][0]
+	if len(args) == 1 {
+		log.Printf("%c: %s", name, strconv.Quote(app.Registers[name]))
+		return
+	}
+
+	app.Registers[name] = strings.Join(args[1:], " ")
+}
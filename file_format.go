@@ -0,0 +1,360 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+type LineEnding string
+type Encoding string
+
+const (
+	LineEnding_LF   LineEnding = "LF"
+	LineEnding_CRLF LineEnding = "CRLF"
+	LineEnding_CR   LineEnding = "CR"
+)
+
+const (
+	Encoding_UTF8    Encoding = "UTF-8"
+	Encoding_Latin1  Encoding = "Latin-1"
+	Encoding_UTF16LE Encoding = "UTF-16LE"
+	Encoding_UTF16BE Encoding = "UTF-16BE"
+)
+
+var (
+	bomUTF8    = []byte{0xEF, 0xBB, 0xBF}
+	bomUTF16LE = []byte{0xFF, 0xFE}
+	bomUTF16BE = []byte{0xFE, 0xFF}
+)
+
+// FileFormat describes how the text of a file is stored on disk, so that it can be written back exactly the way it was read
+type FileFormat struct {
+	LineEnding      LineEnding
+	Encoding        Encoding
+	BOM             bool
+	TrailingNewline bool
+	Invalid         bool // The file had bytes the encoding can't read. They were replaced, so saving loses them
+}
+
+func CreateFileFormat() (result FileFormat) {
+	result.LineEnding = LineEnding_LF
+	result.Encoding = Encoding_UTF8
+	result.BOM = false
+	result.TrailingNewline = true
+
+	return
+}
+
+// DecodeFileData detects the format of the raw file data and returns the text as UTF-8 with \n line endings and without the trailing new line
+func DecodeFileData(data []byte) (format FileFormat, text []byte) {
+	format = CreateFileFormat()
+
+	switch {
+	case bytes.HasPrefix(data, bomUTF8):
+		format.BOM = true
+		text = data[len(bomUTF8):]
+	case bytes.HasPrefix(data, bomUTF16LE):
+		format.BOM = true
+		format.Encoding = Encoding_UTF16LE
+		text, format.Invalid = decodeUTF16(data[len(bomUTF16LE):], false)
+	case bytes.HasPrefix(data, bomUTF16BE):
+		format.BOM = true
+		format.Encoding = Encoding_UTF16BE
+		text, format.Invalid = decodeUTF16(data[len(bomUTF16BE):], true)
+	default:
+		format.Encoding = guessEncoding(data)
+		switch format.Encoding {
+		case Encoding_UTF16LE, Encoding_UTF16BE:
+			var invalid bool
+			text, invalid = decodeUTF16(data, format.Encoding == Encoding_UTF16BE)
+
+			// Without a BOM the guess was most likely wrong. Latin-1 reads any bytes and writes them back the same
+			if invalid {
+				format.Encoding = Encoding_Latin1
+				text = decodeLatin1(data)
+			}
+		case Encoding_Latin1:
+			text = decodeLatin1(data)
+		default:
+			text = data
+		}
+	}
+
+	format.LineEnding = guessLineEnding(text)
+	text = normalizeLineEndings(text)
+
+	format.TrailingNewline = len(text) > 0 && text[len(text)-1] == '\n'
+	if format.TrailingNewline {
+		text = text[:len(text)-1]
+	}
+
+	return
+}
+
+// EncodeFileData joins the lines back together and encodes them the way the format describes
+func EncodeFileData(format FileFormat, lines []string) []byte {
+	newLine := format.LineEnding.Sequence()
+
+	var sb strings.Builder
+	sb.WriteString(strings.Join(lines, newLine))
+	if format.TrailingNewline {
+		sb.WriteString(newLine)
+	}
+
+	text := sb.String()
+
+	var result []byte
+	switch format.Encoding {
+	case Encoding_UTF16LE:
+		if format.BOM {
+			result = append(result, bomUTF16LE...)
+		}
+		result = append(result, encodeUTF16(text, false)...)
+	case Encoding_UTF16BE:
+		if format.BOM {
+			result = append(result, bomUTF16BE...)
+		}
+		result = append(result, encodeUTF16(text, true)...)
+	case Encoding_Latin1:
+		result = encodeLatin1(text)
+	default:
+		if format.BOM {
+			result = append(result, bomUTF8...)
+		}
+		result = append(result, text...)
+	}
+
+	return result
+}
+
+func (format *FileFormat) String() string {
+	var sb strings.Builder
+	sb.WriteString(string(format.Encoding))
+	if format.BOM {
+		sb.WriteString(" BOM")
+	}
+
+	sb.WriteString(" ")
+	sb.WriteString(string(format.LineEnding))
+
+	if !format.TrailingNewline {
+		sb.WriteString(" [noeol]")
+	}
+
+	if format.Invalid {
+		sb.WriteString(" [invalid]")
+	}
+
+	return sb.String()
+}
+
+// Sequence returns the characters that end a line
+func (ending LineEnding) Sequence() string {
+	switch ending {
+	case LineEnding_CRLF:
+		return "\r\n"
+	case LineEnding_CR:
+		return "\r"
+	}
+
+	return "\n"
+}
+
+// FindUnencodable returns the first character in the lines that the encoding of the format can't store
+func FindUnencodable(format FileFormat, lines []string) (rune, bool) {
+	if format.Encoding != Encoding_Latin1 {
+		return 0, false
+	}
+
+	for _, line := range lines {
+		for _, r := range line {
+			if r > 255 {
+				return r, true
+			}
+		}
+	}
+
+	return 0, false
+}
+
+func ParseLineEnding(value string) (LineEnding, bool) {
+	switch strings.ToLower(value) {
+	case "lf", "unix":
+		return LineEnding_LF, true
+	case "crlf", "dos":
+		return LineEnding_CRLF, true
+	case "cr", "mac":
+		return LineEnding_CR, true
+	}
+
+	return LineEnding_LF, false
+}
+
+func ParseEncoding(value string) (Encoding, bool) {
+	switch strings.ToLower(value) {
+	case "utf-8", "utf8":
+		return Encoding_UTF8, true
+	case "latin-1", "latin1", "iso-8859-1":
+		return Encoding_Latin1, true
+	case "utf-16le", "utf16le":
+		return Encoding_UTF16LE, true
+	case "utf-16be", "utf16be":
+		return Encoding_UTF16BE, true
+	}
+
+	return Encoding_UTF8, false
+}
+
+func guessEncoding(data []byte) Encoding {
+	if utf8.Valid(data) && bytes.IndexByte(data, 0) == -1 {
+		return Encoding_UTF8
+	}
+
+	// UTF-16 text without a BOM that is mostly ASCII has a zero in every other byte
+	if len(data) >= 2 && len(data)%2 == 0 {
+		evenZeros := 0
+		oddZeros := 0
+		for i := 0; i < len(data); i += 2 {
+			if data[i] == 0 {
+				evenZeros += 1
+			}
+			if data[i+1] == 0 {
+				oddZeros += 1
+			}
+		}
+
+		half := len(data) / 2
+		if oddZeros*10 >= half*4 && evenZeros*10 < half {
+			return Encoding_UTF16LE
+		}
+		if evenZeros*10 >= half*4 && oddZeros*10 < half {
+			return Encoding_UTF16BE
+		}
+	}
+
+	if utf8.Valid(data) {
+		return Encoding_UTF8
+	}
+
+	return Encoding_Latin1
+}
+
+func guessLineEnding(text []byte) LineEnding {
+	lf := 0
+	crlf := 0
+	cr := 0
+
+	for i := 0; i < len(text); i += 1 {
+		if text[i] == '\r' {
+			if i+1 < len(text) && text[i+1] == '\n' {
+				crlf += 1
+				i += 1
+			} else {
+				cr += 1
+			}
+		} else if text[i] == '\n' {
+			lf += 1
+		}
+	}
+
+	if crlf > lf && crlf >= cr {
+		return LineEnding_CRLF
+	}
+
+	if cr > lf && cr > crlf {
+		return LineEnding_CR
+	}
+
+	return LineEnding_LF
+}
+
+func normalizeLineEndings(text []byte) []byte {
+	result := make([]byte, 0, len(text))
+
+	for i := 0; i < len(text); i += 1 {
+		if text[i] == '\r' {
+			if i+1 < len(text) && text[i+1] == '\n' {
+				i += 1
+			}
+
+			result = append(result, '\n')
+			continue
+		}
+
+		result = append(result, text[i])
+	}
+
+	return result
+}
+
+// decodeUTF16 reports the text as invalid when it ends in half of a code unit or has surrogates without a pair. Those
+// become U+FFFD
+func decodeUTF16(data []byte, bigEndian bool) (text []byte, invalid bool) {
+	units := make([]uint16, len(data)/2)
+	for i := range units {
+		if bigEndian {
+			units[i] = uint16(data[i*2])<<8 | uint16(data[i*2+1])
+		} else {
+			units[i] = uint16(data[i*2+1])<<8 | uint16(data[i*2])
+		}
+	}
+
+	invalid = len(data)%2 != 0
+	for i := 0; i < len(units); i += 1 {
+		if utf16.IsSurrogate(rune(units[i])) {
+			if units[i] >= 0xDC00 || i+1 == len(units) || units[i+1] < 0xDC00 || units[i+1] > 0xDFFF {
+				invalid = true
+			} else {
+				i += 1
+			}
+		}
+	}
+
+	text = []byte(string(utf16.Decode(units)))
+	if len(data)%2 != 0 {
+		text = append(text, string(utf8.RuneError)...)
+	}
+
+	return
+}
+
+func encodeUTF16(text string, bigEndian bool) []byte {
+	units := utf16.Encode([]rune(text))
+
+	result := make([]byte, len(units)*2)
+	for i, unit := range units {
+		if bigEndian {
+			result[i*2] = byte(unit >> 8)
+			result[i*2+1] = byte(unit)
+		} else {
+			result[i*2] = byte(unit)
+			result[i*2+1] = byte(unit >> 8)
+		}
+	}
+
+	return result
+}
+
+func decodeLatin1(data []byte) []byte {
+	runes := make([]rune, len(data))
+	for i, b := range data {
+		runes[i] = rune(b)
+	}
+
+	return []byte(string(runes))
+}
+
+func encodeLatin1(text string) []byte {
+	result := make([]byte, 0, len(text))
+	for _, r := range text {
+		if r > 255 {
+			r = '?' // Not representable in Latin-1, saveSourceFile asks before this happens
+		}
+
+		result = append(result, byte(r))
+	}
+
+	return result
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestDecodeFileData(t *testing.T) {
+	t.Run("LF with trailing new line", func(t *testing.T) {
+		format, text := DecodeFileData([]byte("abc\ndef\n"))
+
+		FailIfFalse(format.LineEnding == LineEnding_LF, "Expected LF line ending", t)
+		FailIfFalse(format.Encoding == Encoding_UTF8, "Expected UTF-8 encoding", t)
+		FailIfFalse(!format.BOM, "Expected no BOM", t)
+		FailIfFalse(format.TrailingNewline, "Expected trailing new line", t)
+		FailIfFalse(string(text) == "abc\ndef", fmt.Sprintf("Incorrect decoded text %q", text), t)
+	})
+
+	t.Run("CRLF with BOM and no trailing new line", func(t *testing.T) {
+		format, text := DecodeFileData([]byte("\xEF\xBB\xBFabc\r\ndef"))
+
+		FailIfFalse(format.LineEnding == LineEnding_CRLF, "Expected CRLF line ending", t)
+		FailIfFalse(format.BOM, "Expected BOM", t)
+		FailIfFalse(!format.TrailingNewline, "Expected no trailing new line", t)
+		FailIfFalse(string(text) == "abc\ndef", fmt.Sprintf("Incorrect decoded text %q", text), t)
+	})
+
+	t.Run("CR only", func(t *testing.T) {
+		format, text := DecodeFileData([]byte("abc\rdef\r"))
+
+		FailIfFalse(format.LineEnding == LineEnding_CR, "Expected CR line ending", t)
+		FailIfFalse(string(text) == "abc\ndef", fmt.Sprintf("Incorrect decoded text %q", text), t)
+	})
+
+	t.Run("Latin-1", func(t *testing.T) {
+		format, text := DecodeFileData([]byte("caf\xE9\n"))
+
+		FailIfFalse(format.Encoding == Encoding_Latin1, "Expected Latin-1 encoding", t)
+		FailIfFalse(string(text) == "café", fmt.Sprintf("Incorrect decoded text %q", text), t)
+
+		_, found := FindUnencodable(format, []string{"café"})
+		FailIfFalse(!found, "Expected café to fit in Latin-1", t)
+		r, found := FindUnencodable(format, []string{"café", "5 €"})
+		FailIfFalse(found && r == '€', fmt.Sprintf("Expected € not to fit in Latin-1, got %q", r), t)
+	})
+
+	t.Run("UTF-16LE with BOM", func(t *testing.T) {
+		format, text := DecodeFileData([]byte("\xFF\xFEa\x00\r\x00\n\x00b\x00"))
+
+		FailIfFalse(format.Encoding == Encoding_UTF16LE, "Expected UTF-16LE encoding", t)
+		FailIfFalse(format.BOM, "Expected BOM", t)
+		FailIfFalse(format.LineEnding == LineEnding_CRLF, "Expected CRLF line ending", t)
+		FailIfFalse(string(text) == "a\nb", fmt.Sprintf("Incorrect decoded text %q", text), t)
+	})
+
+	t.Run("UTF-16BE without BOM", func(t *testing.T) {
+		format, text := DecodeFileData([]byte("\x00a\x00b\x00\n"))
+
+		FailIfFalse(format.Encoding == Encoding_UTF16BE, "Expected UTF-16BE encoding", t)
+		FailIfFalse(!format.BOM, "Expected no BOM", t)
+		FailIfFalse(string(text) == "ab", fmt.Sprintf("Incorrect decoded text %q", text), t)
+	})
+
+	t.Run("Invalid UTF-16", func(t *testing.T) {
+		format, text := DecodeFileData([]byte("\xFF\xFE\x3D\xD8\x00\xDEa\x00"))
+		FailIfFalse(!format.Invalid && string(text) == "😀a", fmt.Sprintf("Expected a surrogate pair to be valid, got %q", text), t)
+
+		format, text = DecodeFileData([]byte("\xFF\xFEa\x00b\x00c"))
+		FailIfFalse(format.Invalid && format.Encoding == Encoding_UTF16LE, "Expected a trailing odd byte to be invalid", t)
+		FailIfFalse(string(text) == "ab\uFFFD", fmt.Sprintf("Expected the odd byte to be replaced, got %q", text), t)
+		FailIfFalse(strings.HasSuffix(format.String(), "[invalid]"), fmt.Sprintf("Expected the format to show it is invalid, got %s", format.String()), t)
+
+		format, _ = DecodeFileData([]byte("\xFE\xFF\x00a\xDC\x00\x00b"))
+		FailIfFalse(format.Invalid && format.Encoding == Encoding_UTF16BE, "Expected a surrogate without a pair to be invalid", t)
+
+		data := []byte("\x00a\x00b\xD8\x00\x00c\x00d\x00e\x00f\x00g\x00h\x00i\x00j\x00k\x00\n")
+		format, text = DecodeFileData(data)
+		FailIfFalse(format.Encoding == Encoding_Latin1 && !format.Invalid, fmt.Sprintf("Expected invalid UTF-16 without a BOM to be read as Latin-1, got %s", format.String()), t)
+		FailIfFalse(bytes.Equal(EncodeFileData(format, strings.Split(string(text), "\n")), data), "Expected the Latin-1 file to be saved unchanged", t)
+	})
+}
+
+func TestFileDataRoundTrip(t *testing.T) {
+	inputs := [][]byte{
+		[]byte("package main\n\nfunc main() {}\n"),
+		[]byte("line1\r\nline2\r\n"),
+		[]byte("\xEF\xBB\xBFno trailing\r\nnew line"),
+		[]byte("old\rmac\r"),
+		[]byte("caf\xE9 cr\xE8me\n"),
+		[]byte("\xFF\xFEa\x00\r\x00\n\x00b\x00"),
+		[]byte("\xFE\xFF\x00a\x00\n\x00b\x00\n"),
+		[]byte(""),
+	}
+
+	for _, input := range inputs {
+		format, text := DecodeFileData(input)
+		output := EncodeFileData(format, strings.Split(string(text), "\n"))
+
+		FailIfFalse(bytes.Equal(input, output), fmt.Sprintf("Round trip of %q produced %q", input, output), t)
+	}
+}
@@ -1,24 +1,36 @@
 package main
 
 import (
-	"strings"
+	"fmt"
+	"path/filepath"
+	"sort"
 
 	"github.com/veandco/go-sdl2/sdl"
 )
 
 type FileSearchEntry struct {
-	Name     string
-	FullPath string
+	Name      string
+	FullPath  string
+	MatchPath string // The part of the full path the query is matched against, relative to the project root
+	Detail    string // Shown instead of the full path when set
+
+	// Where to put the cursor after opening the file, used by symbols
+	Line   int32
+	Column int32
+}
 
-	NameClean     string
-	FullPathClean string
+type FileSearchResult struct {
+	Index     int // Index into the file entries array
+	Score     int
+	Positions []int // Offsets of the matched characters in MatchPath
 }
 
 type FileSearch struct {
 	SelectionIndex int32
-	SearchQuery    strings.Builder
+	ScrollOffset   int32
+	VisibleCount   int32
+	SearchQuery    TextInput
 
-	Cursor      InputCursor
 	Width       int32
 	LineHeight  int32
 	LineSpacing int32
@@ -26,19 +38,63 @@ type FileSearch struct {
 	Font12      *Font
 
 	FileEntries  []FileSearchEntry
-	FoundEntries []int // Array of indexes into file entries array
+	FoundEntries []FileSearchResult
+	Indexing     bool     // More entries are on their way
+	Recent       []string // Recently opened paths, most recent first. These are listed first and ranked higher
+
+	// Symbols provides the entries for queries starting with @ (symbols in the current file) or # (symbols in the project). Symbol search is off when it is nil
+	Symbols func(scope byte) []FileSearchEntry
+	files   []FileSearchEntry
+	scope   byte
 
-	CloseCallback func(string)
+	CloseCallback func(*FileSearchEntry) // nil when nothing was picked
 
 	altWasPressed bool
 	firstTime     bool
 }
 
-func PathsToFileSearchEntries(paths []string) (result []FileSearchEntry) {
+// Matches in recently opened files get up to this much extra score
+const fileSearchRecencyBonus = 24
+
+func PathsToFileSearchEntries(paths []string, root string) (result []FileSearchEntry) {
 	for _, path := range paths {
+		matchPath := path
+		if root != "" {
+			relPath, err := filepath.Rel(root, path)
+			if err == nil && !isOutsideRoot(relPath) {
+				matchPath = filepath.ToSlash(relPath)
+			}
+		}
+
 		result = append(result, FileSearchEntry{
-			Name:     GetFileNameFromPath(path),
-			FullPath: path,
+			Name:      GetFileNameFromPath(path),
+			FullPath:  path,
+			MatchPath: matchPath,
+		})
+	}
+
+	return
+}
+
+// SymbolsToFileSearchEntries makes entries that match by the symbol name and show the kind and location of the symbol
+func SymbolsToFileSearchEntries(symbols []Symbol, root string) (result []FileSearchEntry) {
+	for _, symbol := range symbols {
+		location := symbol.Filepath
+		if root != "" {
+			relPath, err := filepath.Rel(root, symbol.Filepath)
+			if err == nil && !isOutsideRoot(relPath) {
+				location = filepath.ToSlash(relPath)
+			}
+		}
+
+		name := symbol.FullName()
+		result = append(result, FileSearchEntry{
+			Name:      name,
+			FullPath:  symbol.Filepath,
+			MatchPath: name,
+			Detail:    fmt.Sprintf("%s  %s:%d", symbol.Kind, location, symbol.Line+1),
+			Line:      symbol.Line,
+			Column:    symbol.Column,
 		})
 	}
 
@@ -46,70 +102,156 @@ func PathsToFileSearchEntries(paths []string) (result []FileSearchEntry) {
 }
 
 func CreateFileSearch(lineHeight int32, font14 *Font, font12 *Font) (result FileSearch) {
-	result.Cursor = CreateInputCursor(lineHeight, int32(font14.CharacterWidth))
-	result.Width = 500
-	result.LineHeight = lineHeight
-	result.LineSpacing = (lineHeight - int32(font14.Size)) / 2
-	result.Font14 = font14
-	result.Font12 = font12
+	result.SearchQuery = CreateTextInput(lineHeight, int32(font14.CharacterWidth))
+	result.Width = popupWidth
+	result.VisibleCount = 8
+	result.SetFonts(lineHeight, font14, font12)
 
 	return
 }
 
-func (fs *FileSearch) Open(availableFiles []FileSearchEntry, onClose func(string)) {
+// SetFonts changes the font of the entries and the smaller one of their paths
+func (fs *FileSearch) SetFonts(lineHeight int32, font14 *Font, font12 *Font) {
+	fs.SearchQuery.SetFont(lineHeight, int32(font14.CharacterWidth))
+	fs.LineHeight = lineHeight
+	fs.LineSpacing = (lineHeight - int32(font14.Size)) / 2
+	fs.Font14 = font14
+	fs.Font12 = font12
+}
+
+func (fs *FileSearch) Open(availableFiles []FileSearchEntry, onClose func(*FileSearchEntry)) {
+	fs.OpenWithQuery(availableFiles, "", onClose)
+}
+
+// OpenWithQuery opens the search with some text already typed, like "@" to go straight to the symbols of the current file
+func (fs *FileSearch) OpenWithQuery(availableFiles []FileSearchEntry, query string, onClose func(*FileSearchEntry)) {
 	fs.SelectionIndex = 0
-	fs.Cursor.Column = 0
+	fs.ScrollOffset = 0
 	fs.SearchQuery.Reset()
+	fs.SearchQuery.SetText(query)
 
+	fs.files = availableFiles
 	fs.FileEntries = availableFiles
-	for i := 0; i < len(fs.FileEntries); i += 1 {
-		fs.FileEntries[i].NameClean = cleanString(fs.FileEntries[i].Name)
-		fs.FileEntries[i].FullPathClean = cleanString(fs.FileEntries[i].FullPath)
-	}
-
-	size := Min(len(fs.FileEntries), 5)
-	fs.FoundEntries = make([]int, size)
-	for i := 0; i < size; i += 1 {
-		fs.FoundEntries[i] = i
-	}
+	fs.scope = 0
+	fs.updateSearchResults()
 
 	fs.CloseCallback = onClose
 
 	fs.firstTime = true
 }
 
+// SetEntries replaces the available files while the search is open, keeping the query and the selected file
+func (fs *FileSearch) SetEntries(availableFiles []FileSearchEntry) {
+	// Symbols of a file share its path, so the line and the name tell them apart
+	var selected *FileSearchEntry
+	if int(fs.SelectionIndex) < len(fs.FoundEntries) {
+		entry := fs.FileEntries[fs.FoundEntries[fs.SelectionIndex].Index]
+		selected = &entry
+	}
+
+	fs.files = availableFiles
+	if fs.scope == 0 {
+		fs.FileEntries = availableFiles
+	} else {
+		fs.FileEntries = fs.Symbols(fs.scope) // The index may have found more symbols since they were listed
+	}
+	fs.updateSearchResults()
+
+	for index, result := range fs.FoundEntries {
+		entry := fs.FileEntries[result.Index]
+		if selected != nil && entry.FullPath == selected.FullPath && entry.Line == selected.Line && entry.Name == selected.Name {
+			fs.SelectionIndex = int32(index)
+			break
+		}
+	}
+	fs.moveSelection(0)
+}
+
 func (fs *FileSearch) Close() {
-	fs.CloseCallback("")
+	fs.CloseCallback(nil)
 }
 
 func (fs *FileSearch) Submit() {
-	fs.CloseCallback(fs.FileEntries[fs.FoundEntries[fs.SelectionIndex]].FullPath)
+	fs.SearchQuery.AddHistory(fs.SearchQuery.String())
+
+	entry := fs.FileEntries[fs.FoundEntries[fs.SelectionIndex].Index]
+	fs.CloseCallback(&entry)
 }
 
 func (fs *FileSearch) updateSearchResults() {
-	fs.FoundEntries = make([]int, 0)
-	if fs.SearchQuery.Len() == 0 {
-		return
+	fs.FoundEntries = make([]FileSearchResult, 0)
+	fs.SelectionIndex = 0
+	fs.ScrollOffset = 0
+
+	recency := map[string]int{}
+	for rank, path := range fs.Recent {
+		if _, ok := recency[path]; !ok {
+			recency[path] = fileSearchRecencyBonus * (len(fs.Recent) - rank) / len(fs.Recent)
+		}
 	}
 
 	query := fs.SearchQuery.String()
+
+	scope := byte(0)
+	if fs.Symbols != nil && len(query) > 0 && (query[0] == '@' || query[0] == '#') {
+		scope = query[0]
+		query = query[1:]
+	}
+
+	if scope != fs.scope {
+		fs.scope = scope
+		if scope == 0 {
+			fs.FileEntries = fs.files
+		} else {
+			fs.FileEntries = fs.Symbols(scope)
+		}
+	}
+
 	for index, entry := range fs.FileEntries {
-		if strings.Contains(entry.NameClean, strings.ToLower(query)) || strings.Contains(entry.FullPathClean, strings.ToLower(query)) {
-			fs.FoundEntries = append(fs.FoundEntries, index)
+		score, positions, ok := FuzzyMatch(query, entry.MatchPath)
+		if !ok {
+			continue
 		}
+
+		fs.FoundEntries = append(fs.FoundEntries, FileSearchResult{
+			Index:     index,
+			Score:     score + recency[entry.FullPath],
+			Positions: positions,
+		})
 	}
 
-	if len(fs.FoundEntries) > 0 {
-		fs.SelectionIndex = 0
+	// Shorter paths win ties, they are usually what is meant. Without a query, recent files come first and the rest keep their order
+	sort.SliceStable(fs.FoundEntries, func(i, j int) bool {
+		a := fs.FoundEntries[i]
+		b := fs.FoundEntries[j]
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+
+		if query == "" {
+			return false
+		}
+
+		return len(fs.FileEntries[a.Index].MatchPath) < len(fs.FileEntries[b.Index].MatchPath)
+	})
+}
+
+func (fs *FileSearch) moveSelection(amount int32) {
+	fs.SelectionIndex = int32(Max(Min(int(fs.SelectionIndex+amount), len(fs.FoundEntries)-1), 0))
+
+	if fs.SelectionIndex < fs.ScrollOffset {
+		fs.ScrollOffset = fs.SelectionIndex
+	} else if fs.SelectionIndex >= fs.ScrollOffset+fs.VisibleCount {
+		fs.ScrollOffset = fs.SelectionIndex - fs.VisibleCount + 1
 	}
 }
 
 func (fs *FileSearch) Tick(input Input) {
 	if input.Alt {
 		if input.TypedCharacter == 'j' {
-			fs.SelectionIndex = int32(Min(int(fs.SelectionIndex)+1, len(fs.FoundEntries)-1))
+			fs.moveSelection(1)
 		} else if input.TypedCharacter == 'k' {
-			fs.SelectionIndex = int32(Max(int(fs.SelectionIndex)-1, 0))
+			fs.moveSelection(-1)
 		}
 
 		fs.altWasPressed = true
@@ -132,68 +274,65 @@ func (fs *FileSearch) Tick(input Input) {
 		return
 	}
 
-	if input.Backspace {
-		if input.Ctrl {
-			fs.Cursor.Column = 0
-			fs.SearchQuery.Reset()
-			fs.updateSearchResults()
-		} else if fs.Cursor.Column > 0 {
-			fs.Cursor.Column -= 1
-			str := fs.SearchQuery.String()
-			fs.SearchQuery.Reset()
+	if input.TypedCharacter == '\t' || input.TypedCharacter == '\n' {
+		if len(fs.FoundEntries) > 0 {
+			fs.Submit()
+		}
+		return
+	}
 
-			str = str[:len(str)-1]
-			fs.SearchQuery.WriteString(str)
+	if fs.SearchQuery.Tick(input) {
+		fs.updateSearchResults()
+	}
+}
 
-			fs.updateSearchResults()
-		}
+// TickMouse submits the entry that was clicked and closes the search on a click anywhere else. The wheel moves the selection
+func (fs *FileSearch) TickMouse(mouse Mouse, parentRect *sdl.Rect) {
+	if mouse.WheelY != 0 {
+		fs.moveSelection(-mouse.WheelY)
+	}
 
+	if !mouse.Pressed {
 		return
 	}
 
-	if input.TypedCharacter != 0 {
-		if input.TypedCharacter == '\t' || input.TypedCharacter == '\n' {
-			if len(fs.FoundEntries) > 0 {
-				fs.Submit()
-			}
-		} else {
-			fs.SearchQuery.WriteByte(input.TypedCharacter)
-			fs.Cursor.Column += 1
-			fs.updateSearchResults()
+	point := mouse.Point()
+	inputRect := fs.inputRect(parentRect)
+	visibleEntries := fs.visibleEntries()
+	for index := range visibleEntries {
+		entryRect := fs.entryRect(inputRect, index)
+		if point.InRect(&entryRect) {
+			fs.SelectionIndex = fs.ScrollOffset + int32(index)
+			fs.Submit()
+			return
 		}
-		return
 	}
-}
 
-func (fs *FileSearch) Render(renderer *sdl.Renderer, parentRect *sdl.Rect, theme *FileSearchTheme) {
-	inputRect := sdl.Rect{
-		X: parentRect.W/2 - fs.Width/2,
-		Y: parentRect.Y + int32(float32(parentRect.H)*0.15),
-		W: fs.Width,
-		H: fs.LineHeight + 10,
+	borderRect := fs.borderRect(inputRect, len(visibleEntries))
+	if !point.InRect(&borderRect) {
+		fs.Close()
 	}
+}
 
-	borderRect := expandRect(sdl.Rect{
-		X: inputRect.X,
-		Y: inputRect.Y,
-		W: fs.Width,
-		H: inputRect.H + int32(len(fs.FoundEntries))*(fs.LineHeight+fs.LineSpacing*2),
-	}, 1)
+func (fs *FileSearch) Render(renderer Canvas, parentRect *sdl.Rect, theme *FileSearchTheme) {
+	inputRect := fs.inputRect(parentRect)
+	visibleEntries := fs.visibleEntries()
+	borderRect := fs.borderRect(inputRect, len(visibleEntries))
 
 	DrawRect(renderer, &borderRect, theme.BorderColor)
 	DrawRect(renderer, &inputRect, theme.InputBackgroundColor)
-	fs.Cursor.Render(renderer, inputRect, theme.CursorColor)
-
-	query := fs.SearchQuery.String()
-	if len(query) > 0 {
-		queryWidth := fs.Font14.GetStringWidth(query)
-		queryRect := sdl.Rect{
-			X: inputRect.X + 5,
-			Y: inputRect.Y + (inputRect.H-int32(fs.Font14.Size))/2,
-			W: queryWidth,
-			H: int32(fs.Font14.Size),
+	fs.SearchQuery.Render(renderer, fs.Font14, inputRect, theme.InputTextColor, theme.InputSelectionColor, theme.CursorColor)
+
+	if fs.Indexing {
+		indexingText := "indexing..."
+		indexingWidth := fs.Font12.GetStringWidth(indexingText)
+		indexingRect := sdl.Rect{
+			X: inputRect.X + inputRect.W - 5 - indexingWidth,
+			Y: inputRect.Y + (inputRect.H-int32(fs.Font12.Size))/2,
+			W: indexingWidth,
+			H: int32(fs.Font12.Size),
 		}
-		DrawText(renderer, fs.Font14, query, &queryRect, theme.InputTextColor)
+		DrawText(renderer, fs.Font12, indexingText, &indexingRect, theme.ResultPathColor)
 	}
 
 	defaultBgColor := theme.ResultBackgroundColor
@@ -202,38 +341,101 @@ func (fs *FileSearch) Render(renderer *sdl.Renderer, parentRect *sdl.Rect, theme
 	activeBgColor := theme.ResultActiveColor
 	activeTextColor := theme.ResultNameActiveColor
 
-	for index, res := range fs.FoundEntries {
+	for index, res := range visibleEntries {
+		entry := fs.FileEntries[res.Index]
+
 		entryColor := defaultBgColor
 		textColor := defaultTextColor
-		if index == int(fs.SelectionIndex) {
+		if index+int(fs.ScrollOffset) == int(fs.SelectionIndex) {
 			entryColor = activeBgColor
 			textColor = activeTextColor
 		}
 
-		entryRect := sdl.Rect{
-			X: parentRect.W/2 - fs.Width/2,
-			Y: inputRect.Y + inputRect.H + int32(index)*(fs.LineHeight+fs.LineSpacing*2),
-			W: inputRect.W,
-			H: fs.LineHeight + fs.LineSpacing*2,
-		}
+		entryRect := fs.entryRect(inputRect, index)
 		DrawRect(renderer, &entryRect, entryColor)
 
-		txtWidth := fs.Font14.GetStringWidth(fs.FileEntries[res].Name)
-		txtRect := sdl.Rect{
-			X: entryRect.X + 5,
-			Y: entryRect.Y + (entryRect.H-int32(fs.Font14.Size))/2,
-			W: txtWidth,
-			H: int32(fs.Font14.Size),
+		// The name and the full path both end with the matched path, so the offsets only need to be shifted
+		nameOffset := len(entry.MatchPath) - len(entry.Name)
+		pathOffset := len(entry.MatchPath) - len(entry.FullPath)
+
+		nameY := entryRect.Y + (entryRect.H-int32(fs.Font14.Size))/2
+		drawMatchedText(renderer, fs.Font14, entry.Name, res.Positions, nameOffset, entryRect.X+5, nameY, textColor, theme.ResultMatchColor)
+
+		path := entry.FullPath
+		positions := res.Positions
+		if entry.Detail != "" {
+			path = entry.Detail
+			positions = nil
 		}
-		DrawText(renderer, fs.Font14, fs.FileEntries[res].Name, &txtRect, textColor)
 
-		pathWidth := fs.Font12.GetStringWidth(fs.FileEntries[res].FullPath)
-		pathRect := sdl.Rect{
-			X: entryRect.X + entryRect.W - 5 - pathWidth,
-			Y: entryRect.Y + (entryRect.H-int32(fs.Font12.Size))/2,
-			W: pathWidth,
-			H: int32(fs.Font12.Size),
+		pathWidth := fs.Font12.GetStringWidth(path)
+		pathY := entryRect.Y + (entryRect.H-int32(fs.Font12.Size))/2
+		drawMatchedText(renderer, fs.Font12, path, positions, pathOffset, entryRect.X+entryRect.W-5-pathWidth, pathY, theme.ResultPathColor, theme.ResultMatchColor)
+	}
+}
+
+func (fs *FileSearch) inputRect(parentRect *sdl.Rect) sdl.Rect {
+	return sdl.Rect{
+		X: parentRect.W/2 - fs.Width/2,
+		Y: parentRect.Y + int32(float32(parentRect.H)*0.15),
+		W: fs.Width,
+		H: fs.LineHeight + 10,
+	}
+}
+
+func (fs *FileSearch) visibleEntries() []FileSearchResult {
+	return fs.FoundEntries[fs.ScrollOffset:Min(int(fs.ScrollOffset+fs.VisibleCount), len(fs.FoundEntries))]
+}
+
+func (fs *FileSearch) borderRect(inputRect sdl.Rect, count int) sdl.Rect {
+	return expandRect(sdl.Rect{
+		X: inputRect.X,
+		Y: inputRect.Y,
+		W: fs.Width,
+		H: inputRect.H + int32(count)*(fs.LineHeight+fs.LineSpacing*2),
+	}, 1)
+}
+
+// entryRect returns where the visible entry at the index is drawn, the first one is right below the input
+func (fs *FileSearch) entryRect(inputRect sdl.Rect, index int) sdl.Rect {
+	return sdl.Rect{
+		X: inputRect.X,
+		Y: inputRect.Y + inputRect.H + int32(index)*(fs.LineHeight+fs.LineSpacing*2),
+		W: inputRect.W,
+		H: fs.LineHeight + fs.LineSpacing*2,
+	}
+}
+
+// drawMatchedText draws the text in runs, the characters at positions (shifted by offset) in the match color and the rest in the regular color
+func drawMatchedText(renderer Canvas, font *Font, text string, positions []int, offset int, x int32, y int32, color sdl.Color, matchColor sdl.Color) {
+	matched := make([]bool, len(text))
+	for _, position := range positions {
+		position -= offset
+		if position >= 0 && position < len(text) {
+			matched[position] = true
+		}
+	}
+
+	start := 0
+	for end := 1; end <= len(text); end += 1 {
+		if end < len(text) && matched[end] == matched[start] {
+			continue
+		}
+
+		runColor := color
+		if matched[start] {
+			runColor = matchColor
 		}
-		DrawText(renderer, fs.Font12, fs.FileEntries[res].FullPath, &pathRect, theme.ResultPathColor)
+
+		run := text[start:end]
+		rect := sdl.Rect{
+			X: x + font.GetStringWidth(text[:start]),
+			Y: y,
+			W: font.GetStringWidth(run),
+			H: int32(font.Size),
+		}
+		DrawText(renderer, font, run, &rect, runColor)
+
+		start = end
 	}
 }
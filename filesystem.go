@@ -1,15 +1,30 @@
 package main
 
 import (
+	"crypto/sha256"
+	"fmt"
 	"io/ioutil"
+	"log"
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/sqweek/dialog"
 )
 
 func SaveFile(path string, data []string) (string, bool) {
+	return WriteFile(path, []byte(strings.Join(data, "\n")))
+}
+
+// FileStamp remembers what a file looked like on disk when it was last read or written
+type FileStamp struct {
+	ModTime time.Time
+	Size    int64
+	Hash    [sha256.Size]byte
+}
+
+func WriteFile(path string, data []byte) (string, bool) {
 	if path == "" {
 		newPath, err := dialog.File().Filter("All files (*.*)", "*").Filter("Text file (*.txt)", "txt").Filter("Go file (*.go)", "go").Save()
 		if err != dialog.ErrCancelled {
@@ -21,18 +36,78 @@ func SaveFile(path string, data []string) (string, bool) {
 		path = newPath
 	}
 
-	file, err := os.Create(path)
+	err := writeFileAtomic(path, data)
 	if err != nil {
+		log.Printf("Unable to save %s: %s", path, err)
 		return "", false
 	}
-	defer file.Close()
-
-	file.WriteString(strings.Join(data, "\n"))
-	file.Sync()
 
 	return path, true
 }
 
+// BackupFile copies the current contents of the file into the backup directory before it gets overwritten
+func BackupFile(path string, backupDir string) bool {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return os.IsNotExist(err) // Nothing to back up
+	}
+
+	err = os.MkdirAll(backupDir, 0755)
+	if err != nil {
+		log.Printf("Unable to create backup directory %s: %s", backupDir, err)
+		return false
+	}
+
+	err = writeFileAtomic(filepath.Join(backupDir, pathToFileName(path)+"~"), data)
+	if err != nil {
+		log.Printf("Unable to back up %s: %s", path, err)
+		return false
+	}
+
+	return true
+}
+
+func CreateFileStamp(path string, data []byte) (result FileStamp) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return
+	}
+
+	result.ModTime = info.ModTime()
+	result.Size = info.Size()
+	result.Hash = sha256.Sum256(data)
+
+	return
+}
+
+// FileChangedOnDisk reports whether the file is different from what it was when the stamp was taken
+func FileChangedOnDisk(path string, stamp FileStamp) bool {
+	if stamp.ModTime.IsZero() {
+		return false
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return false // File was removed, saving will simply recreate it
+	}
+
+	if info.ModTime().Equal(stamp.ModTime) && info.Size() == stamp.Size {
+		return false
+	}
+
+	// Modification time alone is not enough, checking out a branch touches files without changing them
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return true
+	}
+
+	return sha256.Sum256(data) != stamp.Hash
+}
+
+func ConfirmDialog(title string, message string) bool {
+	return dialog.Message("%s", message).Title(title).YesNo()
+}
+
 func OpenFile(path string) ([]byte, string, bool) {
 	if path == "" {
 		newPath, err := dialog.File().Filter("All files (*.*)", "*").Filter("Text file (*.txt)", "txt").Filter("Go file (*.go)", "go").Load()
@@ -53,9 +128,14 @@ func OpenFile(path string) ([]byte, string, bool) {
 	return data, path, true
 }
 
-func CreateDirectory(path string) {
-	err := os.Mkdir(path, 0755)
-	checkError(err)
+func CreateDirectory(path string) bool {
+	err := os.MkdirAll(path, 0755)
+	if err != nil {
+		log.Printf("Unable to create %s: %s", path, err)
+		return false
+	}
+
+	return true
 }
 
 func SelectDirectory() (string, bool) {
@@ -69,34 +149,82 @@ func SelectDirectory() (string, bool) {
 	return path, true
 }
 
-func ReadDirectory(dirPath string, exclude []string) (result []string) {
-	files, err := ioutil.ReadDir(dirPath)
-	checkError(err)
-
-	shouldInclude := func(excludes []string, path string) bool {
-		for _, ex := range excludes {
-			if strings.Contains(path, ex) {
-				return false
-			}
+// writeFileAtomic writes the data into a temporary file next to the target and renames it over the target, so the file is never left half written
+func writeFileAtomic(path string, data []byte) error {
+	// Write through symlinks instead of replacing them with a regular file
+	target, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			return err
 		}
 
-		return true
+		target = path
 	}
 
-	for _, file := range files {
-		fullPath := filepath.Join(dirPath, file.Name())
-		if !shouldInclude(exclude, fullPath) {
-			continue
-		}
+	mode := os.FileMode(0644)
+	info, err := os.Stat(target)
+	if err == nil {
+		mode = info.Mode().Perm()
+	}
 
-		if !file.IsDir() {
-			result = append(result, fullPath)
-		} else {
-			result = append(result, ReadDirectory(fullPath, exclude)...)
-		}
+	dir := filepath.Dir(target)
+	temp, err := ioutil.TempFile(dir, fmt.Sprintf(".%s.*.tmp", filepath.Base(target)))
+	if err != nil {
+		return err
 	}
 
-	return
+	tempPath := temp.Name()
+	cleanup := func(err error) error {
+		temp.Close()
+		os.Remove(tempPath)
+		return err
+	}
+
+	_, err = temp.Write(data)
+	if err != nil {
+		return cleanup(err)
+	}
+
+	err = temp.Sync()
+	if err != nil {
+		return cleanup(err)
+	}
+
+	err = temp.Chmod(mode)
+	if err != nil {
+		return cleanup(err)
+	}
+
+	err = temp.Close()
+	if err != nil {
+		return cleanup(err)
+	}
+
+	err = os.Rename(tempPath, target)
+	if err != nil {
+		os.Remove(tempPath)
+		return err
+	}
+
+	// Make sure the rename itself reaches the disk. Not supported on every platform, so errors are ignored
+	dirFile, err := os.Open(dir)
+	if err == nil {
+		dirFile.Sync()
+		dirFile.Close()
+	}
+
+	return nil
+}
+
+// pathToFileName turns a full path into a single file name, so that files with the same name from different directories do not collide
+func pathToFileName(path string) string {
+	absolute, err := filepath.Abs(path)
+	if err == nil {
+		path = absolute
+	}
+
+	replacer := strings.NewReplacer("/", "%", "\\", "%", ":", "%")
+	return replacer.Replace(path)
 }
 
 func GetFileNameFromPath(path string) string {
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestWriteFile(t *testing.T) {
+	t.Run("Replace existing file and keep its permissions", func(t *testing.T) {
+		dir := t.TempDir()
+		path := filepath.Join(dir, "main.go")
+		ioutil.WriteFile(path, []byte("old"), 0600)
+
+		_, success := WriteFile(path, []byte("new"))
+		FailNowIfFalse(success, "Expected the file to be saved", t)
+
+		data, _ := ioutil.ReadFile(path)
+		FailIfFalse(string(data) == "new", "Incorrect file contents after saving", t)
+
+		if runtime.GOOS != "windows" {
+			info, _ := os.Stat(path)
+			FailIfFalse(info.Mode().Perm() == 0600, "File permissions were not kept", t)
+		}
+
+		entries, _ := ioutil.ReadDir(dir)
+		FailIfFalse(len(entries) == 1, "Temporary file was left behind", t)
+	})
+
+	t.Run("Write through a symlink", func(t *testing.T) {
+		if runtime.GOOS == "windows" {
+			t.Skip("Symlinks need special privileges on windows")
+		}
+
+		dir := t.TempDir()
+		target := filepath.Join(dir, "target.txt")
+		link := filepath.Join(dir, "link.txt")
+		ioutil.WriteFile(target, []byte("old"), 0644)
+		os.Symlink(target, link)
+
+		_, success := WriteFile(link, []byte("new"))
+		FailNowIfFalse(success, "Expected the file to be saved", t)
+
+		info, _ := os.Lstat(link)
+		FailIfFalse(info.Mode()&os.ModeSymlink != 0, "Symlink was replaced with a regular file", t)
+
+		data, _ := ioutil.ReadFile(target)
+		FailIfFalse(string(data) == "new", "Symlink target was not updated", t)
+	})
+}
+
+func TestFileChangedOnDisk(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "file.txt")
+	ioutil.WriteFile(path, []byte("original"), 0644)
+
+	stamp := CreateFileStamp(path, []byte("original"))
+	FailIfFalse(!FileChangedOnDisk(path, stamp), "Untouched file should not be reported as changed", t)
+
+	// Same contents with a new modification time
+	later := stamp.ModTime.Add(time.Minute)
+	os.Chtimes(path, later, later)
+	FailIfFalse(!FileChangedOnDisk(path, stamp), "Touched file with the same contents should not be reported as changed", t)
+
+	ioutil.WriteFile(path, []byte("modified"), 0644)
+	os.Chtimes(path, later.Add(time.Minute), later.Add(time.Minute))
+	FailIfFalse(FileChangedOnDisk(path, stamp), "Modified file should be reported as changed", t)
+
+	FailIfFalse(!FileChangedOnDisk(path, FileStamp{}), "File that was never read from disk should not be reported as changed", t)
+}
+
+func TestBackupFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "file.txt")
+	backupDir := filepath.Join(dir, "backups")
+	ioutil.WriteFile(path, []byte("contents"), 0644)
+
+	FailNowIfFalse(BackupFile(path, backupDir), "Expected the backup to succeed", t)
+
+	entries, _ := ioutil.ReadDir(backupDir)
+	FailNowIfFalse(len(entries) == 1, "Expected exactly one backup file", t)
+
+	data, _ := ioutil.ReadFile(filepath.Join(backupDir, entries[0].Name()))
+	FailIfFalse(string(data) == "contents", "Incorrect backup contents", t)
+}
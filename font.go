@@ -1,6 +1,9 @@
 package main
 
 import (
+	"log"
+
+	"github.com/veandco/go-sdl2/sdl"
 	"github.com/veandco/go-sdl2/ttf"
 )
 
@@ -8,6 +11,8 @@ type Font struct {
 	Data           *ttf.Font
 	Size           int
 	CharacterWidth int
+
+	atlas *GlyphAtlas
 }
 
 func LoadFont(path string, size int) (result Font) {
@@ -25,10 +30,76 @@ func LoadFont(path string, size int) (result Font) {
 	return
 }
 
+// TryLoadFont is LoadFont for fonts that can be changed while the editor runs, a font that can't be opened is logged
+// instead of closing the editor
+func TryLoadFont(path string, size int) (result Font, ok bool) {
+	font, err := ttf.OpenFont(path, size)
+	if err != nil {
+		log.Printf("Could not open the font %s: %s", path, err)
+		return result, false
+	}
+
+	metrics, err := font.GlyphMetrics('m')
+	if err != nil {
+		log.Printf("Could not measure the font %s: %s", path, err)
+		font.Close()
+		return result, false
+	}
+
+	result.Data = font
+	result.Size = size
+	result.CharacterWidth = metrics.Advance
+
+	return result, true
+}
+
+// UnloadFonts unloads every font once. Copies of a font share its data and sometimes its atlas
+func UnloadFonts(fonts ...*Font) {
+	closed := map[*ttf.Font]bool{}
+	destroyed := map[*GlyphAtlas]bool{}
+	for _, font := range fonts {
+		if font == nil {
+			continue
+		}
+
+		if font.atlas != nil && !destroyed[font.atlas] {
+			font.atlas.Destroy()
+			destroyed[font.atlas] = true
+		}
+		font.atlas = nil
+
+		if font.Data != nil && !closed[font.Data] {
+			font.Data.Close()
+			closed[font.Data] = true
+		}
+	}
+}
+
 func (font *Font) GetStringWidth(text string) int32 {
 	return int32(len(text) * font.CharacterWidth)
 }
 
+// Atlas returns the glyphs of the font for the renderer. They are rendered the first time text is drawn with the font
+func (font *Font) Atlas(renderer *sdl.Renderer) *GlyphAtlas {
+	if font.atlas != nil && font.atlas.Renderer == renderer {
+		return font.atlas
+	}
+
+	if font.atlas != nil {
+		font.atlas.Destroy()
+	}
+
+	atlas := CreateGlyphAtlas(renderer, font.Data)
+	font.atlas = &atlas
+
+	return font.atlas
+}
+
 func (font *Font) Unload() {
+	if font.atlas != nil {
+		font.atlas.Destroy()
+		font.atlas = nil
+	}
+
 	font.Data.Close()
 }
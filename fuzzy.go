@@ -0,0 +1,179 @@
+package main
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Scores are modelled after fzf: every matched character is worth the same, gaps cost a little and characters at the start of a word are worth more
+const (
+	fuzzyScoreMatch        = 16
+	fuzzyScoreGapStart     = -3
+	fuzzyScoreGapExtension = -1
+
+	fuzzyBonusSegment     = 10 // After a path separator
+	fuzzyBonusBoundary    = 8  // After a space, dash, underscore, dot and the like
+	fuzzyBonusCamelCase   = 7  // Upper case letter after a lower case letter, or a digit after a letter
+	fuzzyBonusConsecutive = 4
+	fuzzyBonusFileName    = 2 // Characters in the last path segment
+	fuzzyBonusFirstChar   = 2 // The bonus of the first matched character is multiplied by this
+)
+
+// FuzzyMatch finds the query characters in order in the text and returns the score of the best alignment and the byte offsets of the matched characters.
+// The match ignores case unless the query has upper case letters
+func FuzzyMatch(query string, text string) (score int, positions []int, ok bool) {
+	if query == "" {
+		return 0, nil, true
+	}
+
+	// Bonuses are found before lowering the case, camelCase needs it
+	bonuses := fuzzyBonuses(text)
+
+	caseSensitive := strings.ToLower(query) != query
+	if !caseSensitive {
+		text = toLowerASCII(text)
+	}
+
+	// Most texts do not match at all, which is much cheaper to find out than the best alignment
+	if !isSubsequence(query, text) {
+		return 0, nil, false
+	}
+
+	n := len(query)
+	m := len(text)
+
+	// scores[i][j] is the best score with query[i] matched at text[j], from[i][j] is where query[i-1] was matched in that case
+	const none = -1 << 30
+	scores := make([][]int, n)
+	from := make([][]int, n)
+	for i := 0; i < n; i += 1 {
+		scores[i] = make([]int, m)
+		from[i] = make([]int, m)
+	}
+
+	for i := 0; i < n; i += 1 {
+		carry := none // Best score of the previous row with a gap before j
+		carryFrom := -1
+
+		for j := 0; j < m; j += 1 {
+			if i > 0 && j >= 2 {
+				if carry != none {
+					carry += fuzzyScoreGapExtension
+				}
+
+				if scores[i-1][j-2] != none && scores[i-1][j-2]+fuzzyScoreGapStart > carry {
+					carry = scores[i-1][j-2] + fuzzyScoreGapStart
+					carryFrom = j - 2
+				}
+			}
+
+			scores[i][j] = none
+			from[i][j] = -1
+			if text[j] != query[i] {
+				continue
+			}
+
+			bonus := bonuses[j]
+			if i == 0 {
+				scores[i][j] = fuzzyScoreMatch + bonus*fuzzyBonusFirstChar
+				continue
+			}
+
+			best := carry
+			bestFrom := carryFrom
+			if j > 0 && scores[i-1][j-1] != none && scores[i-1][j-1]+fuzzyBonusConsecutive >= best {
+				best = scores[i-1][j-1] + fuzzyBonusConsecutive
+				bestFrom = j - 1
+			}
+
+			if best == none {
+				continue
+			}
+
+			scores[i][j] = best + fuzzyScoreMatch + bonus
+			from[i][j] = bestFrom
+		}
+	}
+
+	end := -1
+	for j := 0; j < m; j += 1 {
+		if scores[n-1][j] != none && (end == -1 || scores[n-1][j] > scores[n-1][end]) {
+			end = j
+		}
+	}
+
+	if end == -1 {
+		return 0, nil, false
+	}
+
+	score = scores[n-1][end]
+	positions = make([]int, n)
+	for i := n - 1; i >= 0; i -= 1 {
+		positions[i] = end
+		end = from[i][end]
+	}
+
+	return score, positions, true
+}
+
+func fuzzyBonuses(text string) (result []int) {
+	result = make([]int, len(text))
+
+	fileNameStart := strings.LastIndexAny(text, "/\\") + 1
+
+	for index := 0; index < len(text); index += 1 {
+		bonus := 0
+		char := rune(text[index])
+
+		if index == 0 {
+			bonus = fuzzyBonusSegment
+		} else {
+			prev := rune(text[index-1])
+
+			if prev == '/' || prev == '\\' {
+				bonus = fuzzyBonusSegment
+			} else if !isWordCharacter(prev) && isWordCharacter(char) {
+				bonus = fuzzyBonusBoundary
+			} else if unicode.IsLower(prev) && unicode.IsUpper(char) {
+				bonus = fuzzyBonusCamelCase
+			} else if unicode.IsLetter(prev) && unicode.IsDigit(char) {
+				bonus = fuzzyBonusCamelCase
+			}
+		}
+
+		if index >= fileNameStart {
+			bonus += fuzzyBonusFileName
+		}
+
+		result[index] = bonus
+	}
+
+	return
+}
+
+func isSubsequence(query string, text string) bool {
+	i := 0
+	for j := 0; j < len(text) && i < len(query); j += 1 {
+		if text[j] == query[i] {
+			i += 1
+		}
+	}
+
+	return i == len(query)
+}
+
+func isWordCharacter(char rune) bool {
+	return unicode.IsLetter(char) || unicode.IsDigit(char)
+}
+
+// toLowerASCII lowers the case without changing the length of the text, so byte offsets stay valid
+func toLowerASCII(text string) string {
+	result := []byte(text)
+	for index, char := range result {
+		if char >= 'A' && char <= 'Z' {
+			result[index] = char + ('a' - 'A')
+		}
+	}
+
+	return string(result)
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestFuzzyMatch(t *testing.T) {
+	t.Run("Matches", func(t *testing.T) {
+		cases := []struct {
+			Query    string
+			Text     string
+			Expected bool
+		}{
+			{"main2", "cmd/main2.go", true},
+			{"v1api", "v1/api.go", true},
+			{"mg", "main.go", true},
+			{"gm", "main.go", false},
+			{"MAIN", "main.go", false},
+			{"Main", "cmd/MainWindow.go", true},
+			{"", "anything", true},
+			{"toolong", "short", false},
+		}
+
+		for _, c := range cases {
+			_, _, ok := FuzzyMatch(c.Query, c.Text)
+			FailIfFalse(ok == c.Expected, fmt.Sprintf("Expected %q in %q to be %t", c.Query, c.Text, c.Expected), t)
+		}
+	})
+
+	t.Run("Positions prefer boundaries", func(t *testing.T) {
+		_, positions, _ := FuzzyMatch("fs", "src/filesearch.go")
+		FailIfFalse(fmt.Sprint(positions) == "[4 8]", fmt.Sprintf("Expected the start of the name and the s, got %v", positions), t)
+
+		_, positions, _ = FuzzyMatch("bc", "buffer_cursor.go")
+		FailIfFalse(fmt.Sprint(positions) == "[0 7]", fmt.Sprintf("Expected word starts, got %v", positions), t)
+
+		_, positions, _ = FuzzyMatch("fw", "src/fileWatcher.go")
+		FailIfFalse(fmt.Sprint(positions) == "[4 8]", fmt.Sprintf("Expected camelCase humps, got %v", positions), t)
+	})
+
+	t.Run("Ranking", func(t *testing.T) {
+		better := [][3]string{
+			// query, expected to rank higher, expected to rank lower
+			{"buf", "buffer.go", "buf/x.go"},
+			{"app", "app.go", "wrapper.go"},
+			{"bc", "buffer_cursor.go", "abc.go"},
+			{"main", "main.go", "domain.go"},
+			{"ft", "file_format.go", "left.go"},
+		}
+
+		for _, c := range better {
+			high, _, _ := FuzzyMatch(c[0], c[1])
+			low, _, _ := FuzzyMatch(c[0], c[2])
+			FailIfFalse(high > low, fmt.Sprintf("Expected %q to rank %q (%d) above %q (%d)", c[0], c[1], high, c[2], low), t)
+		}
+	})
+}
+
+func TestFileSearchResults(t *testing.T) {
+	paths := []string{"/p/lib/domain.go", "/p/main.go", "/p/cmd/main2.go", "/p/v1/api.go", "/p/a.go", "/p/b.go", "/p/c.go"}
+
+	fs := FileSearch{VisibleCount: 3}
+	fs.Open(PathsToFileSearchEntries(paths, "/p"), func(*FileSearchEntry) {})
+
+	t.Run("Ranked by score", func(t *testing.T) {
+		fs.SearchQuery.SetText("main")
+		fs.updateSearchResults()
+
+		FailNowIfFalse(len(fs.FoundEntries) == 3, fmt.Sprintf("Expected 3 results, got %d", len(fs.FoundEntries)), t)
+		FailIfFalse(fs.FileEntries[fs.FoundEntries[0].Index].MatchPath == "main.go", "Expected main.go first", t)
+		FailIfFalse(fs.FileEntries[fs.FoundEntries[2].Index].MatchPath == "lib/domain.go", "Expected domain.go last", t)
+	})
+
+	t.Run("Paths are relative to the root", func(t *testing.T) {
+		entries := PathsToFileSearchEntries([]string{"/p/..config/a.go", "/a.go"}, "/p")
+		FailIfFalse(entries[0].MatchPath == "..config/a.go", "Expected names starting with dots to be relative", t)
+		FailIfFalse(entries[1].MatchPath == "/a.go", "Expected paths outside of the root to stay full", t)
+	})
+
+	t.Run("Digits and separators are matched", func(t *testing.T) {
+		fs.SearchQuery.SetText("v1/a")
+		fs.updateSearchResults()
+
+		FailNowIfFalse(len(fs.FoundEntries) == 1, fmt.Sprintf("Expected 1 result, got %d", len(fs.FoundEntries)), t)
+		FailIfFalse(fs.FileEntries[fs.FoundEntries[0].Index].MatchPath == "v1/api.go", "Expected v1/api.go", t)
+	})
+
+	t.Run("Recent files come first", func(t *testing.T) {
+		fs.Recent = []string{"/p/c.go", "/p/lib/domain.go"}
+		fs.SearchQuery.Reset()
+		fs.updateSearchResults()
+
+		FailNowIfFalse(len(fs.FoundEntries) == len(paths), "Expected every file without a query", t)
+		FailIfFalse(fs.FileEntries[fs.FoundEntries[0].Index].FullPath == "/p/c.go", "Expected the most recent file first", t)
+		FailIfFalse(fs.FileEntries[fs.FoundEntries[1].Index].FullPath == "/p/lib/domain.go", "Expected the second most recent file second", t)
+	})
+
+	t.Run("Selection scrolls", func(t *testing.T) {
+		for i := 0; i < 5; i += 1 {
+			fs.moveSelection(1)
+		}
+		FailIfFalse(fs.SelectionIndex == 5 && fs.ScrollOffset == 3, fmt.Sprintf("Expected selection 5 at offset 3, got %d at %d", fs.SelectionIndex, fs.ScrollOffset), t)
+
+		fs.moveSelection(10)
+		FailIfFalse(fs.SelectionIndex == int32(len(paths)-1), "Selection should stop at the last result", t)
+
+		fs.moveSelection(-10)
+		FailIfFalse(fs.SelectionIndex == 0 && fs.ScrollOffset == 0, "Selection should scroll back to the top", t)
+	})
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"path"
+	"strings"
+)
+
+// MatchGlob reports whether the pattern matches the path or one of its parent directories. The path is relative to the project root and uses forward slashes.
+// Patterns without a slash match the name of any file or directory on the path, so ".git" matches ".git/config" but not ".gitignore".
+// Patterns with a slash are matched from the root, where "**" stands for any number of directories.
+func MatchGlob(pattern string, relPath string) bool {
+	segments := strings.Split(strings.Trim(relPath, "/"), "/")
+
+	pattern = strings.TrimSuffix(pattern, "/")
+	if !strings.Contains(pattern, "/") {
+		for _, segment := range segments {
+			if matched, _ := path.Match(pattern, segment); matched {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	patternSegments := strings.Split(strings.TrimPrefix(pattern, "/"), "/")
+	for end := 1; end <= len(segments); end += 1 {
+		if matchSegments(patternSegments, segments[:end]) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ValidateGlob returns false if the pattern is malformed
+func ValidateGlob(pattern string) bool {
+	for _, segment := range strings.Split(pattern, "/") {
+		if _, err := path.Match(segment, ""); err != nil {
+			return false
+		}
+	}
+
+	return true
+}
+
+func matchSegments(pattern []string, segments []string) bool {
+	if len(pattern) == 0 {
+		return len(segments) == 0
+	}
+
+	if pattern[0] == "**" {
+		for skip := 0; skip <= len(segments); skip += 1 {
+			if matchSegments(pattern[1:], segments[skip:]) {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	if len(segments) == 0 {
+		return false
+	}
+
+	if matched, _ := path.Match(pattern[0], segments[0]); !matched {
+		return false
+	}
+
+	return matchSegments(pattern[1:], segments[1:])
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"unicode/utf8"
+
+	"github.com/veandco/go-sdl2/sdl"
+	"github.com/veandco/go-sdl2/ttf"
+)
+
+// Printable ASCII goes into the atlas up front, anything else is rendered the first time it is drawn
+const (
+	atlasFirstGlyph = 32
+	atlasLastGlyph  = 126
+	atlasColumns    = 16
+)
+
+type Glyph struct {
+	Texture *sdl.Texture
+	Rect    sdl.Rect // Where the glyph is in the texture
+}
+
+// GlyphAtlas holds white glyphs of a font in a single texture. Text is drawn by copying a glyph at a time with the color
+// mod set, so consecutive copies come from the same texture and SDL batches them into one draw call
+type GlyphAtlas struct {
+	Renderer *sdl.Renderer
+	Texture  *sdl.Texture
+	Glyphs   [atlasLastGlyph - atlasFirstGlyph + 1]sdl.Rect // Empty for glyphs the font has no pixels for
+	Extra    map[rune]Glyph
+}
+
+func CreateGlyphAtlas(renderer *sdl.Renderer, font *ttf.Font) (result GlyphAtlas) {
+	result.Renderer = renderer
+	result.Extra = map[rune]Glyph{}
+
+	white := sdl.Color{R: 255, G: 255, B: 255, A: 255}
+	surfaces := [len(result.Glyphs)]*sdl.Surface{}
+	var cellWidth, cellHeight int32
+	for index := range surfaces {
+		char := rune(atlasFirstGlyph + index)
+		if char == ' ' {
+			continue
+		}
+
+		surface, err := font.RenderUTF8Blended(string(char), white)
+		if err != nil {
+			continue
+		}
+
+		surfaces[index] = surface
+		if surface.W > cellWidth {
+			cellWidth = surface.W
+		}
+		if surface.H > cellHeight {
+			cellHeight = surface.H
+		}
+	}
+
+	rows := (int32(len(surfaces)) + atlasColumns - 1) / atlasColumns
+	atlas, err := sdl.CreateRGBSurfaceWithFormat(0, atlasColumns*cellWidth, rows*cellHeight, 32, sdl.PIXELFORMAT_RGBA32)
+	checkError(err)
+	defer atlas.Free()
+
+	for index, surface := range surfaces {
+		if surface == nil {
+			continue
+		}
+
+		rect := sdl.Rect{X: int32(index%atlasColumns) * cellWidth, Y: int32(index/atlasColumns) * cellHeight, W: surface.W, H: surface.H}
+
+		// The alpha of the glyph is copied as it is instead of being blended with the empty atlas
+		surface.SetBlendMode(sdl.BLENDMODE_NONE)
+		surface.Blit(nil, atlas, &rect)
+		surface.Free()
+
+		result.Glyphs[index] = rect
+	}
+
+	result.Texture, err = renderer.CreateTextureFromSurface(atlas)
+	checkError(err)
+	result.Texture.SetBlendMode(sdl.BLENDMODE_BLEND)
+
+	return
+}
+
+// Draw draws the text from the left edge of rect, one cell per byte so the text lines up with the cursor columns
+func (atlas *GlyphAtlas) Draw(font *Font, text string, rect *sdl.Rect, color sdl.Color) {
+	atlas.Texture.SetColorMod(color.R, color.G, color.B)
+	atlas.Texture.SetAlphaMod(color.A)
+
+	x := rect.X
+	for index := 0; index < len(text); {
+		char, size := utf8.DecodeRuneInString(text[index:])
+		index += size
+
+		glyph, ok := atlas.glyph(font, char)
+		if ok {
+			// Glyphs are stretched to the height of rect, the same way whole strings used to be
+			dst := sdl.Rect{X: x, Y: rect.Y, W: glyph.Rect.W, H: rect.H}
+			if glyph.Texture != atlas.Texture {
+				glyph.Texture.SetColorMod(color.R, color.G, color.B)
+				glyph.Texture.SetAlphaMod(color.A)
+			}
+			atlas.Renderer.Copy(glyph.Texture, &glyph.Rect, &dst)
+		}
+
+		x += int32(size * font.CharacterWidth)
+	}
+}
+
+func (atlas *GlyphAtlas) Destroy() {
+	atlas.Texture.Destroy()
+	for _, glyph := range atlas.Extra {
+		glyph.Texture.Destroy()
+	}
+}
+
+func (atlas *GlyphAtlas) glyph(font *Font, char rune) (result Glyph, ok bool) {
+	if char >= atlasFirstGlyph && char <= atlasLastGlyph {
+		rect := atlas.Glyphs[char-atlasFirstGlyph]
+		return Glyph{Texture: atlas.Texture, Rect: rect}, rect.W > 0
+	}
+
+	result, ok = atlas.Extra[char]
+	if ok {
+		return result, result.Texture != nil
+	}
+
+	// Glyphs that fail to render are remembered as well, so they are not tried again every frame
+	atlas.Extra[char] = result
+
+	surface, err := font.Data.RenderUTF8Blended(string(char), sdl.Color{R: 255, G: 255, B: 255, A: 255})
+	if err != nil {
+		return result, false
+	}
+	defer surface.Free()
+
+	texture, err := atlas.Renderer.CreateTextureFromSurface(surface)
+	if err != nil {
+		return result, false
+	}
+	texture.SetBlendMode(sdl.BLENDMODE_BLEND)
+
+	result = Glyph{Texture: texture, Rect: sdl.Rect{W: surface.W, H: surface.H}}
+	atlas.Extra[char] = result
+
+	return result, true
+}
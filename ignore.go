@@ -0,0 +1,239 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+var ignoreFileNames = []string{".gitignore", ".ignore"}
+
+// IgnoreRule is a single line of a .gitignore file
+type IgnoreRule struct {
+	Pattern  string // As written in the file
+	Source   string // File and line the rule came from
+	Negate   bool
+	DirOnly  bool
+	Anchored bool
+
+	segments []string
+}
+
+// IgnoreMatcher applies .gitignore semantics: rules in deeper directories win over rules higher up, and within a file the last matching rule wins
+type IgnoreMatcher struct {
+	Root string
+
+	mutex  sync.Mutex // Ignore files are read lazily, from the indexer and the editor at the same time
+	global []IgnoreRule
+	rules  map[string][]IgnoreRule // Relative directory -> rules of the ignore files in it
+}
+
+func CreateIgnoreMatcher(root string) *IgnoreMatcher {
+	result := &IgnoreMatcher{}
+	result.Root = root
+	result.rules = map[string][]IgnoreRule{}
+
+	globalPath := findGlobalIgnoreFile()
+	if globalPath != "" {
+		data, err := ioutil.ReadFile(globalPath)
+		if err == nil {
+			result.global = append(result.global, ParseIgnoreRules(string(data), globalPath)...)
+		}
+	}
+
+	data, err := ioutil.ReadFile(filepath.Join(root, ".git", "info", "exclude"))
+	if err == nil {
+		result.global = append(result.global, ParseIgnoreRules(string(data), ".git/info/exclude")...)
+	}
+
+	return result
+}
+
+func ParseIgnoreRules(data string, source string) (result []IgnoreRule) {
+	for index, line := range strings.Split(data, "\n") {
+		line = strings.TrimRight(line, "\r")
+
+		// Trailing spaces are ignored unless they are escaped
+		for strings.HasSuffix(line, " ") && !strings.HasSuffix(line, "\\ ") {
+			line = line[:len(line)-1]
+		}
+
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		rule := IgnoreRule{Pattern: line, Source: fmt.Sprintf("%s:%d", source, index+1)}
+
+		if strings.HasPrefix(line, "!") {
+			rule.Negate = true
+			line = line[1:]
+		} else if strings.HasPrefix(line, "\\!") || strings.HasPrefix(line, "\\#") {
+			line = line[1:]
+		}
+
+		if strings.HasSuffix(line, "/") {
+			rule.DirOnly = true
+			line = strings.TrimSuffix(line, "/")
+		}
+
+		// A slash anywhere but at the end ties the pattern to the directory of the ignore file
+		rule.Anchored = strings.Contains(line, "/")
+		line = strings.TrimPrefix(line, "/")
+
+		if line == "" || !ValidateGlob(line) {
+			continue
+		}
+
+		rule.segments = strings.Split(line, "/")
+		result = append(result, rule)
+	}
+
+	return
+}
+
+// Match reports whether the rule applies to the path, which is relative to the directory of the ignore file
+func (rule *IgnoreRule) Match(relPath string, isDir bool) bool {
+	if rule.DirOnly && !isDir {
+		return false
+	}
+
+	if !rule.Anchored {
+		matched, _ := path.Match(rule.segments[0], path.Base(relPath))
+		return matched
+	}
+
+	return matchSegments(rule.segments, strings.Split(relPath, "/"))
+}
+
+// Match reports whether the path, relative to the root, is ignored and which rule decided it. A path inside an ignored directory is ignored as well
+func (matcher *IgnoreMatcher) Match(relPath string, isDir bool) (bool, *IgnoreRule) {
+	matcher.mutex.Lock()
+	defer matcher.mutex.Unlock()
+
+	segments := strings.Split(relPath, "/")
+
+	for end := 1; end <= len(segments); end += 1 {
+		current := strings.Join(segments[:end], "/")
+		currentIsDir := isDir || end < len(segments)
+
+		ignored, rule := matcher.matchSingle(current, currentIsDir)
+		if ignored {
+			return true, rule
+		}
+	}
+
+	return false, nil
+}
+
+func (matcher *IgnoreMatcher) matchSingle(relPath string, isDir bool) (ignored bool, decidingRule *IgnoreRule) {
+	for i := range matcher.global {
+		rule := &matcher.global[i]
+		if rule.Match(relPath, isDir) {
+			ignored = !rule.Negate
+			decidingRule = rule
+		}
+	}
+
+	// Walk from the root to the directory that holds the path, deeper ignore files override earlier ones
+	dir := ""
+	rest := relPath
+	for {
+		for i, rule := range matcher.loadRules(dir) {
+			if rule.Match(rest, isDir) {
+				ignored = !rule.Negate
+				decidingRule = &matcher.rules[dir][i]
+			}
+		}
+
+		slash := strings.Index(rest, "/")
+		if slash == -1 {
+			break
+		}
+
+		if dir == "" {
+			dir = rest[:slash]
+		} else {
+			dir = dir + "/" + rest[:slash]
+		}
+		rest = rest[slash+1:]
+	}
+
+	if !ignored {
+		decidingRule = nil
+	}
+
+	return
+}
+
+// loadRules reads the ignore files of the directory the first time they are needed
+func (matcher *IgnoreMatcher) loadRules(dir string) []IgnoreRule {
+	rules, ok := matcher.rules[dir]
+	if ok {
+		return rules
+	}
+
+	rules = []IgnoreRule{}
+	for _, name := range ignoreFileNames {
+		data, err := ioutil.ReadFile(filepath.Join(matcher.Root, filepath.FromSlash(dir), name))
+		if err != nil {
+			continue
+		}
+
+		rules = append(rules, ParseIgnoreRules(string(data), path.Join(dir, name))...)
+	}
+
+	matcher.rules[dir] = rules
+	return rules
+}
+
+func IsIgnoreFile(path string) bool {
+	name := filepath.Base(path)
+	for _, ignoreFileName := range ignoreFileNames {
+		if name == ignoreFileName {
+			return true
+		}
+	}
+
+	return false
+}
+
+// findGlobalIgnoreFile looks for core.excludesFile in the user's git config and falls back to the default location
+func findGlobalIgnoreFile() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+
+	data, err := ioutil.ReadFile(filepath.Join(home, ".gitconfig"))
+	if err == nil {
+		section := ""
+		for _, line := range strings.Split(string(data), "\n") {
+			line = strings.TrimSpace(line)
+			if strings.HasPrefix(line, "[") {
+				section = strings.ToLower(strings.Trim(line, "[] "))
+				continue
+			}
+
+			key, value, ok := getKeyValue(line, "=")
+			if ok && section == "core" && strings.ToLower(key) == "excludesfile" {
+				value = strings.Trim(value, "\"")
+				if strings.HasPrefix(value, "~/") {
+					value = filepath.Join(home, value[2:])
+				}
+
+				return value
+			}
+		}
+	}
+
+	configDir := os.Getenv("XDG_CONFIG_HOME")
+	if configDir == "" {
+		configDir = filepath.Join(home, ".config")
+	}
+
+	return filepath.Join(configDir, "git", "ignore")
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// isolateGlobalIgnore points the global ignore file lookup at an empty home directory so the user's own git config does not leak into the tests
+func isolateGlobalIgnore(t *testing.T) string {
+	home := t.TempDir()
+
+	oldHome := os.Getenv("HOME")
+	oldConfig := os.Getenv("XDG_CONFIG_HOME")
+	os.Setenv("HOME", home)
+	os.Setenv("XDG_CONFIG_HOME", filepath.Join(home, ".config"))
+	t.Cleanup(func() {
+		os.Setenv("HOME", oldHome)
+		os.Setenv("XDG_CONFIG_HOME", oldConfig)
+	})
+
+	return home
+}
+
+func TestIgnoreMatcher(t *testing.T) {
+	isolateGlobalIgnore(t)
+
+	root := createFixture(t, map[string]string{
+		".gitignore":        "# build output\n*.log\n!keep.log\nbuild/\n/top.txt\ndocs/**/*.tmp\n\\#hash\n",
+		"src/.gitignore":    "generated.go\n!/important.log\n",
+		"src/deep/.ignore":  "*.go\n",
+		"src/deep/main.go":  "",
+		"src/generated.go":  "",
+		"src/important.log": "",
+		"src/top.txt":       "",
+		"top.txt":           "",
+		"debug.log":         "",
+		"keep.log":          "",
+		"build":             "", // A file, the directory-only rule must not match it
+		"out/build/app":     "",
+		"docs/a/b/x.tmp":    "",
+		"#hash":             "",
+	})
+
+	cases := []struct {
+		Path     string
+		IsDir    bool
+		Expected bool
+	}{
+		{"debug.log", false, true},
+		{"keep.log", false, false},
+		{"src/sub/trace.log", false, true},
+		{"src/important.log", false, false},
+		{"build", false, false},
+		{"out/build", true, true},
+		{"out/build/app", false, true},
+		{"top.txt", false, true},
+		{"src/top.txt", false, false},
+		{"docs/a/b/x.tmp", false, true},
+		{"docs/x.tmp", false, true},
+		{"x.tmp", false, false},
+		{"src/generated.go", false, true},
+		{"generated.go", false, false},
+		{"src/deep/main.go", false, true},
+		{"src/main.go", false, false},
+		{"#hash", false, true},
+	}
+
+	matcher := CreateIgnoreMatcher(root)
+	for _, c := range cases {
+		ignored, _ := matcher.Match(c.Path, c.IsDir)
+		FailIfFalse(ignored == c.Expected, fmt.Sprintf("Expected %s ignored to be %t", c.Path, c.Expected), t)
+	}
+
+	t.Run("Deciding rule is reported", func(t *testing.T) {
+		_, rule := matcher.Match("src/generated.go", false)
+		FailNowIfFalse(rule != nil, "Expected a rule for an ignored path", t)
+		FailIfFalse(rule.Source == "src/.gitignore:1", fmt.Sprintf("Incorrect rule source %s", rule.Source), t)
+
+		_, rule = matcher.Match("keep.log", false)
+		FailIfFalse(rule == nil, "Expected no rule for a path that is not ignored", t)
+	})
+}
+
+func TestIgnoreMatcherGlobal(t *testing.T) {
+	home := isolateGlobalIgnore(t)
+
+	excludes := createFixture(t, map[string]string{"excludes": "*.swp\n"})
+	ioutil.WriteFile(filepath.Join(home, ".gitconfig"), []byte("[user]\n\tname = someone\n[core]\n\texcludesFile = "+filepath.Join(excludes, "excludes")+"\n"), 0644)
+
+	root := createFixture(t, map[string]string{
+		".git/info/exclude": "local/\n",
+		".gitignore":        "!wanted.swp\n",
+	})
+
+	matcher := CreateIgnoreMatcher(root)
+
+	ignored, _ := matcher.Match("main.go.swp", false)
+	FailIfFalse(ignored, "Expected the global excludes file to be honored", t)
+
+	ignored, _ = matcher.Match("wanted.swp", false)
+	FailIfFalse(!ignored, "Expected .gitignore to override the global excludes file", t)
+
+	ignored, _ = matcher.Match("local/notes.txt", false)
+	FailIfFalse(ignored, "Expected .git/info/exclude to be honored", t)
+}
+
+func TestProjectIgnoreFiles(t *testing.T) {
+	isolateGlobalIgnore(t)
+
+	root := createFixture(t, map[string]string{
+		".gitignore":             "node_modules/\n*.o\n",
+		"main.go":                "",
+		"main.o":                 "",
+		"node_modules/pkg/a.js":  "",
+		"vendor/lib/lib.go":      "",
+		"vendor/lib/.gitignore":  "!*.o\n",
+		"vendor/lib/prebuilt.o":  "",
+		"vendor/lib/ignored.txt": "",
+	})
+
+	t.Run("Ignore files are honored", func(t *testing.T) {
+		project, _ := ParseProject("exclude: .git, *.txt\n", filepath.Join(root, "test.aproject"))
+		defer project.Close()
+		project.WaitForIndex()
+
+		files := relativeFiles(root, project.Files)
+		expected := []string{".gitignore", "main.go", "vendor/lib/.gitignore", "vendor/lib/lib.go", "vendor/lib/prebuilt.o"}
+		FailIfFalse(linesEqual(files, expected), fmt.Sprintf("Expected files %v, got %v", expected, files), t)
+
+		reason := project.Skipped[filepath.Join(root, "node_modules")]
+		FailIfFalse(reason == ".gitignore:1 \"node_modules/\"", fmt.Sprintf("Incorrect reason %q", reason), t)
+
+		reason = project.Skipped[filepath.Join(root, "vendor", "lib", "ignored.txt")]
+		FailIfFalse(reason == "project exclude \"*.txt\"", fmt.Sprintf("Incorrect reason %q", reason), t)
+
+		FailIfFalse(project.IsExcluded(filepath.Join(root, "node_modules", "pkg", "a.js")), "Expected files in ignored directories to be excluded", t)
+
+		filter := project.ExcludeFilter()
+		project.Exclude = nil
+		FailIfFalse(filter(filepath.Join(root, "vendor", "lib", "ignored.txt")), "The filter should not see later changes to the project", t)
+	})
+
+	t.Run("Ignore files can be turned off", func(t *testing.T) {
+		project, errors := ParseProject("ignorefiles: off\n", filepath.Join(root, "test.aproject"))
+		defer project.Close()
+		project.WaitForIndex()
+		FailIfFalse(len(errors) == 0, fmt.Sprintf("Expected no errors, got %v", errors), t)
+
+		files := relativeFiles(root, project.Files)
+		FailIfFalse(len(files) == 8, fmt.Sprintf("Expected every file, got %v", files), t)
+	})
+}
@@ -1,30 +1,38 @@
 package main
 
 import (
+	"image"
+	"image/draw"
+	"image/png"
+	"os"
+
 	"github.com/veandco/go-sdl2/img"
 	"github.com/veandco/go-sdl2/sdl"
 )
 
+// Image keeps its pixels so any canvas can draw it. The SDL texture is made the first time the image is drawn to a window
 type Image struct {
-	Data   *sdl.Texture
+	Pixels *image.NRGBA
 	Width  int32
 	Height int32
+
+	texture  *sdl.Texture
+	renderer *sdl.Renderer
 }
 
-func LoadImage(path string, renderer *sdl.Renderer) (result Image) {
-	image, err := img.Load(path)
+func LoadImage(path string) (result Image) {
+	file, err := os.Open(path)
 	checkError(err)
+	defer file.Close()
 
-	texture, err := renderer.CreateTextureFromSurface(image)
+	decoded, err := png.Decode(file)
 	checkError(err)
 
-	result = Image{
-		Data:   texture,
-		Width:  image.W,
-		Height: image.H,
-	}
-
-	image.Free()
+	bounds := decoded.Bounds()
+	result.Pixels = image.NewNRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(result.Pixels, result.Pixels.Bounds(), decoded, bounds.Min, draw.Src)
+	result.Width = int32(bounds.Dx())
+	result.Height = int32(bounds.Dy())
 
 	return
 }
@@ -36,7 +44,26 @@ func LoadIcon(path string) *sdl.Surface {
 	return image
 }
 
-func (image *Image) Render(renderer *sdl.Renderer, position sdl.Point, color sdl.Color) {
+// Texture returns the image as a texture of the renderer
+func (image *Image) Texture(renderer *sdl.Renderer) *sdl.Texture {
+	if image.texture != nil && image.renderer == renderer {
+		return image.texture
+	}
+
+	image.Unload()
+
+	texture, err := renderer.CreateTexture(sdl.PIXELFORMAT_RGBA32, sdl.TEXTUREACCESS_STATIC, image.Width, image.Height)
+	checkError(err)
+	texture.Update(nil, image.Pixels.Pix, image.Pixels.Stride)
+	texture.SetBlendMode(sdl.BLENDMODE_BLEND)
+
+	image.texture = texture
+	image.renderer = renderer
+
+	return texture
+}
+
+func (image *Image) Render(renderer Canvas, position sdl.Point, color sdl.Color) {
 	rect := sdl.Rect{
 		X: position.X,
 		Y: position.Y,
@@ -44,9 +71,12 @@ func (image *Image) Render(renderer *sdl.Renderer, position sdl.Point, color sdl
 		H: image.Height,
 	}
 
-	DrawImage(renderer, image.Data, rect, color)
+	DrawImage(renderer, image, rect, color)
 }
 
 func (image *Image) Unload() {
-	image.Data.Destroy()
+	if image.texture != nil {
+		image.texture.Destroy()
+		image.texture = nil
+	}
 }
@@ -23,3 +23,7 @@ func Min(v1 int, v2 int) int {
 
 	return v2
 }
+
+func Clamp(value int, min int, max int) int {
+	return Max(min, Min(value, max))
+}
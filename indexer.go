@@ -0,0 +1,272 @@
+package main
+
+import (
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Indexer collects the files of a project in the background. The files found so far can be read at any time, so the file search is usable before the walk is done
+type Indexer struct {
+	Root string
+
+	exclude func(path string, isDir bool) string // Returns why the path is left out, empty if it is not
+
+	mutex   sync.Mutex
+	files   map[string]bool
+	skipped map[string]string
+	changed bool // Files changed since the last snapshot
+	done    bool // The initial walk is finished
+	pending []string
+
+	wake     chan struct{}
+	ready    chan struct{} // Closed when the initial walk is finished
+	quit     chan struct{}
+	finished chan struct{}
+}
+
+func CreateIndexer(root string, exclude func(path string, isDir bool) string) *Indexer {
+	result := &Indexer{
+		Root:     root,
+		exclude:  exclude,
+		files:    map[string]bool{},
+		skipped:  map[string]string{},
+		wake:     make(chan struct{}, 1),
+		ready:    make(chan struct{}),
+		quit:     make(chan struct{}),
+		finished: make(chan struct{}),
+	}
+
+	go result.run()
+
+	return result
+}
+
+// Update indexes the path again after it was created, changed or removed
+func (indexer *Indexer) Update(path string) {
+	indexer.mutex.Lock()
+	indexer.pending = append(indexer.pending, path)
+	indexer.mutex.Unlock()
+
+	select {
+	case indexer.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Snapshot returns the files found so far, sorted. changed is false if nothing happened since the previous snapshot
+func (indexer *Indexer) Snapshot() (files []string, skipped map[string]string, changed bool, done bool) {
+	indexer.mutex.Lock()
+	defer indexer.mutex.Unlock()
+
+	files = make([]string, 0, len(indexer.files))
+	for path := range indexer.files {
+		files = append(files, path)
+	}
+	sort.Strings(files)
+
+	skipped = make(map[string]string, len(indexer.skipped))
+	for path, reason := range indexer.skipped {
+		skipped[path] = reason
+	}
+
+	changed = indexer.changed
+	done = indexer.done
+	indexer.changed = false
+
+	return
+}
+
+// Wait blocks until the initial walk is finished
+func (indexer *Indexer) Wait() {
+	<-indexer.ready
+}
+
+func (indexer *Indexer) Done() bool {
+	indexer.mutex.Lock()
+	defer indexer.mutex.Unlock()
+
+	return indexer.done
+}
+
+// Close stops the indexer and waits for it to exit
+func (indexer *Indexer) Close() {
+	close(indexer.quit)
+	<-indexer.finished
+}
+
+func (indexer *Indexer) run() {
+	defer close(indexer.finished)
+
+	visited := map[string]bool{}
+	indexer.walk(indexer.Root, visited)
+
+	indexer.mutex.Lock()
+	indexer.done = true
+	indexer.changed = true
+	indexer.mutex.Unlock()
+	close(indexer.ready)
+
+	for {
+		select {
+		case <-indexer.quit:
+			return
+		case <-indexer.wake:
+		}
+
+		indexer.mutex.Lock()
+		pending := indexer.pending
+		indexer.pending = nil
+		indexer.mutex.Unlock()
+
+		for _, path := range pending {
+			indexer.update(path)
+		}
+	}
+}
+
+func (indexer *Indexer) stopped() bool {
+	select {
+	case <-indexer.quit:
+		return true
+	default:
+		return false
+	}
+}
+
+// walk adds every file under the directory. visited holds the real paths of the directories already walked, so symlinks that point back up the tree are only followed once
+func (indexer *Indexer) walk(dirPath string, visited map[string]bool) {
+	if indexer.stopped() {
+		return
+	}
+
+	realPath, err := filepath.EvalSymlinks(dirPath)
+	if err != nil {
+		indexer.skip(dirPath, err.Error())
+		return
+	}
+
+	if visited[realPath] {
+		indexer.skip(dirPath, "symlink to a directory that is already indexed")
+		return
+	}
+	visited[realPath] = true
+
+	// Permission errors and the like leave the directory out instead of stopping the editor
+	entries, err := ioutil.ReadDir(dirPath)
+	if err != nil {
+		log.Printf("Unable to index %s: %s", dirPath, err)
+		indexer.skip(dirPath, err.Error())
+		return
+	}
+
+	var found []string
+	var dirs []string
+	for _, entry := range entries {
+		path := filepath.Join(dirPath, entry.Name())
+
+		isDir := entry.IsDir()
+		if entry.Mode()&os.ModeSymlink != 0 {
+			target, err := os.Stat(path)
+			if err != nil {
+				indexer.skip(path, "broken symlink")
+				continue
+			}
+
+			isDir = target.IsDir()
+		}
+
+		reason := indexer.exclude(path, isDir)
+		if reason != "" {
+			indexer.skip(path, reason)
+			continue
+		}
+
+		if isDir {
+			dirs = append(dirs, path)
+		} else {
+			found = append(found, path)
+		}
+	}
+
+	// Files are published a directory at a time
+	indexer.mutex.Lock()
+	for _, path := range found {
+		indexer.files[path] = true
+		delete(indexer.skipped, path)
+	}
+	if len(found) > 0 {
+		indexer.changed = true
+	}
+	indexer.mutex.Unlock()
+
+	for _, dir := range dirs {
+		indexer.walk(dir, visited)
+	}
+}
+
+func (indexer *Indexer) update(path string) {
+	info, err := os.Stat(path)
+	if err != nil {
+		indexer.remove(path)
+		return
+	}
+
+	reason := indexer.exclude(path, info.IsDir())
+	if reason != "" {
+		indexer.remove(path)
+		indexer.skip(path, reason)
+		return
+	}
+
+	if info.IsDir() {
+		visited := map[string]bool{}
+		if path != indexer.Root {
+			// Keeps a new symlink back to the root from indexing the whole project again
+			rootPath, _ := filepath.EvalSymlinks(indexer.Root)
+			visited[rootPath] = true
+		}
+
+		indexer.walk(path, visited)
+		return
+	}
+
+	indexer.mutex.Lock()
+	if !indexer.files[path] {
+		indexer.files[path] = true
+		indexer.changed = true
+	}
+	delete(indexer.skipped, path)
+	indexer.mutex.Unlock()
+}
+
+// remove forgets the path and, if it was a directory, everything under it
+func (indexer *Indexer) remove(path string) {
+	prefix := path + string(filepath.Separator)
+
+	indexer.mutex.Lock()
+	defer indexer.mutex.Unlock()
+
+	for file := range indexer.files {
+		if file == path || strings.HasPrefix(file, prefix) {
+			delete(indexer.files, file)
+			indexer.changed = true
+		}
+	}
+
+	for file := range indexer.skipped {
+		if file == path || strings.HasPrefix(file, prefix) {
+			delete(indexer.skipped, file)
+		}
+	}
+}
+
+func (indexer *Indexer) skip(path string, reason string) {
+	indexer.mutex.Lock()
+	indexer.skipped[path] = reason
+	indexer.mutex.Unlock()
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func noExclude(path string, isDir bool) string {
+	return ""
+}
+
+// waitForFiles polls the indexer until the files match or a second has passed
+func waitForFiles(indexer *Indexer, root string, expected []string) (files []string) {
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		snapshot, _, _, _ := indexer.Snapshot()
+		files = relativeFiles(root, snapshot)
+		if linesEqual(files, expected) {
+			return
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	return
+}
+
+func TestIndexer(t *testing.T) {
+	t.Run("Initial walk", func(t *testing.T) {
+		root := createFixture(t, map[string]string{
+			"main.go":     "",
+			"lib/util.go": "",
+			"skip/a.go":   "",
+		})
+
+		indexer := CreateIndexer(root, func(path string, isDir bool) string {
+			if filepath.Base(path) == "skip" {
+				return "test"
+			}
+			return ""
+		})
+		defer indexer.Close()
+		indexer.Wait()
+
+		files, skipped, changed, done := indexer.Snapshot()
+		expected := []string{"lib/util.go", "main.go"}
+		FailIfFalse(linesEqual(relativeFiles(root, files), expected), fmt.Sprintf("Expected files %v, got %v", expected, relativeFiles(root, files)), t)
+		FailIfFalse(skipped[filepath.Join(root, "skip")] == "test", "Expected the skipped directory to be recorded", t)
+		FailIfFalse(changed && done, "Expected a finished index with changes", t)
+
+		_, _, changed, _ = indexer.Snapshot()
+		FailIfFalse(!changed, "Expected no changes after the previous snapshot", t)
+	})
+
+	t.Run("Symlink loops are followed once", func(t *testing.T) {
+		root := createFixture(t, map[string]string{"dir/file.go": ""})
+		if err := os.Symlink(root, filepath.Join(root, "dir", "loop")); err != nil {
+			t.Skip("Symlinks are not supported")
+		}
+		os.Symlink(filepath.Join(root, "dir", "file.go"), filepath.Join(root, "link.go"))
+
+		indexer := CreateIndexer(root, noExclude)
+		defer indexer.Close()
+		indexer.Wait()
+
+		files, skipped, _, _ := indexer.Snapshot()
+		expected := []string{"dir/file.go", "link.go"}
+		FailIfFalse(linesEqual(relativeFiles(root, files), expected), fmt.Sprintf("Expected files %v, got %v", expected, relativeFiles(root, files)), t)
+		FailIfFalse(skipped[filepath.Join(root, "dir", "loop")] != "", "Expected the loop to be recorded as skipped", t)
+	})
+
+	t.Run("Unreadable directories are skipped", func(t *testing.T) {
+		root := createFixture(t, map[string]string{
+			"main.go":          "",
+			"private/secret":   "",
+			"private/more/one": "",
+		})
+
+		private := filepath.Join(root, "private")
+		os.Chmod(private, 0)
+		defer os.Chmod(private, 0755)
+
+		if _, err := ioutil.ReadDir(private); err == nil {
+			t.Skip("Permissions are not enforced for this user")
+		}
+
+		indexer := CreateIndexer(root, noExclude)
+		defer indexer.Close()
+		indexer.Wait()
+
+		files, skipped, _, _ := indexer.Snapshot()
+		FailIfFalse(linesEqual(relativeFiles(root, files), []string{"main.go"}), fmt.Sprintf("Expected only main.go, got %v", relativeFiles(root, files)), t)
+		FailIfFalse(skipped[private] != "", "Expected the unreadable directory to be recorded", t)
+	})
+
+	t.Run("Incremental updates", func(t *testing.T) {
+		root := createFixture(t, map[string]string{
+			"main.go":     "",
+			"lib/util.go": "",
+		})
+
+		indexer := CreateIndexer(root, noExclude)
+		defer indexer.Close()
+		indexer.Wait()
+
+		created := filepath.Join(root, "new.go")
+		ioutil.WriteFile(created, []byte{}, 0644)
+		indexer.Update(created)
+
+		expected := []string{"lib/util.go", "main.go", "new.go"}
+		files := waitForFiles(indexer, root, expected)
+		FailIfFalse(linesEqual(files, expected), fmt.Sprintf("Expected files %v, got %v", expected, files), t)
+
+		dir := filepath.Join(root, "pkg")
+		os.MkdirAll(filepath.Join(dir, "sub"), 0755)
+		ioutil.WriteFile(filepath.Join(dir, "sub", "a.go"), []byte{}, 0644)
+		indexer.Update(dir)
+
+		expected = []string{"lib/util.go", "main.go", "new.go", "pkg/sub/a.go"}
+		files = waitForFiles(indexer, root, expected)
+		FailIfFalse(linesEqual(files, expected), fmt.Sprintf("Expected files %v, got %v", expected, files), t)
+
+		os.RemoveAll(filepath.Join(root, "lib"))
+		indexer.Update(filepath.Join(root, "lib"))
+
+		expected = []string{"main.go", "new.go", "pkg/sub/a.go"}
+		files = waitForFiles(indexer, root, expected)
+		FailIfFalse(linesEqual(files, expected), fmt.Sprintf("Expected files %v, got %v", expected, files), t)
+	})
+}
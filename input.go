@@ -4,6 +4,17 @@ type Input struct {
 	TypedCharacter byte
 	Backspace      bool
 	Escape         bool
+	Delete         bool
+	Left           bool
+	Right          bool
+	Up             bool
+	Down           bool
+	Home           bool
+	End            bool
+	PageUp         bool
+	PageDown       bool
+	Insert         bool
+	Function       uint8 // 1 to 12 for F1 to F12, 0 when no function key was pressed
 	Ctrl           bool
 	Alt            bool
 	Shift          bool
@@ -14,4 +25,15 @@ func (input *Input) Clear() {
 	input.TypedCharacter = 0
 	input.Backspace = false
 	input.Escape = false
+	input.Delete = false
+	input.Left = false
+	input.Right = false
+	input.Up = false
+	input.Down = false
+	input.Home = false
+	input.End = false
+	input.PageUp = false
+	input.PageDown = false
+	input.Insert = false
+	input.Function = 0
 }
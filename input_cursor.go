@@ -18,7 +18,7 @@ func CreateInputCursor(height int32, advance int32) (result InputCursor) {
 	return
 }
 
-func (cursor *InputCursor) Render(renderer *sdl.Renderer, inputRect sdl.Rect, color sdl.Color) {
+func (cursor *InputCursor) Render(renderer Canvas, inputRect sdl.Rect, color sdl.Color) {
 	rect := sdl.Rect{
 		X: inputRect.X + 5 + cursor.Column*cursor.Advance,
 		Y: inputRect.Y + 5,
@@ -0,0 +1,93 @@
+package main
+
+type Position struct {
+	Filepath string
+	Line     int32 // 0-based
+	Column   int32 // 0-based
+}
+
+// PositionList keeps positions to come back to, with an index for moving through them. It is used for both the jump list and the change list
+type PositionList struct {
+	Positions []Position // Oldest first
+	Index     int        // len(Positions) when not moving through the list
+}
+
+const positionListSize = 100
+
+// Push adds a jump. An older jump to the same line is dropped, so going back never stops on the same line twice
+func (list *PositionList) Push(position Position) {
+	positions := []Position{}
+	for _, item := range list.Positions {
+		if item.Filepath != position.Filepath || item.Line != position.Line {
+			positions = append(positions, item)
+		}
+	}
+
+	list.Positions = append(positions, position)
+	list.trim()
+}
+
+// Record adds a change. Changes on the same line as the last one replace it, typing a word is one change and not one per letter
+func (list *PositionList) Record(position Position) {
+	last := len(list.Positions) - 1
+	if last >= 0 && list.Positions[last].Filepath == position.Filepath && list.Positions[last].Line == position.Line {
+		list.Positions[last] = position
+	} else {
+		list.Positions = append(list.Positions, position)
+	}
+
+	list.trim()
+}
+
+// Back goes to the previous jump. The current position is pushed first when starting to move, so Forward can come back to it
+func (list *PositionList) Back(current Position) (Position, bool) {
+	if list.Index >= len(list.Positions) {
+		list.Push(current)
+		list.Index = len(list.Positions) - 1
+	}
+
+	return list.Older()
+}
+
+// Forward goes to the next jump, the opposite of Back
+func (list *PositionList) Forward() (Position, bool) {
+	return list.Newer()
+}
+
+func (list *PositionList) Older() (Position, bool) {
+	if list.Index <= 0 || len(list.Positions) == 0 {
+		return Position{}, false
+	}
+
+	list.Index = Min(list.Index, len(list.Positions)) - 1
+
+	return list.Positions[list.Index], true
+}
+
+func (list *PositionList) Newer() (Position, bool) {
+	if list.Index+1 >= len(list.Positions) {
+		return Position{}, false
+	}
+
+	list.Index += 1
+
+	return list.Positions[list.Index], true
+}
+
+// ShiftLines moves the positions in the file at or below the line by delta, so they keep pointing at the same text when lines are added or removed above them
+func (list *PositionList) ShiftLines(filepath string, from int32, delta int32) {
+	for index := range list.Positions {
+		position := &list.Positions[index]
+		if position.Filepath == filepath && position.Line >= from {
+			position.Line = int32(Max(int(position.Line+delta), 0))
+		}
+	}
+}
+
+func (list *PositionList) trim() {
+	if len(list.Positions) > positionListSize {
+		list.Positions = list.Positions[len(list.Positions)-positionListSize:]
+	}
+
+	list.Index = len(list.Positions)
+}
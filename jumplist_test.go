@@ -0,0 +1,107 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+func TestJumpList(t *testing.T) {
+	var list PositionList
+	list.Push(Position{Filepath: "a.go", Line: 1})
+	list.Push(Position{Filepath: "a.go", Line: 40})
+	list.Push(Position{Filepath: "b.go", Line: 3})
+
+	t.Run("Back and forward", func(t *testing.T) {
+		position, ok := list.Back(Position{Filepath: "b.go", Line: 90})
+		FailIfFalse(ok && position == Position{Filepath: "b.go", Line: 3}, "Expected to go back to the last jump", t)
+
+		position, _ = list.Back(Position{})
+		FailIfFalse(position.Line == 40, "Expected to go back to line 40", t)
+
+		list.Forward()
+		position, ok = list.Forward()
+		FailIfFalse(ok && position == Position{Filepath: "b.go", Line: 90}, "Expected to come forward to where the jumps started", t)
+
+		_, ok = list.Forward()
+		FailIfFalse(!ok, "Expected no jump past the newest one", t)
+	})
+
+	t.Run("Same line is kept once", func(t *testing.T) {
+		list.Push(Position{Filepath: "a.go", Line: 1, Column: 8})
+
+		count := 0
+		for _, position := range list.Positions {
+			if position.Filepath == "a.go" && position.Line == 1 {
+				count += 1
+			}
+		}
+		FailIfFalse(count == 1, fmt.Sprintf("Expected one jump on a.go:1, got %d", count), t)
+		FailIfFalse(list.Positions[len(list.Positions)-1].Column == 8, "Expected the newest jump last", t)
+	})
+
+	t.Run("Lines are shifted", func(t *testing.T) {
+		list.ShiftLines("a.go", 10, 5)
+
+		for _, position := range list.Positions {
+			if position.Filepath == "a.go" && position.Line != 1 {
+				FailIfFalse(position.Line == 45, fmt.Sprintf("Expected line 40 to move to 45, got %d", position.Line), t)
+			}
+			if position.Filepath == "b.go" {
+				FailIfFalse(position.Line == 3 || position.Line == 90, "Jumps in other files should not move", t)
+			}
+		}
+	})
+}
+
+func TestUntitledJumps(t *testing.T) {
+	fakeFont := GetFakeFont()
+	app := App{Buffer: CreateBuffer(16, &fakeFont, sdl.Rect{W: 800, H: 600})}
+	app.Buffer.SetData([]byte("one\ntwo\nthree"), "")
+
+	app.Buffer.PushJump()
+	app.Buffer.MoveToPosition(2, 0)
+	app.jumpBack()
+	FailIfFalse(app.Buffer.Cursor.Line == 0, fmt.Sprintf("Expected to jump back in a buffer without a path, got line %d", app.Buffer.Cursor.Line), t)
+
+	app.jumpForward()
+	FailIfFalse(app.Buffer.Cursor.Line == 2, fmt.Sprintf("Expected to jump forward again, got line %d", app.Buffer.Cursor.Line), t)
+
+	app.Buffer.Filepath = "test.txt"
+	app.goToPosition(Position{Line: 0})
+	FailIfFalse(app.Buffer.Filepath == "test.txt" && app.Buffer.Cursor.Line == 2, "Expected no jump to a buffer without a path from another file", t)
+}
+
+func TestChangeList(t *testing.T) {
+	fakeFont := GetFakeFont()
+	buffer := CreateBuffer(16, &fakeFont, sdl.Rect{})
+	buffer.SetData([]byte("one\ntwo\nthree\nfour"), "test.txt")
+
+	buffer.MoveToPosition(1, 0)
+	buffer.Insert('a')
+	buffer.Insert('b')
+	buffer.MoveToPosition(3, 2)
+	buffer.Insert('c')
+
+	FailNowIfFalse(len(buffer.ChangeList.Positions) == 2, fmt.Sprintf("Expected 2 changes, got %d", len(buffer.ChangeList.Positions)), t)
+
+	buffer.MoveToPosition(0, 3)
+	buffer.Insert('\n')
+	FailIfFalse(buffer.ChangeList.Positions[0].Line == 2, "Expected the change on line 1 to move down with the inserted line", t)
+	FailIfFalse(buffer.ChangeList.Positions[1].Line == 4, "Expected the change on line 3 to move down with the inserted line", t)
+
+	buffer.RemoveBefore()
+	FailIfFalse(buffer.ChangeList.Positions[1].Line == 3, "Expected the change to move back up when the line break is removed", t)
+
+	buffer.MoveToPosition(1, 0)
+	buffer.RemoveCurrentLine()
+	FailIfFalse(buffer.ChangeList.Positions[1].Line == 2, "Expected the change below the removed line to move up", t)
+
+	position, ok := buffer.ChangeList.Older()
+	FailIfFalse(ok && position.Line == 1, fmt.Sprintf("Expected the newest change first, got line %d", position.Line), t)
+	position, _ = buffer.ChangeList.Older()
+	FailIfFalse(position.Line == 0, fmt.Sprintf("Expected the older change next, got line %d", position.Line), t)
+	_, ok = buffer.ChangeList.Newer()
+	FailIfFalse(ok, "Expected to move back to the newer change", t)
+}
@@ -0,0 +1,312 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The keymap sits between the keyboard and the modes. Keys are written in the same notation as macros, like <C-p> or gd,
+// and a binding runs a named action, a command like :wrap on, or types other keys. Keys typed by a binding are not looked
+// up again, so bindings can't loop
+
+type KeymapMode string
+
+const (
+	KeymapMode_Global KeymapMode = "global" // Every mode, bindings of the current mode come first
+	KeymapMode_Normal KeymapMode = "normal"
+	KeymapMode_Insert KeymapMode = "insert"
+	KeymapMode_Visual KeymapMode = "visual"
+)
+
+type KeyBinding struct {
+	Keys    []Input
+	Action  string  // Name of one of the keymap actions
+	Command string  // Run the same as if it was typed into the command palette
+	Typed   []Input // Keys passed on to the buffer instead
+}
+
+type Keymap struct {
+	Bindings map[KeymapMode][]KeyBinding
+	Timeout  time.Duration // How long to wait for more keys when the keys so far start a longer binding
+
+	pending   []Input
+	pendingAt time.Time
+}
+
+const defaultKeymapTimeout = 1000 * time.Millisecond
+
+// keymapActions are what bindings can run by name
+var keymapActions = map[string]func(app *App){
+	"file_search":           func(app *App) { app.openFileSearch("") },
+	"symbol_search":         func(app *App) { app.openFileSearch("@") },
+	"project_symbol_search": func(app *App) { app.openFileSearch("#") },
+	"project_search":        func(app *App) { app.openProjectSearch() },
+	"command_palette":       func(app *App) { app.openCommandPalette("") },
+	"save": func(app *App) {
+		if app.Buffer.Dirty {
+			app.saveSourceFile()
+		}
+	},
+	"save_project":          func(app *App) { app.saveProject() },
+	"open_project":          func(app *App) { app.openProject("") },
+	"show_in_explorer":      func(app *App) { app.showFileInExplorer() },
+	"jump_back":             func(app *App) { app.jumpBack() },
+	"jump_forward":          func(app *App) { app.jumpForward() },
+	"scroll_line_down":      func(app *App) { app.Buffer.ScrollRows(int32(app.takeCount())) },
+	"scroll_line_up":        func(app *App) { app.Buffer.ScrollRows(-int32(app.takeCount())) },
+	"scroll_half_page_down": func(app *App) { app.Buffer.ScrollHalfPage(Direction_Down) },
+	"scroll_half_page_up":   func(app *App) { app.Buffer.ScrollHalfPage(Direction_Up) },
+	"zoom_in":               func(app *App) { app.setZoom(app.Zoom + 1) },
+	"zoom_out":              func(app *App) { app.setZoom(app.Zoom - 1) },
+	"zoom_reset":            func(app *App) { app.setZoom(0) },
+}
+
+func CreateKeymap() (result Keymap) {
+	result.Bindings = map[KeymapMode][]KeyBinding{}
+	result.Timeout = defaultKeymapTimeout
+
+	return
+}
+
+func ParseKeymapMode(name string) (KeymapMode, bool) {
+	switch KeymapMode(name) {
+	case KeymapMode_Global, KeymapMode_Normal, KeymapMode_Insert, KeymapMode_Visual:
+		return KeymapMode(name), true
+	}
+
+	return "", false
+}
+
+// ParseKeyBinding reads the keys and what they are bound to. Targets starting with : are commands, names of actions are
+// actions and anything else is typed
+func ParseKeyBinding(keys string, target string) (result KeyBinding, ok bool) {
+	result.Keys = normalizeKeys(DecodeKeys(keys))
+	if len(result.Keys) == 0 || target == "" {
+		return result, false
+	}
+
+	if strings.HasPrefix(target, ":") {
+		result.Command = strings.TrimSpace(target[1:])
+		return result, result.Command != ""
+	}
+
+	if _, isAction := keymapActions[target]; isAction {
+		result.Action = target
+		return result, true
+	}
+
+	result.Typed = DecodeKeys(target)
+	return result, true
+}
+
+// Load adds the bindings in the file, replacing the ones bound to the same keys. Every line is a binding,
+// <mode> <keys> <target>, or timeout <milliseconds>. Lines starting with # are comments
+func (keymap *Keymap) Load(path string) bool {
+	data, _, success := OpenFile(path)
+	if !success {
+		return false
+	}
+
+	for index, line := range strings.Split(string(data), "\n") {
+		l := strings.TrimSpace(line)
+		if l == "" || strings.HasPrefix(l, "#") {
+			continue
+		}
+
+		fields := strings.Fields(l)
+		if fields[0] == "timeout" && len(fields) == 2 {
+			milliseconds, err := strconv.Atoi(fields[1])
+			if err == nil && milliseconds >= 0 {
+				keymap.Timeout = time.Duration(milliseconds) * time.Millisecond
+				continue
+			}
+		}
+
+		if len(fields) < 3 {
+			log.Printf("%s:%d: Invalid key binding: %s", path, index+1, l)
+			continue
+		}
+
+		mode, ok := ParseKeymapMode(fields[0])
+		if !ok {
+			log.Printf("%s:%d: Unknown mode: %s", path, index+1, fields[0])
+			continue
+		}
+
+		binding, ok := ParseKeyBinding(fields[1], strings.Join(fields[2:], " "))
+		if !ok {
+			log.Printf("%s:%d: Invalid key binding: %s", path, index+1, l)
+			continue
+		}
+
+		keymap.Bind(mode, binding)
+	}
+
+	return true
+}
+
+func (keymap *Keymap) Bind(mode KeymapMode, binding KeyBinding) {
+	keymap.Unbind(mode, binding.Keys)
+	keymap.Bindings[mode] = append(keymap.Bindings[mode], binding)
+}
+
+func (keymap *Keymap) Unbind(mode KeymapMode, keys []Input) bool {
+	bindings := keymap.Bindings[mode]
+	for index, binding := range bindings {
+		if keysEqual(binding.Keys, keys) {
+			keymap.Bindings[mode] = append(bindings[:index], bindings[index+1:]...)
+			return true
+		}
+	}
+
+	return false
+}
+
+// Lookup returns the binding for the keys, and whether there are longer bindings starting with them
+func (keymap *Keymap) Lookup(mode KeymapMode, keys []Input) (result *KeyBinding, prefix bool) {
+	keys = normalizeKeys(keys)
+	for _, bindingMode := range []KeymapMode{mode, KeymapMode_Global} {
+		bindings := keymap.Bindings[bindingMode]
+		for index := range bindings {
+			binding := &bindings[index]
+			if len(binding.Keys) > len(keys) && keysEqual(binding.Keys[:len(keys)], keys) {
+				prefix = true
+			} else if result == nil && keysEqual(binding.Keys, keys) {
+				result = binding
+			}
+		}
+	}
+
+	return
+}
+
+// Expired reports whether keys are waiting for a longer binding and the time to type it has run out
+func (keymap *Keymap) Expired(now time.Time) bool {
+	return len(keymap.pending) > 0 && now.Sub(keymap.pendingAt) >= keymap.Timeout
+}
+
+func (binding *KeyBinding) String() string {
+	target := EncodeKeys(binding.Typed)
+	if binding.Action != "" {
+		target = binding.Action
+	} else if binding.Command != "" {
+		target = ":" + binding.Command
+	}
+
+	return fmt.Sprintf("%s %s", EncodeKeys(binding.Keys), target)
+}
+
+// handleMappedInput passes the key through the keymap. Keys typed in the middle of a command, like the character after f,
+// are passed on as they are
+func (app *App) handleMappedInput(input Input) {
+	if !input.IsKey() || (len(app.Keymap.pending) == 0 && app.Submode != Submode_None) {
+		app.handleUnmappedInput(input)
+		return
+	}
+
+	app.Keymap.pending = append(app.Keymap.pending, input)
+	app.Keymap.pendingAt = time.Now()
+	app.resolveKeys(false)
+}
+
+// resolveKeys runs the binding of the keys typed so far. Keys that no binding starts with are passed on one at a time,
+// the rest are looked up again. Keys that start a longer binding wait for more keys until the timeout
+func (app *App) resolveKeys(timedOut bool) {
+	for len(app.Keymap.pending) > 0 {
+		keys := app.Keymap.pending
+		binding, prefix := app.Keymap.Lookup(app.keymapMode(), keys)
+		if prefix && !timedOut {
+			return
+		}
+
+		if binding != nil {
+			app.Keymap.pending = nil
+			app.runBinding(binding)
+			return
+		}
+
+		app.Keymap.pending = keys[1:]
+		app.handleUnmappedInput(keys[0])
+	}
+}
+
+// handleUnmappedInput passes the key to the buffer. Combinations with Ctrl do nothing unless they are bound
+func (app *App) handleUnmappedInput(input Input) {
+	if input.Ctrl {
+		return
+	}
+
+	app.handleBufferInput(input)
+
+	// Keys that move the cursor sideways don't scroll on their own
+	app.Buffer.ScrollToCursor()
+}
+
+func (app *App) runBinding(binding *KeyBinding) {
+	if binding.Action != "" {
+		keymapActions[binding.Action](app)
+	} else if binding.Command != "" {
+		app.runCommand(binding.Command)
+	} else {
+		for _, input := range binding.Typed {
+			app.handleUnmappedInput(input)
+		}
+	}
+}
+
+func (app *App) keymapMode() KeymapMode {
+	switch app.Mode {
+	case Mode_Insert:
+		return KeymapMode_Insert
+	case Mode_Visual, Mode_VisualLine:
+		return KeymapMode_Visual
+	}
+
+	return KeymapMode_Normal
+}
+
+// takeCount returns the count typed before the command, 1 when there is none
+func (app *App) takeCount() int {
+	count := 1
+	if app.AmountModifier.Len() > 0 {
+		count, _ = strconv.Atoi(app.AmountModifier.String())
+		app.AmountModifier.Reset()
+	}
+
+	return count
+}
+
+// normalizeKey drops what doesn't tell keys apart. Typed characters are already upper case when Shift is held
+func normalizeKey(input Input) Input {
+	input.CapsLock = false
+	if input.TypedCharacter != 0 {
+		input.Shift = false
+	}
+
+	return input
+}
+
+func normalizeKeys(inputs []Input) (result []Input) {
+	for _, input := range inputs {
+		result = append(result, normalizeKey(input))
+	}
+
+	return
+}
+
+func keysEqual(a []Input, b []Input) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for index := range a {
+		if a[index] != b[index] {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestKeymapFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keymap.akeys")
+	text := strings.Join([]string{
+		"# comment",
+		"timeout 250",
+		"",
+		"normal gd :open main.go",
+		"insert jk <Esc>",
+		"global <F5> save",
+		"nowhere x y",
+		"normal",
+	}, "\n")
+	FailNowIfFalse(os.WriteFile(path, []byte(text), 0644) == nil, "Could not write the keymap", t)
+
+	keymap := CreateKeymap()
+	FailNowIfFalse(keymap.Load(path), "Expected the keymap to load", t)
+	FailIfFalse(keymap.Timeout == 250*time.Millisecond, fmt.Sprintf("Expected a timeout of 250ms, got %s", keymap.Timeout), t)
+
+	binding, _ := keymap.Lookup(KeymapMode_Normal, DecodeKeys("gd"))
+	FailIfFalse(binding != nil && binding.Command == "open main.go", "Expected gd to run a command", t)
+
+	binding, _ = keymap.Lookup(KeymapMode_Insert, DecodeKeys("jk"))
+	FailIfFalse(binding != nil && len(binding.Typed) == 1 && binding.Typed[0].Escape, "Expected jk to type <Esc>", t)
+
+	binding, _ = keymap.Lookup(KeymapMode_Visual, DecodeKeys("<F5>"))
+	FailIfFalse(binding != nil && binding.Action == "save", "Expected global bindings in every mode", t)
+
+	FailIfFalse(len(keymap.Bindings) == 3, fmt.Sprintf("Expected the invalid lines to be skipped, got %v", keymap.Bindings), t)
+	FailIfFalse(!keymap.Load(filepath.Join(t.TempDir(), "missing.akeys")), "Expected a missing keymap not to load", t)
+}
+
+func TestKeymapLookup(t *testing.T) {
+	keymap := CreateKeymap()
+	bind := func(mode KeymapMode, keys string, target string) {
+		binding, ok := ParseKeyBinding(keys, target)
+		FailNowIfFalse(ok, fmt.Sprintf("Could not parse %s %s", keys, target), t)
+		keymap.Bind(mode, binding)
+	}
+
+	bind(KeymapMode_Global, "<C-p>", "file_search")
+	bind(KeymapMode_Normal, "<C-p>", "command_palette")
+	bind(KeymapMode_Normal, "g", "0")
+	bind(KeymapMode_Normal, "gx", ":wrap on")
+
+	t.Run("Mode bindings come before global ones", func(t *testing.T) {
+		binding, _ := keymap.Lookup(KeymapMode_Normal, DecodeKeys("<C-p>"))
+		FailIfFalse(binding != nil && binding.Action == "command_palette", "Expected the normal mode binding", t)
+
+		binding, _ = keymap.Lookup(KeymapMode_Insert, DecodeKeys("<C-p>"))
+		FailIfFalse(binding != nil && binding.Action == "file_search", "Expected the global binding", t)
+	})
+
+	t.Run("Prefixes", func(t *testing.T) {
+		binding, prefix := keymap.Lookup(KeymapMode_Normal, DecodeKeys("g"))
+		FailIfFalse(binding != nil && prefix, "Expected g to be bound and to start a longer binding", t)
+
+		binding, prefix = keymap.Lookup(KeymapMode_Normal, DecodeKeys("gy"))
+		FailIfFalse(binding == nil && !prefix, "Expected nothing to be bound to gy", t)
+	})
+
+	t.Run("Shift and caps lock don't matter for typed characters", func(t *testing.T) {
+		bind(KeymapMode_Global, "<C-O>", "show_in_explorer")
+
+		binding, _ := keymap.Lookup(KeymapMode_Normal, []Input{{TypedCharacter: 'O', Ctrl: true, Shift: true, CapsLock: true}})
+		FailIfFalse(binding != nil && binding.Action == "show_in_explorer", "Expected <C-O> to match with Shift held", t)
+	})
+
+	t.Run("Binding again replaces", func(t *testing.T) {
+		bind(KeymapMode_Normal, "g", "$")
+		FailIfFalse(len(keymap.Bindings[KeymapMode_Normal]) == 3, "Expected the old binding to be replaced", t)
+
+		FailIfFalse(keymap.Unbind(KeymapMode_Normal, DecodeKeys("g")), "Expected g to be unbound", t)
+		FailIfFalse(!keymap.Unbind(KeymapMode_Normal, DecodeKeys("g")), "Expected nothing left to unbind", t)
+	})
+}
+
+func TestKeymap(t *testing.T) {
+	createKeymapTestApp := func(text string) (result App) {
+		result = createTestApp(text)
+		result.registerCommands()
+		return
+	}
+
+	t.Run("Defaults are loaded", func(t *testing.T) {
+		app := createKeymapTestApp("")
+
+		binding, _ := app.Keymap.Lookup(KeymapMode_Normal, DecodeKeys("<C-d>"))
+		FailIfFalse(binding != nil && binding.Action == "scroll_half_page_down", "Expected <C-d> to be bound", t)
+
+		for _, bindings := range app.Keymap.Bindings {
+			for _, binding := range bindings {
+				FailIfFalse(binding.Action != "", fmt.Sprintf("Expected only actions in the defaults, got %s", binding.String()), t)
+			}
+		}
+	})
+
+	t.Run("Sequence types other keys", func(t *testing.T) {
+		app := createKeymapTestApp("one")
+		commandMap(&app, []string{"insert", "jk", "<Esc>"})
+
+		typeKeys(&app, "ixjk")
+		FailIfFalse(app.Mode == Mode_Normal, "Expected jk to leave insert mode", t)
+		FailIfFalse(app.Buffer.LineText(0) == "xone", fmt.Sprintf("Expected %q, got %q", "xone", app.Buffer.LineText(0)), t)
+	})
+
+	t.Run("Keys that don't finish a binding are typed", func(t *testing.T) {
+		app := createKeymapTestApp("one")
+		commandMap(&app, []string{"insert", "jk", "<Esc>"})
+
+		typeKeys(&app, "ijjx")
+		FailIfFalse(app.Mode == Mode_Insert, "Expected to stay in insert mode", t)
+		FailIfFalse(app.Buffer.LineText(0) == "jjxone", fmt.Sprintf("Expected %q, got %q", "jjxone", app.Buffer.LineText(0)), t)
+	})
+
+	t.Run("Waiting keys are typed after the timeout", func(t *testing.T) {
+		app := createKeymapTestApp("one")
+		commandMap(&app, []string{"insert", "jk", "<Esc>"})
+
+		typeKeys(&app, "ij")
+		FailIfFalse(app.Buffer.LineText(0) == "one", "Expected j to wait for the next key", t)
+		FailIfFalse(!app.Keymap.Expired(time.Now()), "Expected the timeout not to have run out yet", t)
+		FailNowIfFalse(app.Keymap.Expired(time.Now().Add(app.Keymap.Timeout)), "Expected the timeout to run out", t)
+
+		app.resolveKeys(true)
+		FailIfFalse(app.Buffer.LineText(0) == "jone", fmt.Sprintf("Expected %q, got %q", "jone", app.Buffer.LineText(0)), t)
+	})
+
+	t.Run("Command binding", func(t *testing.T) {
+		app := createKeymapTestApp("one")
+		app.runCommand("map normal <F5> :wrap 40")
+
+		typeKeys(&app, "<F5>")
+		FailIfFalse(app.Buffer.Wrap && app.Buffer.WrapColumn == 40, "Expected <F5> to turn wrapping on", t)
+	})
+
+	t.Run("Bindings don't apply in the middle of a command", func(t *testing.T) {
+		app := createKeymapTestApp("abcd")
+		commandMap(&app, []string{"normal", "d", "$"})
+
+		typeKeys(&app, "fd")
+		FailIfFalse(app.Buffer.Cursor.Column == 3, fmt.Sprintf("Expected the cursor on the d, got %d", app.Buffer.Cursor.Column), t)
+
+		typeKeys(&app, "0d")
+		FailIfFalse(app.Buffer.Cursor.Column == 4, fmt.Sprintf("Expected d to move to the end, got %d", app.Buffer.Cursor.Column), t)
+	})
+
+	t.Run("Unbound Ctrl keys do nothing", func(t *testing.T) {
+		app := createKeymapTestApp("one")
+		typeKeys(&app, "<C-x><C-l>")
+		FailIfFalse(app.Buffer.LineText(0) == "one" && app.Buffer.Cursor.Column == 0, "Expected nothing to change", t)
+
+		commandUnmap(&app, []string{"normal", "<C-d>"})
+		typeKeys(&app, "<C-d>")
+		FailIfFalse(app.Buffer.LineText(0) == "one", "Expected unbound <C-d> not to delete", t)
+	})
+
+	t.Run("Function and page keys in macro notation", func(t *testing.T) {
+		keys := DecodeKeys("<F5><S-F12><PageUp><C-PageDown><Insert>")
+		FailNowIfFalse(len(keys) == 5, fmt.Sprintf("Expected 5 keys, got %d", len(keys)), t)
+		FailIfFalse(keys[0].Function == 5 && keys[1].Function == 12 && keys[1].Shift, "Expected function keys", t)
+		FailIfFalse(keys[2].PageUp && keys[3].PageDown && keys[3].Ctrl && keys[4].Insert, "Expected page keys", t)
+
+		text := EncodeKeys(keys)
+		FailIfFalse(text == "<F5><S-F12><PageUp><C-PageDown><Insert>", fmt.Sprintf("Unexpected text %q", text), t)
+	})
+}
@@ -0,0 +1,66 @@
+package main
+
+import "sort"
+
+// LineIndex keeps the starts of the lines of the buffer and is split at the gap the same way the text is. Starts of the
+// lines before the gap are offsets from the start of the text. Starts of the lines after the gap are distances from the end
+// of the data, nearest to the gap last. Edits and cursor moves at the gap only ever touch the ends of the two lists
+type LineIndex struct {
+	Before []int // The first line always starts at 0
+	After  []int
+}
+
+func CreateLineIndex(data []byte, gapEnd int) (result LineIndex) {
+	result.Before = []int{0}
+
+	for i := len(data) - 1; i > gapEnd; i -= 1 {
+		if data[i] == '\n' {
+			result.After = append(result.After, len(data)-i-1)
+		}
+	}
+
+	return
+}
+
+func (index *LineIndex) Count() int {
+	return len(index.Before) + len(index.After)
+}
+
+// Start returns the offset of the line in the text without the gap
+func (index *LineIndex) Start(line int, dataSize int, gapSize int) int {
+	if line < len(index.Before) {
+		return index.Before[line]
+	}
+
+	distance := index.After[len(index.After)-1-(line-len(index.Before))]
+	return dataSize - distance - gapSize
+}
+
+// Line returns the line the offset is on
+func (index *LineIndex) Line(offset int, dataSize int, gapSize int) int {
+	return sort.Search(index.Count(), func(line int) bool {
+		return index.Start(line, dataSize, gapSize) > offset
+	}) - 1
+}
+
+func (index *LineIndex) PushBefore(start int) {
+	index.Before = append(index.Before, start)
+}
+
+func (index *LineIndex) PopBefore() (result int) {
+	result = index.Before[len(index.Before)-1]
+	index.Before = index.Before[:len(index.Before)-1]
+
+	return
+}
+
+func (index *LineIndex) PushAfter(distance int) {
+	index.After = append(index.After, distance)
+}
+
+func (index *LineIndex) PopAfter() (result int) {
+	result = index.After[len(index.After)-1]
+	index.After = index.After[:len(index.After)-1]
+
+	return
+}
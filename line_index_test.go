@@ -0,0 +1,148 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+// generateGoSource returns a Go file with 8 lines for every function, plus 3 lines of header
+func generateGoSource(functions int) []byte {
+	var sb strings.Builder
+	sb.WriteString("package main\n\nimport \"fmt\"\n")
+	for index := 0; index < functions; index += 1 {
+		fmt.Fprintf(&sb, "\n// function%d prints its argument\nfunc function%d(value int) {\n\tif value > %d {\n\t\tfmt.Println(\"large\", value)\n\t}\n\treturn\n}\n", index, index, index)
+	}
+
+	return []byte(sb.String())
+}
+
+func expectLineIndex(buffer *Buffer, t *testing.T) {
+	text := buffer.GetText()
+	FailNowIfFalse(buffer.Lines.Count() == len(text), fmt.Sprintf("Expected %d lines in the index, got %d", len(text), buffer.Lines.Count()), t)
+	FailIfFalse(buffer.TotalLines == len(text), fmt.Sprintf("Expected %d total lines, got %d", len(text), buffer.TotalLines), t)
+	FailIfFalse(int(buffer.Cursor.Line) == len(buffer.Lines.Before)-1, "Expected the cursor on the last line before the gap", t)
+
+	for index, line := range text {
+		FailIfFalse(buffer.LineText(int32(index)) == line, fmt.Sprintf("Expected line %d to be %q, got %q", index, line, buffer.LineText(int32(index))), t)
+
+		start := buffer.lineStart(int32(index))
+		FailIfFalse(buffer.lineAt(start) == int32(index), fmt.Sprintf("Expected offset %d to be on line %d", start, index), t)
+	}
+}
+
+func TestLineIndex(t *testing.T) {
+	fakeFont := GetFakeFont()
+	buffer := CreateBuffer(16, &fakeFont, sdl.Rect{H: 600})
+
+	t.Run("Built from the data", func(t *testing.T) {
+		buffer.SetData([]byte("one\n\ntwo\nthree\n"), "")
+		expectLineIndex(&buffer, t)
+	})
+
+	t.Run("Follows typing and removing", func(t *testing.T) {
+		buffer.MoveToLine(3)
+		buffer.MoveToEndOfLine()
+		buffer.Insert('\n')
+		buffer.Insert('x')
+		buffer.Insert('{')
+		buffer.Insert('\n')
+		expectLineIndex(&buffer, t)
+
+		buffer.RemoveBefore()
+		buffer.RemoveBefore()
+		buffer.MoveToStartOfLine()
+		buffer.RemoveBefore()
+		expectLineIndex(&buffer, t)
+
+		buffer.MoveToLine(1)
+		buffer.RemoveCurrentLine()
+		buffer.MergeLineBelow()
+		expectLineIndex(&buffer, t)
+	})
+
+	t.Run("Follows the gap across many lines", func(t *testing.T) {
+		buffer.SetData(generateGoSource(100), "")
+
+		buffer.MoveToLine(500)
+		FailIfFalse(buffer.Cursor.Line == 499, fmt.Sprintf("Expected line 499, got %d", buffer.Cursor.Line), t)
+		buffer.Insert('a')
+		expectLineIndex(&buffer, t)
+
+		buffer.MoveToBufferStart()
+		buffer.MoveDownByLines(20)
+		buffer.Insert('\n')
+		expectLineIndex(&buffer, t)
+
+		buffer.MoveToBufferEnd()
+		FailIfFalse(int(buffer.Cursor.Line) == buffer.TotalLines-1, "Expected the cursor on the last line", t)
+		expectLineIndex(&buffer, t)
+	})
+
+	t.Run("Keeps the column when moving over lines", func(t *testing.T) {
+		buffer.SetData([]byte("abcdef\nab\n\nabcdefgh"), "")
+		buffer.MoveToPosition(0, 4)
+
+		buffer.MoveDown()
+		FailIfFalse(buffer.Cursor.Column == 2, fmt.Sprintf("Expected column 2 on a short line, got %d", buffer.Cursor.Column), t)
+
+		buffer.MoveToLine(4)
+		FailIfFalse(buffer.Cursor.Column == 4, fmt.Sprintf("Expected column 4 back on a long line, got %d", buffer.Cursor.Column), t)
+
+		buffer.MoveUp()
+		FailIfFalse(buffer.Cursor.Column == 0, fmt.Sprintf("Expected column 0 on an empty line, got %d", buffer.Cursor.Column), t)
+	})
+}
+
+func createBenchmarkBuffer() (result Buffer) {
+	fakeFont := GetFakeFont()
+	result = CreateBuffer(16, &fakeFont, sdl.Rect{W: 1280, H: 720})
+	result.SetData(generateGoSource(1250), "bench.go")
+
+	return
+}
+
+func BenchmarkMoveToLine(b *testing.B) {
+	buffer := createBenchmarkBuffer()
+
+	for i := 0; i < b.N; i += 1 {
+		buffer.MoveToLine(int32(1 + i*7919%buffer.TotalLines))
+	}
+}
+
+func BenchmarkMoveDown(b *testing.B) {
+	buffer := createBenchmarkBuffer()
+
+	for i := 0; i < b.N; i += 1 {
+		if int(buffer.Cursor.Line) == buffer.TotalLines-1 {
+			buffer.MoveToBufferStart()
+		}
+		buffer.MoveDown()
+	}
+}
+
+func BenchmarkVisibleLines(b *testing.B) {
+	buffer := createBenchmarkBuffer()
+	buffer.MoveToLine(5000)
+
+	for i := 0; i < b.N; i += 1 {
+		for line := int32(4960); line < 5000; line += 1 {
+			buffer.LineText(line)
+		}
+	}
+}
+
+func BenchmarkInsert(b *testing.B) {
+	buffer := createBenchmarkBuffer()
+	buffer.MoveToLine(5000)
+
+	for i := 0; i < b.N; i += 1 {
+		if i%80 == 79 {
+			buffer.Insert('\n')
+		} else {
+			buffer.Insert('x')
+		}
+	}
+}
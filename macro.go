@@ -0,0 +1,304 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+)
+
+// Macros are kept in the registers as text, in the same notation as vim: typed characters as they are and other keys in angle brackets, like <Esc> or <C-w>
+
+var macroKeyNames = []struct {
+	Name string
+	Get  func(input *Input) *bool
+}{
+	{"Esc", func(input *Input) *bool { return &input.Escape }},
+	{"BS", func(input *Input) *bool { return &input.Backspace }},
+	{"Del", func(input *Input) *bool { return &input.Delete }},
+	{"Left", func(input *Input) *bool { return &input.Left }},
+	{"Right", func(input *Input) *bool { return &input.Right }},
+	{"Up", func(input *Input) *bool { return &input.Up }},
+	{"Down", func(input *Input) *bool { return &input.Down }},
+	{"Home", func(input *Input) *bool { return &input.Home }},
+	{"End", func(input *Input) *bool { return &input.End }},
+	{"PageUp", func(input *Input) *bool { return &input.PageUp }},
+	{"PageDown", func(input *Input) *bool { return &input.PageDown }},
+	{"Insert", func(input *Input) *bool { return &input.Insert }},
+}
+
+var macroCharacterNames = map[byte]string{
+	'\n': "CR",
+	'\t': "Tab",
+	'<':  "lt",
+	' ':  "Space",
+}
+
+// Macros this deep are most likely playing themselves
+const maxMacroDepth = 20
+
+// IsKey reports whether a key was pressed, as opposed to only holding a modifier
+func (input *Input) IsKey() bool {
+	if input.TypedCharacter != 0 || input.Function != 0 {
+		return true
+	}
+
+	for _, key := range macroKeyNames {
+		if *key.Get(input) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func EncodeKeys(inputs []Input) string {
+	var sb strings.Builder
+
+	for _, input := range inputs {
+		modifiers := ""
+		if input.Ctrl {
+			modifiers += "C-"
+		}
+		if input.Alt {
+			modifiers += "A-"
+		}
+
+		for _, key := range macroKeyNames {
+			if !*key.Get(&input) {
+				continue
+			}
+
+			// Shift only matters for the named keys, typed characters are already upper case
+			shift := ""
+			if input.Shift {
+				shift = "S-"
+			}
+			sb.WriteString("<" + modifiers + shift + key.Name + ">")
+		}
+
+		if input.Function != 0 {
+			shift := ""
+			if input.Shift {
+				shift = "S-"
+			}
+			sb.WriteString(fmt.Sprintf("<%s%sF%d>", modifiers, shift, input.Function))
+		}
+
+		char := input.TypedCharacter
+		if char == 0 {
+			continue
+		}
+
+		name, named := macroCharacterNames[char]
+		if !named {
+			name = string(char)
+		}
+
+		// A space on its own is easier to read as it is
+		if modifiers == "" && (!named || char == ' ') {
+			sb.WriteByte(char)
+		} else {
+			sb.WriteString("<" + modifiers + name + ">")
+		}
+	}
+
+	return encodeSpaces(sb.String())
+}
+
+// encodeSpaces writes the spaces at the ends and the runs of spaces as <Space>. :register splits its arguments at
+// spaces, so only single spaces between other keys survive being typed there
+func encodeSpaces(text string) string {
+	var sb strings.Builder
+	for i := 0; i < len(text); i += 1 {
+		inner := i > 0 && i < len(text)-1 && text[i-1] != ' ' && text[i+1] != ' '
+		if text[i] == ' ' && !inner {
+			sb.WriteString("<Space>")
+		} else {
+			sb.WriteByte(text[i])
+		}
+	}
+
+	return sb.String()
+}
+
+// DecodeKeys turns macro text back into key presses. Anything in angle brackets that is not a key is typed as it is
+func DecodeKeys(text string) (result []Input) {
+	for index := 0; index < len(text); index += 1 {
+		if text[index] == '<' {
+			end := strings.IndexByte(text[index:], '>')
+			if end > 1 {
+				input, ok := decodeKey(text[index+1 : index+end])
+				if ok {
+					result = append(result, input)
+					index += end
+					continue
+				}
+			}
+		}
+
+		result = append(result, Input{TypedCharacter: text[index]})
+	}
+
+	return
+}
+
+func decodeKey(name string) (result Input, ok bool) {
+	for len(name) > 2 && name[1] == '-' {
+		switch name[0] {
+		case 'C':
+			result.Ctrl = true
+		case 'A':
+			result.Alt = true
+		case 'S':
+			result.Shift = true
+		default:
+			return result, false
+		}
+
+		name = name[2:]
+	}
+
+	for _, key := range macroKeyNames {
+		if strings.EqualFold(key.Name, name) {
+			*key.Get(&result) = true
+			return result, true
+		}
+	}
+
+	if len(name) > 1 && (name[0] == 'F' || name[0] == 'f') {
+		number, err := strconv.Atoi(name[1:])
+		if err == nil && number >= 1 && number <= 12 {
+			result.Function = uint8(number)
+			return result, true
+		}
+	}
+
+	for char, charName := range macroCharacterNames {
+		if strings.EqualFold(charName, name) {
+			result.TypedCharacter = char
+			return result, true
+		}
+	}
+
+	// Single characters need a modifier, <a> is just text
+	if len(name) == 1 && (result.Ctrl || result.Alt) {
+		result.TypedCharacter = name[0]
+		return result, true
+	}
+
+	return result, false
+}
+
+func isUpper(char byte) bool {
+	return char >= 'A' && char <= 'Z'
+}
+
+func toLower(char byte) byte {
+	if isUpper(char) {
+		return char - 'A' + 'a'
+	}
+
+	return char
+}
+
+func (app *App) recordInput(input Input) {
+	if app.MacroRegister == 0 || app.macroDepth > 0 || !input.IsKey() {
+		return
+	}
+
+	app.macroKeys = append(app.macroKeys, input)
+}
+
+// startRecording records into a register a-z. A-Z append to the lower case register
+func (app *App) startRecording(name byte) {
+	if !isAlpha(name) {
+		log.Printf("Can't record into register %c", name)
+		return
+	}
+
+	app.MacroRegister = name
+	app.macroKeys = nil
+}
+
+func (app *App) stopRecording() {
+	// The last key is the q that stopped the recording
+	keys := app.macroKeys
+	if len(keys) > 0 {
+		keys = keys[:len(keys)-1]
+	}
+
+	name := app.MacroRegister
+	text := EncodeKeys(keys)
+	if isUpper(name) {
+		name = toLower(name)
+		text = app.Registers[name] + text
+	}
+
+	app.Registers[name] = text
+	app.MacroRegister = 0
+	app.macroKeys = nil
+}
+
+// playMacro feeds the keys in the register to the editor count times. @ plays the last played macro
+func (app *App) playMacro(name byte, count int) {
+	if name == '@' {
+		name = app.lastMacro
+	} else if isUpper(name) {
+		name = toLower(name)
+	}
+
+	text, ok := app.Registers[name]
+	if !ok {
+		log.Printf("Register %c is empty", name)
+		return
+	}
+
+	if app.macroDepth >= maxMacroDepth {
+		log.Printf("Macro %c was stopped, it plays itself", name)
+		return
+	}
+
+	app.lastMacro = name
+	keys := DecodeKeys(text)
+
+	app.macroDepth += 1
+	for i := 0; i < count; i += 1 {
+		for _, input := range keys {
+			app.handleInput(input)
+		}
+	}
+	app.macroDepth -= 1
+}
+
+func (app *App) handleInputSubmodeMacro(input Input) {
+	if input.Ctrl || input.Alt {
+		return
+	}
+
+	if input.Escape {
+		app.AmountModifier.Reset()
+		app.Submode = Submode_None
+		return
+	}
+
+	if input.TypedCharacter == 0 {
+		return
+	}
+
+	submode := app.Submode
+	app.Submode = Submode_None
+
+	if submode == Submode_Record {
+		app.startRecording(input.TypedCharacter)
+		return
+	}
+
+	count := 1
+	if app.AmountModifier.Len() > 0 {
+		count, _ = strconv.Atoi(app.AmountModifier.String())
+		app.AmountModifier.Reset()
+	}
+
+	app.playMacro(input.TypedCharacter, count)
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestMacroNotation(t *testing.T) {
+	inputs := []Input{
+		{TypedCharacter: 'A'},
+		{TypedCharacter: ' '},
+		{TypedCharacter: '<'},
+		{TypedCharacter: '\n'},
+		{Escape: true},
+		{TypedCharacter: 'w', Ctrl: true},
+		{Left: true, Shift: true},
+	}
+
+	text := EncodeKeys(inputs)
+	FailIfFalse(text == "A <lt><CR><Esc><C-w><S-Left>", fmt.Sprintf("Unexpected macro text %q", text), t)
+
+	decoded := DecodeKeys(text)
+	FailNowIfFalse(len(decoded) == len(inputs), fmt.Sprintf("Expected %d keys, got %d", len(inputs), len(decoded)), t)
+	for index := range inputs {
+		FailIfFalse(decoded[index] == inputs[index], fmt.Sprintf("Key %d did not survive the round trip", index), t)
+	}
+
+	spaces := EncodeKeys([]Input{{TypedCharacter: ' '}, {TypedCharacter: 'a'}, {TypedCharacter: ' '}})
+	FailIfFalse(spaces == "<Space>a<Space>", fmt.Sprintf("Expected named spaces at the ends, got %q", spaces), t)
+
+	plain := DecodeKeys("<a> <b")
+	FailIfFalse(len(plain) == 6 && plain[0].TypedCharacter == '<', "Unknown keys should be typed as text", t)
+}
+
+func TestMacroPlayback(t *testing.T) {
+	t.Run("Recorded and played with a count", func(t *testing.T) {
+		app := createTestApp("one\ntwo\nthree\nfour")
+
+		typeKeys(&app, "qaA;<Esc>jq")
+		FailIfFalse(app.Registers['a'] == "A;<Esc>j", fmt.Sprintf("Unexpected register text %q", app.Registers['a']), t)
+		FailIfFalse(app.MacroRegister == 0, "Recording should have stopped", t)
+
+		typeKeys(&app, "2@a")
+		typeKeys(&app, "@@")
+
+		text := app.Buffer.GetText()
+		expected := []string{"one;", "two;", "three;", "four;"}
+		FailNowIfFalse(len(text) == len(expected), "Incorrect line count", t)
+		for index, line := range text {
+			FailIfFalse(line == expected[index], fmt.Sprintf("Expected line %d to be %s, got %s", index, expected[index], line), t)
+		}
+	})
+
+	t.Run("Upper case appends", func(t *testing.T) {
+		app := createTestApp("abc")
+		app.Registers['a'] = "x"
+
+		typeKeys(&app, "qAxq")
+		FailIfFalse(app.Registers['a'] == "xx", fmt.Sprintf("Expected the keys to be appended, got %q", app.Registers['a']), t)
+	})
+
+	t.Run("Spaces survive editing the register", func(t *testing.T) {
+		app := createTestApp("")
+
+		typeKeys(&app, "qaI Hello  World<Esc>q")
+		FailIfFalse(app.Registers['a'] == "I Hello<Space><Space>World<Esc>", fmt.Sprintf("Unexpected register text %q", app.Registers['a']), t)
+
+		commandRegister(&app, strings.Fields("b "+app.Registers['a']+"A<Space><Esc>"))
+		typeKeys(&app, "dd@b")
+		text := app.Buffer.GetText()
+		FailIfFalse(text[0] == " Hello  World ", fmt.Sprintf("Expected the spaces to be typed, got %q", text[0]), t)
+	})
+
+	t.Run("Edited register text is played", func(t *testing.T) {
+		app := createTestApp("abc")
+		app.Registers['b'] = "I<lt>-<Esc>"
+
+		typeKeys(&app, "@b")
+		text := app.Buffer.GetText()
+		FailIfFalse(text[0] == "<-abc", fmt.Sprintf("Expected <-abc, got %s", text[0]), t)
+	})
+
+	t.Run("Macros that play themselves stop", func(t *testing.T) {
+		app := createTestApp("abc")
+		app.Registers['c'] = "x@c"
+
+		typeKeys(&app, "@c")
+		text := app.Buffer.GetText()
+		FailIfFalse(text[0] == "", fmt.Sprintf("Expected every character to be removed, got %s", text[0]), t)
+		FailIfFalse(app.macroDepth == 0, "Expected every macro to finish", t)
+	})
+}
@@ -122,8 +122,14 @@ func keyToCharacter(key sdl.Keycode, mod uint16) byte {
 	return 0
 }
 
+// Milliseconds to wait on frames where nothing changed, roughly the length of a frame at 60 fps
+const idleFrameDelay = 16
+
+func toPixels(points int32, scale float32) int32 {
+	return int32(float32(points) * scale)
+}
+
 func main() {
-	// @TODO (!important) is it normal for the app to use 20MB of memory with 20 line file open
 	err := sdl.Init(sdl.INIT_EVERYTHING)
 	checkError(err)
 	defer sdl.Quit()
@@ -132,24 +138,57 @@ func main() {
 	checkError(err)
 	defer ttf.Quit()
 
-	window, err := sdl.CreateWindow("Agurkas", sdl.WINDOWPOS_UNDEFINED, sdl.WINDOWPOS_UNDEFINED, 800, 600, sdl.WINDOW_RESIZABLE|sdl.WINDOW_MAXIMIZED)
+	settings, settingsErrors := ReadSettings(UserSettingsPath())
+
+	var windowFlags uint32 = sdl.WINDOW_RESIZABLE | sdl.WINDOW_ALLOW_HIGHDPI
+	if settings.Maximized {
+		windowFlags |= sdl.WINDOW_MAXIMIZED
+	}
+
+	window, err := sdl.CreateWindow("Agurkas", sdl.WINDOWPOS_UNDEFINED, sdl.WINDOWPOS_UNDEFINED, settings.WindowWidth, settings.WindowHeight, windowFlags)
 	checkError(err)
 	defer window.Destroy()
 
+	// Glyphs are drawn one copy at a time from the font atlas, SDL turns those into a single draw call
+	sdl.SetHint(sdl.HINT_RENDER_BATCHING, "1")
+
 	renderer, err := sdl.CreateRenderer(window, -1, sdl.RENDERER_ACCELERATED|sdl.RENDERER_PRESENTVSYNC)
 	checkError(err)
 	defer renderer.Destroy()
 
-	windowWidth, windowHeight := window.GetSize()
+	// The editor draws in pixels, while the window and the mouse are measured in points. On displays with a high pixel
+	// density a point is more than one pixel
+	drawableSize := func() (width int32, height int32, scale float32) {
+		width, height, err := renderer.GetOutputSize()
+		checkError(err)
+
+		scale = 1
+		windowWidth, _ := window.GetSize()
+		if windowWidth > 0 {
+			scale = float32(width) / float32(windowWidth)
+		}
+
+		return
+	}
 
-	app := Init(renderer, windowWidth, windowHeight)
+	windowWidth, windowHeight, scale := drawableSize()
+
+	canvas := CreateSDLCanvas(renderer)
+	app := Init(windowWidth, windowHeight, scale, settings)
+	defer app.Close()
 	input := Input{}
+	mouse := Mouse{}
 
 	window.SetIcon(app.Icon)
+	app.RecoverSwapFiles()
+	if len(settingsErrors) > 0 {
+		app.reportParseErrors(app.SettingsPath, settingsErrors)
+	}
 
 	running := true
 	for running {
 		input.Clear()
+		mouse.Clear()
 
 		for event := sdl.PollEvent(); event != nil; event = sdl.PollEvent() {
 			switch t := event.(type) {
@@ -179,23 +218,101 @@ func main() {
 					if t.State != sdl.RELEASED {
 						input.Escape = true
 					}
+				case sdl.K_DELETE:
+					if t.State != sdl.RELEASED {
+						input.Delete = true
+					}
+				case sdl.K_LEFT:
+					if t.State != sdl.RELEASED {
+						input.Left = true
+					}
+				case sdl.K_RIGHT:
+					if t.State != sdl.RELEASED {
+						input.Right = true
+					}
+				case sdl.K_UP:
+					if t.State != sdl.RELEASED {
+						input.Up = true
+					}
+				case sdl.K_DOWN:
+					if t.State != sdl.RELEASED {
+						input.Down = true
+					}
+				case sdl.K_HOME:
+					if t.State != sdl.RELEASED {
+						input.Home = true
+					}
+				case sdl.K_END:
+					if t.State != sdl.RELEASED {
+						input.End = true
+					}
+				case sdl.K_PAGEUP:
+					if t.State != sdl.RELEASED {
+						input.PageUp = true
+					}
+				case sdl.K_PAGEDOWN:
+					if t.State != sdl.RELEASED {
+						input.PageDown = true
+					}
+				case sdl.K_INSERT:
+					if t.State != sdl.RELEASED {
+						input.Insert = true
+					}
 				case sdl.K_CAPSLOCK:
 					if t.Type == sdl.KEYDOWN && t.Repeat == 0 {
 						input.CapsLock = !input.CapsLock
 					}
 				default:
-					if t.State != sdl.RELEASED {
+					if t.State == sdl.RELEASED {
+						break
+					}
+
+					// The function keys follow each other in SDL
+					if keycode >= sdl.K_F1 && keycode <= sdl.K_F12 {
+						input.Function = uint8(keycode-sdl.K_F1) + 1
+					} else {
 						input.TypedCharacter = keyToCharacter(keycode, t.Keysym.Mod)
 					}
 				}
+			case *sdl.MouseButtonEvent:
+				if t.Button == sdl.BUTTON_LEFT {
+					mouse.X, mouse.Y = toPixels(t.X, scale), toPixels(t.Y, scale)
+					mouse.Held = t.State == sdl.PRESSED
+					mouse.Pressed = mouse.Pressed || mouse.Held
+					mouse.Released = mouse.Released || !mouse.Held
+					if mouse.Held {
+						mouse.Clicks = t.Clicks
+					}
+				}
+			case *sdl.MouseMotionEvent:
+				mouse.X, mouse.Y = toPixels(t.X, scale), toPixels(t.Y, scale)
+				mouse.Moved = true
+			case *sdl.MouseWheelEvent:
+				if t.Direction == sdl.MOUSEWHEEL_FLIPPED {
+					mouse.WheelY -= t.Y
+				} else {
+					mouse.WheelY += t.Y
+				}
 			case *sdl.WindowEvent:
 				if t.Event == sdl.WINDOWEVENT_RESIZED {
-					app.Resized(t.Data1, t.Data2)
+					// The window may have moved to a display with another pixel density
+					windowWidth, windowHeight, scale = drawableSize()
+					app.SetScale(scale)
+					app.Resized(windowWidth, windowHeight)
 				}
+
+				// The window may have been covered or shown again, so its contents are drawn again
+				app.Damaged = true
 			}
 		}
 
 		app.Tick(input)
-		app.Render(renderer)
+		app.HandleMouse(mouse)
+		if app.Damaged {
+			app.Render(canvas)
+		} else {
+			// Nothing changed and the last frame is still on the screen, so the frame is skipped
+			sdl.Delay(idleFrameDelay)
+		}
 	}
 }
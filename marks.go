@@ -0,0 +1,118 @@
+package main
+
+import "sort"
+
+type NamedMark struct {
+	Name     byte
+	Position Position
+}
+
+// Marks are named positions. Lower case marks a-z belong to a file, upper case marks A-Z are global and remember their file
+type Marks struct {
+	Local  map[string]map[byte]Position // By file path
+	Global map[byte]Position
+}
+
+func CreateMarks() (result Marks) {
+	result.Local = map[string]map[byte]Position{}
+	result.Global = map[byte]Position{}
+
+	return
+}
+
+func IsLocalMark(name byte) bool {
+	return name >= 'a' && name <= 'z'
+}
+
+func IsGlobalMark(name byte) bool {
+	return name >= 'A' && name <= 'Z'
+}
+
+// Set places the mark, local marks go to the file of the position. Returns false if the name is not a mark
+func (marks *Marks) Set(name byte, position Position) bool {
+	if IsLocalMark(name) {
+		if marks.Local[position.Filepath] == nil {
+			marks.Local[position.Filepath] = map[byte]Position{}
+		}
+		marks.Local[position.Filepath][name] = position
+
+		return true
+	}
+
+	// A global mark has to be able to open its file again
+	if IsGlobalMark(name) && position.Filepath != "" {
+		marks.Global[name] = position
+		return true
+	}
+
+	return false
+}
+
+// Get finds the mark, local marks are looked up in the file
+func (marks *Marks) Get(name byte, filepath string) (Position, bool) {
+	if IsLocalMark(name) {
+		position, ok := marks.Local[filepath][name]
+		return position, ok
+	}
+
+	position, ok := marks.Global[name]
+	return position, ok
+}
+
+// List returns the marks ordered by name, the local ones of every file when filepath is empty
+func (marks *Marks) List(filepath string) (result []NamedMark) {
+	for path, local := range marks.Local {
+		if filepath != "" && path != filepath {
+			continue
+		}
+
+		for name, position := range local {
+			result = append(result, NamedMark{Name: name, Position: position})
+		}
+	}
+
+	for name, position := range marks.Global {
+		if filepath == "" || position.Filepath == filepath {
+			result = append(result, NamedMark{Name: name, Position: position})
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Name != result[j].Name {
+			return result[i].Name < result[j].Name
+		}
+
+		return result[i].Position.Filepath < result[j].Position.Filepath
+	})
+
+	return
+}
+
+// ByLine groups the names of the marks in the file by line, for drawing them in the gutter
+func (marks *Marks) ByLine(filepath string) map[int32]string {
+	result := map[int32]string{}
+	for _, mark := range marks.List(filepath) {
+		result[mark.Position.Line] += string(mark.Name)
+	}
+
+	return result
+}
+
+// ShiftLines moves the marks in the file at or below the line by delta, like PositionList.ShiftLines
+func (marks *Marks) ShiftLines(filepath string, from int32, delta int32) {
+	shift := func(position Position) Position {
+		if position.Filepath == filepath && position.Line >= from {
+			position.Line = int32(Max(int(position.Line+delta), 0))
+		}
+
+		return position
+	}
+
+	for name, position := range marks.Local[filepath] {
+		marks.Local[filepath][name] = shift(position)
+	}
+
+	for name, position := range marks.Global {
+		marks.Global[name] = shift(position)
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+func TestMarks(t *testing.T) {
+	marks := CreateMarks()
+
+	t.Run("Local marks belong to a file", func(t *testing.T) {
+		FailIfFalse(marks.Set('a', Position{Filepath: "a.go", Line: 4, Column: 2}), "Expected mark a to be set", t)
+		FailIfFalse(marks.Set('a', Position{Filepath: "b.go", Line: 9}), "Expected mark a to be set in another file", t)
+
+		position, ok := marks.Get('a', "a.go")
+		FailIfFalse(ok && position.Line == 4 && position.Column == 2, "Expected mark a of a.go", t)
+
+		position, ok = marks.Get('a', "b.go")
+		FailIfFalse(ok && position.Line == 9, "Expected mark a of b.go", t)
+
+		_, ok = marks.Get('a', "c.go")
+		FailIfFalse(!ok, "Mark a should not be set in c.go", t)
+	})
+
+	t.Run("Global marks remember their file", func(t *testing.T) {
+		FailIfFalse(!marks.Set('A', Position{Line: 1}), "Global marks need a file", t)
+		FailIfFalse(!marks.Set('1', Position{Filepath: "a.go"}), "Only letters are marks", t)
+
+		marks.Set('A', Position{Filepath: "b.go", Line: 1})
+		position, ok := marks.Get('A', "a.go")
+		FailIfFalse(ok && position.Filepath == "b.go", "Expected mark A to be found from any file", t)
+	})
+
+	t.Run("Listed by name", func(t *testing.T) {
+		all := marks.List("")
+		FailNowIfFalse(len(all) == 3, fmt.Sprintf("Expected 3 marks, got %d", len(all)), t)
+		FailIfFalse(all[0].Name == 'A' && all[1].Position.Filepath == "a.go" && all[2].Position.Filepath == "b.go", "Expected marks ordered by name and file", t)
+
+		byLine := marks.ByLine("b.go")
+		FailIfFalse(byLine[1] == "A" && byLine[9] == "a", "Expected the marks of b.go by line", t)
+	})
+}
+
+func TestMarksFollowEdits(t *testing.T) {
+	fakeFont := GetFakeFont()
+	buffer := CreateBuffer(16, &fakeFont, sdl.Rect{})
+	buffer.SetData([]byte("one\n    two\nthree"), "test.txt")
+
+	buffer.MoveToPosition(1, 6)
+	buffer.SetMark('a')
+	buffer.SetMark('B')
+
+	buffer.MoveToPosition(0, 0)
+	buffer.InsertNewLineAbove()
+	buffer.Insert('x')
+
+	position, _ := buffer.Marks.Get('a', "test.txt")
+	FailIfFalse(position.Line == 2 && position.Column == 6, fmt.Sprintf("Expected mark a at 2:6, got %d:%d", position.Line, position.Column), t)
+
+	position, _ = buffer.Marks.Get('B', "")
+	FailIfFalse(position.Line == 2, fmt.Sprintf("Expected mark B on line 2, got %d", position.Line), t)
+
+	buffer.RemoveCurrentLine()
+	position, _ = buffer.Marks.Get('a', "test.txt")
+	FailIfFalse(position.Line == 1, fmt.Sprintf("Expected mark a back on line 1, got %d", position.Line), t)
+
+	buffer.MoveToPosition(position.Line, 0)
+	buffer.MoveToFirstNonWhitespace()
+	FailIfFalse(buffer.Cursor.Column == 4, fmt.Sprintf("Expected the cursor on the first non blank character, got %d", buffer.Cursor.Column), t)
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+// Mouse is what the mouse did during a frame. It is kept apart from Input, so clicks are never recorded into macros or
+// repeated by . where the text under them may be different
+type Mouse struct {
+	X        int32
+	Y        int32
+	Pressed  bool  // The left button went down this frame
+	Released bool  // The left button went up this frame
+	Held     bool  // The left button is down, kept across frames
+	Clicks   uint8 // 1 for a single click, 2 for a double click
+	Moved    bool
+	WheelY   int32 // Positive away from the user
+}
+
+// Rows scrolled for every step of the mouse wheel
+const mouseWheelRows = 3
+
+func (mouse *Mouse) Clear() {
+	mouse.Pressed = false
+	mouse.Released = false
+	mouse.Clicks = 0
+	mouse.Moved = false
+	mouse.WheelY = 0
+}
+
+// IsEvent reports whether the mouse did anything the editor reacts to. Moving without the button down does not count
+func (mouse *Mouse) IsEvent() bool {
+	return mouse.Pressed || mouse.Released || (mouse.Moved && mouse.Held) || mouse.WheelY != 0
+}
+
+func (mouse *Mouse) Point() sdl.Point {
+	return sdl.Point{X: mouse.X, Y: mouse.Y}
+}
+
+// HandleMouse passes the mouse to whatever is under it. Nothing else reacts to the mouse while a prompt, the command
+// palette or the search is open
+func (app *App) HandleMouse(mouse Mouse) {
+	if !mouse.IsEvent() {
+		return
+	}
+	app.Damaged = true
+
+	if mouse.Released {
+		app.dragging = false
+	}
+
+	if app.PromptOpen || app.CommandPaletteOpen || app.SearchOpen {
+		return
+	}
+
+	if app.FileSearchOpen {
+		app.FileSearch.TickMouse(mouse, &app.Buffer.Rect)
+		return
+	}
+
+	point := mouse.Point()
+	if mouse.Pressed && point.InRect(&app.StatusBar.Rect) {
+		if item, ok := app.StatusBar.ItemAt(point); ok {
+			app.clickStatusBar(item)
+		}
+		return
+	}
+
+	if mouse.WheelY != 0 {
+		app.Buffer.ScrollRows(-mouse.WheelY * mouseWheelRows)
+	}
+
+	if mouse.Pressed && point.InRect(&app.Buffer.Rect) {
+		app.clickBuffer(mouse)
+	} else if mouse.Moved && mouse.Held && app.dragging {
+		app.dragBuffer(mouse)
+	}
+}
+
+// clickBuffer puts the cursor on the character that was clicked, a double click selects the word under it
+func (app *App) clickBuffer(mouse Mouse) {
+	if app.Mode != Mode_Insert {
+		app.startNormalMode()
+	}
+
+	line, column := app.Buffer.PositionAt(mouse.X, mouse.Y)
+	app.Buffer.MoveToPosition(line, column)
+
+	if mouse.Clicks == 2 {
+		app.selectWord()
+		return
+	}
+
+	app.dragging = true
+	app.Buffer.ScrollToCursor()
+}
+
+// dragBuffer selects from where the button went down to the character under the mouse
+func (app *App) dragBuffer(mouse Mouse) {
+	line, column := app.Buffer.PositionAt(mouse.X, mouse.Y)
+	if app.Mode != Mode_Visual {
+		if line == app.Buffer.Cursor.Line && column == app.Buffer.Cursor.Column {
+			return
+		}
+
+		app.startVisualMode()
+	}
+
+	app.Buffer.MoveToPosition(line, column)
+	app.Buffer.ScrollToCursor()
+}
+
+// selectWord selects the word under the cursor in visual mode. Words end at the same characters w stops at, a click on
+// one of those selects just it
+func (app *App) selectWord() {
+	text := app.Buffer.GetCurrentLineText()
+	line := app.Buffer.Cursor.Line
+	start := int(app.Buffer.Cursor.Column)
+	if start >= len(text) {
+		return
+	}
+
+	end := start
+	if !isPunctuation(text[start]) {
+		for start > 0 && !isPunctuation(text[start-1]) {
+			start -= 1
+		}
+
+		for end+1 < len(text) && !isPunctuation(text[end+1]) {
+			end += 1
+		}
+	}
+
+	if app.Mode == Mode_Insert {
+		app.startNormalMode()
+	}
+
+	app.Buffer.MoveToPosition(line, int32(start))
+	app.startVisualMode()
+	app.Buffer.MoveToPosition(line, int32(end))
+	app.Buffer.ScrollToCursor()
+}
+
+func (app *App) clickStatusBar(item StatusBarItem) {
+	switch item {
+	case StatusBarItem_Mode:
+		app.startNormalMode()
+	case StatusBarItem_File:
+		app.openFileSearch("")
+	case StatusBarItem_FileFormat:
+		app.openCommandPalette("lineending ")
+	}
+}
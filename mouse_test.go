@@ -0,0 +1,206 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+// cellPoint returns a pixel in the middle of the cell of the display row and column, with the view not scrolled
+func cellPoint(buffer *Buffer, row int32, column int32) (x int32, y int32) {
+	return buffer.GutterWidth + 5 + column*int32(buffer.Font.CharacterWidth) + int32(buffer.Font.CharacterWidth)/2, row*buffer.Cursor.Height + buffer.Cursor.Height/2
+}
+
+func click(app *App, x int32, y int32, clicks uint8) {
+	app.HandleMouse(Mouse{X: x, Y: y, Pressed: true, Held: true, Clicks: clicks})
+	app.HandleMouse(Mouse{X: x, Y: y, Released: true})
+}
+
+func TestPositionAt(t *testing.T) {
+	tests := []struct {
+		Name    string
+		Row     int32
+		Column  int32
+		ScrollY int32
+		Line    int32
+		Col     int32
+	}{
+		{"Start of the text", 0, 0, 0, 0, 0},
+		{"Inside a line", 1, 3, 0, 1, 3},
+		{"Past the end of a line", 0, 30, 0, 0, 5},
+		{"Below the text", 10, 2, 0, 2, 2},
+		{"On the gutter", 1, -4, 0, 1, 0},
+		{"Scrolled down", 0, 1, -1, 1, 1},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			app := createTestApp("first\nsecond\nthird")
+			app.Buffer.ScrollY = test.ScrollY * app.Buffer.Cursor.Height
+
+			x, y := cellPoint(&app.Buffer, test.Row, test.Column)
+			line, column := app.Buffer.PositionAt(x, y)
+			FailIfFalse(line == test.Line && column == test.Col, fmt.Sprintf("Expected %d:%d, got %d:%d", test.Line, test.Col, line, column), t)
+		})
+	}
+
+	t.Run("Scrolled sideways", func(t *testing.T) {
+		app := createTestApp(strings.Repeat("abcdefghij", 20))
+		typeKeys(&app, "$")
+
+		x, y := cellPoint(&app.Buffer, 0, 0)
+		_, column := app.Buffer.PositionAt(x, y)
+		FailIfFalse(column == app.Buffer.firstColumn(), fmt.Sprintf("Expected column %d, got %d", app.Buffer.firstColumn(), column), t)
+	})
+
+	t.Run("Wrapped line", func(t *testing.T) {
+		app := createTestApp("    one two three four")
+		app.Buffer.Wrap = true
+		app.Buffer.WrapColumn = 12
+
+		// The second row is "three " indented by 4
+		x, y := cellPoint(&app.Buffer, 1, 5)
+		line, column := app.Buffer.PositionAt(x, y)
+		FailIfFalse(line == 0 && column == 13, fmt.Sprintf("Expected 0:13, got %d:%d", line, column), t)
+
+		x, y = cellPoint(&app.Buffer, 1, 0)
+		_, column = app.Buffer.PositionAt(x, y)
+		FailIfFalse(column == 12, fmt.Sprintf("Clicks on the indentation should land on the start of the row, got %d", column), t)
+	})
+}
+
+func TestMouse(t *testing.T) {
+	t.Run("Click places the cursor", func(t *testing.T) {
+		app := createTestApp("first\nsecond\nthird")
+		typeKeys(&app, "v")
+
+		x, y := cellPoint(&app.Buffer, 2, 3)
+		click(&app, x, y, 1)
+		FailIfFalse(app.Buffer.Cursor.Line == 2 && app.Buffer.Cursor.Column == 3, fmt.Sprintf("Expected the cursor on 2:3, got %d:%d", app.Buffer.Cursor.Line, app.Buffer.Cursor.Column), t)
+		FailIfFalse(app.Mode == Mode_Normal, "A click should leave visual mode", t)
+		FailIfFalse(app.Damaged, "A click should draw a new frame", t)
+	})
+
+	t.Run("Click in insert mode keeps inserting", func(t *testing.T) {
+		app := createTestApp("first\nsecond")
+		typeKeys(&app, "i")
+
+		x, y := cellPoint(&app.Buffer, 1, 6)
+		click(&app, x, y, 1)
+		FailIfFalse(app.Mode == Mode_Insert, "Expected to stay in insert mode", t)
+		FailIfFalse(app.Buffer.Cursor.Column == 6, "Expected the cursor after the end of the line", t)
+	})
+
+	t.Run("Drag selects", func(t *testing.T) {
+		app := createTestApp("first\nsecond\nthird")
+
+		x, y := cellPoint(&app.Buffer, 0, 2)
+		app.HandleMouse(Mouse{X: x, Y: y, Pressed: true, Held: true, Clicks: 1})
+		app.HandleMouse(Mouse{X: x, Y: y, Moved: true, Held: true})
+		FailIfFalse(app.Mode == Mode_Normal, "Moving within the same character should not select", t)
+
+		x, y = cellPoint(&app.Buffer, 1, 2)
+		app.HandleMouse(Mouse{X: x, Y: y, Moved: true, Held: true})
+		app.HandleMouse(Mouse{X: x, Y: y, Released: true})
+		FailIfFalse(app.Mode == Mode_Visual, "Dragging should start visual mode", t)
+
+		text := app.Buffer.GetSelectionText()
+		FailIfFalse(text == "rst\nsec", fmt.Sprintf("Expected %q to be selected, got %q", "rst\nsec", text), t)
+
+		app.HandleMouse(Mouse{X: 0, Y: 0, Moved: true})
+		FailIfFalse(app.Buffer.Cursor.Line == 1, "Moving after the button came up should not select", t)
+	})
+
+	t.Run("Double click selects a word", func(t *testing.T) {
+		app := createTestApp("call(some_value, 2)")
+
+		x, y := cellPoint(&app.Buffer, 0, 7)
+		click(&app, x, y, 2)
+		FailIfFalse(app.Mode == Mode_Visual, "Expected visual mode", t)
+
+		text := app.Buffer.GetSelectionText()
+		FailIfFalse(text == "some_value", fmt.Sprintf("Expected %q to be selected, got %q", "some_value", text), t)
+	})
+
+	t.Run("Wheel scrolls", func(t *testing.T) {
+		app := createScrollTestApp()
+
+		app.HandleMouse(Mouse{WheelY: -2})
+		FailIfFalse(app.Buffer.firstRow() == 2*mouseWheelRows, fmt.Sprintf("Expected row %d, got %d", 2*mouseWheelRows, app.Buffer.firstRow()), t)
+
+		app.HandleMouse(Mouse{WheelY: 5})
+		FailIfFalse(app.Buffer.firstRow() == 0, "Expected the view back at the start", t)
+	})
+
+	t.Run("Mouse is ignored while the command palette is open", func(t *testing.T) {
+		app := createTestApp("first\nsecond")
+		app.CommandPaletteOpen = true
+
+		x, y := cellPoint(&app.Buffer, 1, 1)
+		click(&app, x, y, 1)
+		FailIfFalse(app.Buffer.Cursor.Line == 0, "The click should not reach the buffer", t)
+	})
+}
+
+func TestMouseFileSearch(t *testing.T) {
+	font := GetFakeFont()
+	fs := CreateFileSearch(18, &font, &font)
+	parent := sdl.Rect{W: 800, H: 600}
+
+	var picked *FileSearchEntry
+	closed := false
+	open := func() {
+		picked = nil
+		closed = false
+		fs.Open(PathsToFileSearchEntries([]string{"/a/one.go", "/a/two.go", "/a/three.go"}, "/a"), func(entry *FileSearchEntry) {
+			picked = entry
+			closed = true
+		})
+	}
+
+	t.Run("Click picks the entry", func(t *testing.T) {
+		open()
+
+		rect := fs.entryRect(fs.inputRect(&parent), 1)
+		fs.TickMouse(Mouse{X: rect.X + 10, Y: rect.Y + 2, Pressed: true, Held: true}, &parent)
+		FailNowIfFalse(picked != nil, "Expected an entry to be picked", t)
+		FailIfFalse(picked.Name == "two.go", fmt.Sprintf("Expected two.go, got %s", picked.Name), t)
+	})
+
+	t.Run("Click on the input does nothing", func(t *testing.T) {
+		open()
+
+		rect := fs.inputRect(&parent)
+		fs.TickMouse(Mouse{X: rect.X + 10, Y: rect.Y + 2, Pressed: true, Held: true}, &parent)
+		FailIfFalse(!closed, "Expected the search to stay open", t)
+	})
+
+	t.Run("Click outside closes", func(t *testing.T) {
+		open()
+
+		fs.TickMouse(Mouse{X: 2, Y: 2, Pressed: true, Held: true}, &parent)
+		FailIfFalse(closed && picked == nil, "Expected the search to close without picking", t)
+	})
+
+	t.Run("Wheel moves the selection", func(t *testing.T) {
+		open()
+
+		fs.TickMouse(Mouse{WheelY: -2}, &parent)
+		FailIfFalse(fs.SelectionIndex == 2, fmt.Sprintf("Expected entry 2 to be selected, got %d", fs.SelectionIndex), t)
+	})
+}
+
+func TestMouseStatusBar(t *testing.T) {
+	app := createRenderTestApp("text", 400, 200)
+	typeKeys(&app, "i")
+	app.Render(CreateSoftwareCanvas(400, 200))
+
+	rect := app.StatusBar.Items[StatusBarItem_Mode]
+	click(&app, rect.X+2, rect.Y+2, 1)
+	FailIfFalse(app.Mode == Mode_Normal, "Clicking the mode should go back to normal mode", t)
+
+	_, ok := app.StatusBar.ItemAt(sdl.Point{X: 200, Y: 2})
+	FailIfFalse(!ok, "Nothing above the status bar is an item", t)
+}
@@ -1,38 +1,323 @@
 package main
 
 import (
+	"fmt"
+	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 )
 
+const ProjectVersion = 2
+
 type Project struct {
-	Root  string
-	Name  string
-	Files []string // Paths
+	Path    string // Location of the .aproject file
+	Version int
+	Root    string
+	Name    string
+	Files   []string // Paths
+	Include []string // Globs, when empty every file is included
+	Exclude []string // Globs
+
+	UseIgnoreFiles bool // Honor .gitignore and .ignore files
+	Ignore         *IgnoreMatcher
+	Index          *Indexer          // Builds Files in the background
+	Skipped        map[string]string // Path -> why it was left out of Files, for debugging
+
+	IndentWidth int32
+	Formatter   string            // Command that is run on a file after it is saved, the path of the file is added as the last argument
+	Tasks       map[string]string // Name -> command, run in the root directory
+	Settings    map[string]string // Name -> value, override the user settings while the project is open
 }
 
-func ParseProject(data string) (result Project) {
-	split := strings.Split(data, "\n")
-	exclude := make([]string, 0)
+// Project files consist of "key: value" lines. Lines starting with # are comments.
+//
+//	version: 2
+//	root: .                   relative to the project file
+//	include: src/**/*.go      globs, can be repeated or separated with commas
+//	exclude: .git, vendor
+//	ignorefiles: on           honor .gitignore and .ignore files
+//	indent: 4
+//	formatter: gofmt -w
+//	task build: go build .
+//	set wrap: on              any option of the settings file
+func ParseProject(data string, path string) (result Project, errors []ParseError) {
+	result.Path = path
+	result.Version = 1 // Project files written before versioning did not have the version key
+	result.IndentWidth = 4
+	result.UseIgnoreFiles = true
+	result.Tasks = map[string]string{}
+	result.Settings = map[string]string{}
+	result.Include = make([]string, 0)
+	result.Exclude = make([]string, 0)
 
-	for _, line := range split {
-		key, value := getKeyValue(line, ": ")
+	root := ""
+
+	for index, line := range strings.Split(data, "\n") {
+		lineNumber := index + 1
+
+		l := strings.TrimSpace(line)
+		if l == "" || strings.HasPrefix(l, "#") {
+			continue
+		}
 
-		if key == "root" {
-			result.Root = value
-			result.Name = filepath.Base(value)
-		} else if key == "exclude" {
-			exclude = append(exclude, strings.Split(value, ",")...)
+		key, value, ok := getKeyValue(l, ":")
+		if !ok {
+			errors = append(errors, ParseError{Line: lineNumber, Message: fmt.Sprintf("expected \"key: value\", got \"%s\"", l)})
+			continue
+		}
+
+		if strings.HasPrefix(key, "task ") {
+			name := strings.TrimSpace(strings.TrimPrefix(key, "task "))
+			if name == "" || value == "" {
+				errors = append(errors, ParseError{Line: lineNumber, Message: "task needs a name and a command"})
+				continue
+			}
+
+			result.Tasks[name] = value
+			continue
+		}
+
+		if strings.HasPrefix(key, "set ") {
+			name := strings.TrimSpace(strings.TrimPrefix(key, "set "))
+			settings := DefaultSettings()
+			err := settings.SetOverride(name, value)
+			if err != nil {
+				errors = append(errors, ParseError{Line: lineNumber, Message: err.Error()})
+				continue
+			}
+
+			result.Settings[name] = value
+			continue
+		}
+
+		switch key {
+		case "version":
+			version, err := strconv.Atoi(value)
+			if err != nil || version < 1 {
+				errors = append(errors, ParseError{Line: lineNumber, Message: fmt.Sprintf("invalid version \"%s\"", value)})
+			} else if version > ProjectVersion {
+				errors = append(errors, ParseError{Line: lineNumber, Message: fmt.Sprintf("version %d is newer than the supported version %d", version, ProjectVersion)})
+			} else {
+				result.Version = version
+			}
+		case "root":
+			root = value
+		case "include", "exclude":
+			for _, glob := range strings.Split(value, ",") {
+				glob = strings.TrimSpace(glob)
+				if glob == "" {
+					continue
+				}
+
+				if !ValidateGlob(glob) {
+					errors = append(errors, ParseError{Line: lineNumber, Message: fmt.Sprintf("invalid pattern \"%s\"", glob)})
+					continue
+				}
+
+				if key == "include" {
+					result.Include = append(result.Include, glob)
+				} else {
+					result.Exclude = append(result.Exclude, glob)
+				}
+			}
+		case "indent":
+			width, err := strconv.Atoi(value)
+			if err != nil || width < 1 || width > 16 {
+				errors = append(errors, ParseError{Line: lineNumber, Message: fmt.Sprintf("indent must be a number between 1 and 16, got \"%s\"", value)})
+			} else {
+				result.IndentWidth = int32(width)
+				result.Settings["indent"] = value
+			}
+		case "ignorefiles":
+			use, ok := parseOnOff([]string{value})
+			if !ok {
+				errors = append(errors, ParseError{Line: lineNumber, Message: fmt.Sprintf("ignorefiles must be on or off, got \"%s\"", value)})
+			} else {
+				result.UseIgnoreFiles = use
+			}
+		case "formatter":
+			result.Formatter = value
+		default:
+			errors = append(errors, ParseError{Line: lineNumber, Message: fmt.Sprintf("unknown key \"%s\"", key)})
 		}
 	}
 
-	result.Files = ReadDirectory(result.Root, exclude)
+	// Relative roots let the project file be committed together with the code
+	projectDir := filepath.Dir(path)
+	if root == "" {
+		root = projectDir
+	} else if !filepath.IsAbs(root) {
+		root = filepath.Join(projectDir, root)
+	}
+
+	result.Root = filepath.Clean(root)
+	result.Name = filepath.Base(result.Root)
+
+	result.Refresh()
 	return
 }
 
-func getKeyValue(line string, separator string) (key string, value string) {
-	split := strings.Split(line, separator)
-	key = split[0]
-	value = split[1]
+// Refresh starts indexing the files from scratch. Files fills up as the index is built, see Sync
+func (project *Project) Refresh() {
+	project.Close()
+
+	// Ignore files may have changed since the last refresh, so the rules are read again
+	project.Ignore = CreateIgnoreMatcher(project.Root)
+	project.Skipped = map[string]string{}
+	project.Files = nil
+
+	project.Index = CreateIndexer(project.Root, project.snapshot().fileExcludeReason)
+}
+
+// ExcludeFilter returns IsExcluded for the project as it is now, for the file watcher
+func (project *Project) ExcludeFilter() func(path string) bool {
+	return project.snapshot().IsExcluded
+}
+
+// snapshot copies the project for work done on other goroutines, so the project can be changed or replaced meanwhile
+func (project *Project) snapshot() *Project {
+	result := *project
+	result.Index = nil
+	result.Files = nil
+	result.Skipped = nil
+
+	return &result
+}
+
+// Sync copies the files indexed so far into Files and returns true if they changed
+func (project *Project) Sync() bool {
+	if project.Index == nil {
+		return false
+	}
+
+	files, skipped, changed, _ := project.Index.Snapshot()
+	if !changed {
+		return false
+	}
+
+	project.Files = files
+	project.Skipped = skipped
+	return true
+}
+
+// IsIndexed reports whether the initial walk of the project is done
+func (project *Project) IsIndexed() bool {
+	if project.Index == nil {
+		return true
+	}
+
+	return project.Index.Done()
+}
+
+// WaitForIndex blocks until the initial walk of the project is done and syncs the files
+func (project *Project) WaitForIndex() {
+	if project.Index == nil {
+		return
+	}
+
+	project.Index.Wait()
+	project.Sync()
+}
+
+func (project *Project) Close() {
+	if project.Index != nil {
+		project.Index.Close()
+		project.Index = nil
+	}
+}
+
+// IsExcluded reports whether the path matches one of the exclude globs or is ignored by an ignore file
+func (project *Project) IsExcluded(path string) bool {
+	info, err := os.Lstat(path)
+	isDir := err == nil && info.IsDir()
+
+	return project.ExcludeReason(path, isDir) != ""
+}
+
+// ExcludeReason returns why the path is excluded from the project or an empty string if it is not
+func (project *Project) ExcludeReason(path string, isDir bool) string {
+	relPath, ok := project.relativePath(path)
+	if !ok {
+		return ""
+	}
+
+	for _, glob := range project.Exclude {
+		if MatchGlob(glob, relPath) {
+			return fmt.Sprintf("project exclude \"%s\"", glob)
+		}
+	}
+
+	if project.UseIgnoreFiles && project.Ignore != nil {
+		ignored, rule := project.Ignore.Match(relPath, isDir)
+		if ignored {
+			return fmt.Sprintf("%s \"%s\"", rule.Source, rule.Pattern)
+		}
+	}
+
+	return ""
+}
+
+func (project *Project) IsIncluded(path string) bool {
+	if len(project.Include) == 0 {
+		return true
+	}
+
+	relPath, ok := project.relativePath(path)
+	if !ok {
+		return false
+	}
+
+	for _, glob := range project.Include {
+		if MatchGlob(glob, relPath) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (project *Project) fileExcludeReason(path string, isDir bool) string {
+	reason := project.ExcludeReason(path, isDir)
+
+	// Include globs are only checked on files, a directory has to be entered to find the files that match
+	if reason == "" && !isDir && !project.IsIncluded(path) {
+		reason = "not matched by any include glob"
+	}
+
+	return reason
+}
+
+// Contains reports whether the path is under the root of the project
+func (project *Project) Contains(path string) bool {
+	_, ok := project.relativePath(path)
+	return ok
+}
+
+func (project *Project) relativePath(path string) (string, bool) {
+	relPath, err := filepath.Rel(project.Root, path)
+	if err != nil || relPath == "." || isOutsideRoot(relPath) {
+		return "", false
+	}
+
+	return filepath.ToSlash(relPath), true
+}
+
+// isOutsideRoot reports whether a path made relative with filepath.Rel climbs out of the root. Names that only start
+// with dots, like ..config, are still inside
+func isOutsideRoot(relPath string) bool {
+	return relPath == ".." || strings.HasPrefix(relPath, ".."+string(filepath.Separator))
+}
+
+// getKeyValue splits the line at the first separator, ok is false if there is no separator
+func getKeyValue(line string, separator string) (key string, value string, ok bool) {
+	split := strings.SplitN(line, separator, 2)
+	if len(split) != 2 {
+		return line, "", false
+	}
+
+	key = strings.TrimSpace(split[0])
+	value = strings.TrimSpace(split[1])
+	ok = true
 	return
 }
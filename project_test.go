@@ -0,0 +1,180 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+// createFixture creates the files (and their directories) under a new temporary directory
+func createFixture(t *testing.T, files map[string]string) string {
+	root := t.TempDir()
+	for name, contents := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		os.MkdirAll(filepath.Dir(path), 0755)
+		ioutil.WriteFile(path, []byte(contents), 0644)
+	}
+
+	return root
+}
+
+func relativeFiles(root string, files []string) (result []string) {
+	for _, file := range files {
+		rel, _ := filepath.Rel(root, file)
+		result = append(result, filepath.ToSlash(rel))
+	}
+	sort.Strings(result)
+
+	return
+}
+
+func TestMatchGlob(t *testing.T) {
+	cases := []struct {
+		Pattern  string
+		Path     string
+		Expected bool
+	}{
+		{".git", ".git", true},
+		{".git", ".git/config", true},
+		{".git", ".gitignore", false},
+		{".git", "sub/.git/HEAD", true},
+		{"*.go", "main.go", true},
+		{"*.go", "cmd/tool/main.go", true},
+		{"*.go", "main.gob", false},
+		{"vendor/", "vendor/lib/a.go", true},
+		{"/build", "build/out.bin", true},
+		{"/build", "src/build/out.bin", false},
+		{"src/*.go", "src/main.go", true},
+		{"src/*.go", "src/sub/main.go", false},
+		{"src/**/*.go", "src/main.go", true},
+		{"src/**/*.go", "src/a/b/main.go", true},
+		{"src/**/*.go", "lib/main.go", false},
+	}
+
+	for _, c := range cases {
+		FailIfFalse(MatchGlob(c.Pattern, c.Path) == c.Expected, fmt.Sprintf("Expected MatchGlob(%q, %q) to be %v", c.Pattern, c.Path, c.Expected), t)
+	}
+}
+
+func TestProjectContains(t *testing.T) {
+	project := Project{Root: filepath.Join("/", "proj")}
+	FailIfFalse(project.Contains(filepath.Join("/", "proj", "src", "main.go")), "Expected files under the root", t)
+	FailIfFalse(!project.Contains(filepath.Join("/", "proj2", "main.go")), "Expected sibling directories to be outside", t)
+
+	project.Root = ""
+	FailIfFalse(!project.Contains(filepath.Join("/", "proj", "main.go")), "Expected nothing in a project without a root", t)
+}
+
+func TestProjectRelativePath(t *testing.T) {
+	project := Project{Root: filepath.Join("/", "proj")}
+
+	relPath, ok := project.relativePath(filepath.Join("/", "proj", "..config", "a.txt"))
+	FailIfFalse(ok && relPath == "..config/a.txt", "Expected names starting with dots to be inside", t)
+	_, ok = project.relativePath(filepath.Join("/", "a.txt"))
+	FailIfFalse(!ok, "Expected the parent directory to be outside", t)
+	_, ok = project.relativePath("/")
+	FailIfFalse(!ok, "Expected the root of the file system to be outside", t)
+}
+
+func TestParseProject(t *testing.T) {
+	t.Run("Relative root with globs and settings", func(t *testing.T) {
+		root := createFixture(t, map[string]string{
+			"main.go":       "",
+			".gitignore":    "",
+			".git/config":   "",
+			"docs/guide.md": "",
+			"lib/util.go":   "",
+			"lib/util.txt":  "",
+		})
+
+		data := "version: 2\nroot: .\ninclude: *.go, .gitignore\nexclude: .git\nindent: 2\nformatter: gofmt -w\ntask build: go build .\n\n"
+		project, errors := ParseProject(data, filepath.Join(root, "test.aproject"))
+		defer project.Close()
+		project.WaitForIndex()
+
+		FailIfFalse(len(errors) == 0, fmt.Sprintf("Expected no errors, got %v", errors), t)
+		FailIfFalse(project.Root == root, "Relative root was not resolved against the project file", t)
+		FailIfFalse(project.Version == 2, "Incorrect project version", t)
+		FailIfFalse(project.IndentWidth == 2, "Incorrect indent width", t)
+		FailIfFalse(project.Formatter == "gofmt -w", "Incorrect formatter", t)
+		FailIfFalse(project.Tasks["build"] == "go build .", "Incorrect task", t)
+
+		files := relativeFiles(root, project.Files)
+		expected := []string{".gitignore", "lib/util.go", "main.go"}
+		FailIfFalse(linesEqual(files, expected), fmt.Sprintf("Expected files %v, got %v", expected, files), t)
+	})
+
+	t.Run("Old project format", func(t *testing.T) {
+		root := createFixture(t, map[string]string{
+			"main.go":            "",
+			"assets/icon.png":    "",
+			".vscode/tasks.json": "",
+		})
+
+		project, errors := ParseProject(fmt.Sprintf("root: %s\nexclude: .vscode,assets", root), filepath.Join(root, "old.aproject"))
+		defer project.Close()
+		project.WaitForIndex()
+
+		FailIfFalse(len(errors) == 0, fmt.Sprintf("Expected no errors, got %v", errors), t)
+		FailIfFalse(project.Version == 1, "Project without a version should be version 1", t)
+		files := relativeFiles(root, project.Files)
+		FailIfFalse(linesEqual(files, []string{"main.go"}), fmt.Sprintf("Expected only main.go, got %v", files), t)
+	})
+
+	t.Run("Errors are reported with line numbers", func(t *testing.T) {
+		root := createFixture(t, map[string]string{"main.go": ""})
+
+		data := "version: 3\n# comment\nno separator\nindent: wide\ncolour: red\nexclude: [abc\n"
+		project, errors := ParseProject(data, filepath.Join(root, "broken.aproject"))
+		defer project.Close()
+		project.WaitForIndex()
+
+		FailNowIfFalse(len(errors) == 5, fmt.Sprintf("Expected 5 errors, got %v", errors), t)
+		lines := []int{1, 3, 4, 5, 6}
+		for index, err := range errors {
+			FailIfFalse(err.Line == lines[index], fmt.Sprintf("Expected error on line %d, got %s", lines[index], err.Error()), t)
+		}
+
+		FailIfFalse(project.Root == root, "Project root should default to the directory of the project file", t)
+	})
+}
+
+func TestTasks(t *testing.T) {
+	waitForResult := func(app *App, t *testing.T) CommandResult {
+		select {
+		case result := <-app.CommandResults:
+			return result
+		case <-time.After(10 * time.Second):
+			t.Fatal("The task did not finish")
+		}
+
+		return CommandResult{}
+	}
+
+	t.Run("Tasks run in the background", func(t *testing.T) {
+		app := App{}
+		app.Project.Tasks = map[string]string{"version": "go version"}
+
+		commandTask(&app, []string{"version"})
+		result := waitForResult(&app, t)
+		FailIfFalse(result.Err == nil && strings.HasPrefix(result.Output, "go version"), fmt.Sprintf("Expected the output of the task, got %q %v", result.Output, result.Err), t)
+
+		app.reportCommandResult(result)
+		FailIfFalse(!app.PromptOpen, "A task that succeeds should not open a prompt", t)
+	})
+
+	t.Run("Failed tasks are reported", func(t *testing.T) {
+		app := App{}
+		app.Project.Tasks = map[string]string{"broken": "go nothing"}
+
+		commandTask(&app, []string{"broken"})
+		app.reportCommandResult(waitForResult(&app, t))
+		FailIfFalse(app.PromptOpen && strings.HasPrefix(app.Prompt.Message, "Task broken failed"), fmt.Sprintf("Expected the failure in a prompt, got %q", app.Prompt.Message), t)
+		FailIfFalse(len(app.Prompt.Preview) > 0, "Expected the output of the task in the prompt", t)
+	})
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+type PromptChoice struct {
+	Key   byte
+	Label string
+}
+
+// Prompt asks a question and waits for one of the choices to be picked with a single key
+type Prompt struct {
+	Message string
+	Choices []PromptChoice
+	Preview []DiffOp // Optional diff shown below the question
+
+	Width      int32
+	LineHeight int32
+	Font       *Font
+
+	CloseCallback func(byte)
+}
+
+func CreatePrompt(lineHeight int32, font *Font) (result Prompt) {
+	result.Width = popupWidth
+	result.SetFont(lineHeight, font)
+
+	return
+}
+
+func (prompt *Prompt) SetFont(lineHeight int32, font *Font) {
+	prompt.LineHeight = lineHeight
+	prompt.Font = font
+}
+
+func (prompt *Prompt) Open(message string, choices []PromptChoice, onClose func(byte)) {
+	prompt.Message = message
+	prompt.Choices = choices
+	prompt.Preview = nil
+	prompt.CloseCallback = onClose
+}
+
+func (prompt *Prompt) Close() {
+	prompt.CloseCallback(0)
+}
+
+func (prompt *Prompt) Tick(input Input) {
+	if input.Escape {
+		prompt.Close()
+		return
+	}
+
+	for _, choice := range prompt.Choices {
+		if input.TypedCharacter == choice.Key {
+			prompt.CloseCallback(choice.Key)
+			return
+		}
+	}
+}
+
+func (prompt *Prompt) Render(renderer Canvas, parentRect *sdl.Rect, theme *Theme) {
+	var sb strings.Builder
+	for index, choice := range prompt.Choices {
+		if index > 0 {
+			sb.WriteString("  ")
+		}
+		sb.WriteString(fmt.Sprintf("[%c] %s", choice.Key, choice.Label))
+	}
+	choices := sb.String()
+
+	width := int32(Max(int(prompt.Width), int(prompt.Font.GetStringWidth(prompt.Message)+20)))
+	for _, op := range prompt.Preview {
+		width = int32(Max(int(width), int(prompt.Font.GetStringWidth(op.Line)+40)))
+	}
+	width = int32(Min(int(width), int(parentRect.W-20)))
+
+	rect := sdl.Rect{
+		X: parentRect.W/2 - width/2,
+		Y: parentRect.Y + int32(float32(parentRect.H)*0.15),
+		W: width,
+		H: prompt.LineHeight*int32(2+len(prompt.Preview)) + 20,
+	}
+	if len(prompt.Preview) > 0 {
+		rect.H += 10
+	}
+
+	borderRect := expandRect(rect, 1)
+
+	DrawRect(renderer, &borderRect, theme.FileSearch.BorderColor)
+	DrawRect(renderer, &rect, theme.FileSearch.InputBackgroundColor)
+
+	messageRect := sdl.Rect{
+		X: rect.X + 10,
+		Y: rect.Y + 5 + (prompt.LineHeight-int32(prompt.Font.Size))/2,
+		W: prompt.Font.GetStringWidth(prompt.Message),
+		H: int32(prompt.Font.Size),
+	}
+	DrawText(renderer, prompt.Font, prompt.Message, &messageRect, theme.FileSearch.InputTextColor)
+
+	y := messageRect.Y + prompt.LineHeight
+	if len(prompt.Preview) > 0 {
+		y += 5
+	}
+
+	for _, op := range prompt.Preview {
+		prefix := "  "
+		color := theme.Diff.ContextColor
+		if op.Type == DiffOp_Insert {
+			prefix = "+ "
+			color = theme.Diff.InsertColor
+		} else if op.Type == DiffOp_Delete {
+			prefix = "- "
+			color = theme.Diff.DeleteColor
+		}
+
+		line := prefix + op.Line
+		lineRect := sdl.Rect{
+			X: rect.X + 10,
+			Y: y,
+			W: prompt.Font.GetStringWidth(line),
+			H: int32(prompt.Font.Size),
+		}
+		DrawText(renderer, prompt.Font, line, &lineRect, color)
+
+		y += prompt.LineHeight
+	}
+
+	choicesRect := sdl.Rect{
+		X: rect.X + 10,
+		Y: y + 10,
+		W: prompt.Font.GetStringWidth(choices),
+		H: int32(prompt.Font.Size),
+	}
+	DrawText(renderer, prompt.Font, choices, &choicesRect, theme.FileSearch.ResultNameActiveColor)
+}
@@ -4,24 +4,60 @@ import (
 	"github.com/veandco/go-sdl2/sdl"
 )
 
-func DrawText(renderer *sdl.Renderer, font *Font, text string, rect *sdl.Rect, color sdl.Color) {
-	surface, err := font.Data.RenderUTF8Blended(text, color)
-	checkError(err)
-	defer surface.Free()
+// Canvas is what the editor draws on. SDLCanvas draws to the window and SoftwareCanvas draws into an image, so the
+// widgets can be drawn and checked without a display
+type Canvas interface {
+	Clear(color sdl.Color)
+	FillRect(rect *sdl.Rect, color sdl.Color)
+	DrawText(font *Font, text string, rect *sdl.Rect, color sdl.Color)
+	DrawImage(image *Image, rect sdl.Rect, color sdl.Color)
+	Present()
+}
+
+type SDLCanvas struct {
+	Renderer *sdl.Renderer
+}
+
+func CreateSDLCanvas(renderer *sdl.Renderer) (result *SDLCanvas) {
+	// Theme colors can be see-through
+	renderer.SetDrawBlendMode(sdl.BLENDMODE_BLEND)
 
-	texture, err := renderer.CreateTextureFromSurface(surface)
-	checkError(err)
-	defer texture.Destroy()
+	return &SDLCanvas{Renderer: renderer}
+}
+
+func (canvas *SDLCanvas) Clear(color sdl.Color) {
+	canvas.Renderer.SetDrawColor(color.R, color.G, color.B, color.A)
+	canvas.Renderer.Clear()
+}
 
-	renderer.Copy(texture, nil, rect)
+func (canvas *SDLCanvas) FillRect(rect *sdl.Rect, color sdl.Color) {
+	canvas.Renderer.SetDrawColor(color.R, color.G, color.B, color.A)
+	canvas.Renderer.FillRect(rect)
 }
 
-func DrawRect(renderer *sdl.Renderer, rect *sdl.Rect, color sdl.Color) {
-	renderer.SetDrawColor(color.R, color.G, color.B, color.A)
-	renderer.FillRect(rect)
+func (canvas *SDLCanvas) DrawText(font *Font, text string, rect *sdl.Rect, color sdl.Color) {
+	font.Atlas(canvas.Renderer).Draw(font, text, rect, color)
 }
 
-func DrawImage(renderer *sdl.Renderer, texture *sdl.Texture, rect sdl.Rect, color sdl.Color) {
+func (canvas *SDLCanvas) DrawImage(image *Image, rect sdl.Rect, color sdl.Color) {
+	texture := image.Texture(canvas.Renderer)
 	texture.SetColorMod(color.R, color.G, color.B)
-	renderer.Copy(texture, nil, &rect)
+	texture.SetAlphaMod(color.A)
+	canvas.Renderer.Copy(texture, nil, &rect)
+}
+
+func (canvas *SDLCanvas) Present() {
+	canvas.Renderer.Present()
+}
+
+func DrawText(renderer Canvas, font *Font, text string, rect *sdl.Rect, color sdl.Color) {
+	renderer.DrawText(font, text, rect, color)
+}
+
+func DrawRect(renderer Canvas, rect *sdl.Rect, color sdl.Color) {
+	renderer.FillRect(rect, color)
+}
+
+func DrawImage(renderer Canvas, image *Image, rect sdl.Rect, color sdl.Color) {
+	renderer.DrawImage(image, rect, color)
 }
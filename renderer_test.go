@@ -0,0 +1,67 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/veandco/go-sdl2/sdl"
+	"github.com/veandco/go-sdl2/ttf"
+)
+
+func TestDamageTracking(t *testing.T) {
+	app := createTestApp("one\ntwo")
+	app.Watcher = CreateFileWatcher()
+	defer app.Watcher.Close()
+
+	app.Tick(Input{TypedCharacter: 'j'})
+	FailIfFalse(app.Damaged, "A key should damage the frame", t)
+
+	app.Damaged = false
+	app.Tick(Input{})
+	FailIfFalse(!app.Damaged, "An idle frame should not be drawn", t)
+
+	app.Tick(Input{Ctrl: true})
+	FailIfFalse(app.Damaged, "Pressing a modifier should damage the frame", t)
+
+	app.Damaged = false
+	app.Tick(Input{Ctrl: true})
+	FailIfFalse(!app.Damaged, "Holding a modifier should not damage the frame", t)
+
+	app.Resized(640, 480)
+	FailIfFalse(app.Damaged, "Resizing should damage the frame", t)
+}
+
+// BenchmarkBufferRender measures a frame of a 10k line Go file, drawn with the software renderer
+func BenchmarkBufferRender(b *testing.B) {
+	err := ttf.Init()
+	if err != nil {
+		b.Skip("SDL_ttf is not available")
+	}
+	defer ttf.Quit()
+
+	surface, err := sdl.CreateRGBSurfaceWithFormat(0, 1280, 720, 32, sdl.PIXELFORMAT_RGBA32)
+	if err != nil {
+		b.Skip("Could not create a surface to draw to")
+	}
+	defer surface.Free()
+
+	renderer, err := sdl.CreateSoftwareRenderer(surface)
+	if err != nil {
+		b.Skip("Could not create a software renderer")
+	}
+	defer renderer.Destroy()
+
+	font := LoadFont("./assets/fonts/consola.ttf", 14)
+	defer font.Unload()
+	theme, _ := ParseTheme("./themes/default.atheme")
+
+	buffer := CreateBuffer(18, &font, sdl.Rect{W: 1280, H: 720})
+	buffer.SetData(generateGoSource(1250), "bench.go")
+	buffer.MoveToLine(5000)
+
+	canvas := CreateSDLCanvas(renderer)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i += 1 {
+		buffer.Render(canvas, Mode_Normal, &theme)
+	}
+}
@@ -0,0 +1,76 @@
+package main
+
+// A change is every key from the start of a normal mode command until the editor is back in normal mode with nothing pending,
+// as long as the text was edited on the way. That covers operators with their motions, x and the like, and whole insert sessions
+
+// handleBufferInput passes the key on to the current mode and remembers the keys of the last change for the . command
+func (app *App) handleBufferInput(input Input) {
+	// Keys of a change that is being repeated, or of a macro played by one, are part of the outer command
+	if app.changeDepth > 0 {
+		app.handleModeInput(input)
+		return
+	}
+
+	if len(app.pendingChange) == 0 {
+		app.pendingEdits = app.Buffer.EditCount
+	}
+
+	if input.IsKey() {
+		app.pendingChange = append(app.pendingChange, input)
+	}
+
+	app.changeDepth += 1
+	app.handleModeInput(input)
+	app.changeDepth -= 1
+
+	if app.Mode != Mode_Normal || app.Submode != Submode_None || app.AmountModifier.Len() > 0 {
+		return
+	}
+
+	keys := app.pendingChange
+	app.pendingChange = nil
+
+	if app.Buffer.EditCount != app.pendingEdits && !isRepeatCommand(keys) {
+		app.LastChange = keys
+	}
+}
+
+// repeatChange plays the last change again. A count replaces the count the change was made with
+func (app *App) repeatChange() {
+	count := app.AmountModifier.String()
+	app.AmountModifier.Reset()
+
+	if len(app.LastChange) == 0 {
+		return
+	}
+
+	if count != "" {
+		keys := []Input{}
+		for index := 0; index < len(count); index += 1 {
+			keys = append(keys, Input{TypedCharacter: count[index]})
+		}
+		app.LastChange = append(keys, withoutCount(app.LastChange)...)
+	}
+
+	for _, input := range app.LastChange {
+		app.handleModeInput(input)
+	}
+}
+
+func withoutCount(keys []Input) []Input {
+	index := 0
+	for index < len(keys) && keys[index].TypedCharacter >= '0' && keys[index].TypedCharacter <= '9' && !keys[index].Ctrl && !keys[index].Alt {
+		// A leading 0 is a motion, not a count
+		if index == 0 && keys[index].TypedCharacter == '0' {
+			break
+		}
+		index += 1
+	}
+
+	return keys[index:]
+}
+
+func isRepeatCommand(keys []Input) bool {
+	keys = withoutCount(keys)
+	return len(keys) == 1 && keys[0].TypedCharacter == '.' && !keys[0].Ctrl && !keys[0].Alt
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+)
+
+func expectLines(app *App, expected []string, t *testing.T) {
+	text := app.Buffer.GetText()
+	FailNowIfFalse(len(text) == len(expected), fmt.Sprintf("Expected %d lines, got %d: %q", len(expected), len(text), text), t)
+	for index, line := range text {
+		FailIfFalse(line == expected[index], fmt.Sprintf("Expected line %d to be %q, got %q", index, expected[index], line), t)
+	}
+}
+
+func TestRepeatChange(t *testing.T) {
+	t.Run("Single key changes", func(t *testing.T) {
+		app := createTestApp("abcdef")
+
+		typeKeys(&app, "x..")
+		expectLines(&app, []string{"def"}, t)
+	})
+
+	t.Run("Operators with a new count", func(t *testing.T) {
+		app := createTestApp("1\n2\n3\n4\n5\n6")
+
+		typeKeys(&app, "dd")
+		typeKeys(&app, "2.")
+		expectLines(&app, []string{"4", "5", "6"}, t)
+
+		typeKeys(&app, ".")
+		expectLines(&app, []string{"6"}, t)
+	})
+
+	t.Run("Insert sessions", func(t *testing.T) {
+		app := createTestApp("one\ntwo\nthree")
+
+		typeKeys(&app, "A;<Esc>j.j.")
+		expectLines(&app, []string{"one;", "two;", "three;"}, t)
+
+		typeKeys(&app, "ccfour<Esc>k.")
+		expectLines(&app, []string{"one;", "four", "four"}, t)
+	})
+
+	t.Run("Motions are not changes", func(t *testing.T) {
+		app := createTestApp("    a\nb\nc")
+
+		typeKeys(&app, "j>")
+		typeKeys(&app, "jll.")
+		expectLines(&app, []string{"    a", "    b", "    c"}, t)
+		FailIfFalse(len(app.LastChange) == 1 && app.LastChange[0].TypedCharacter == '>', "Expected > to be the last change", t)
+	})
+}
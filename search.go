@@ -1,15 +1,12 @@
 package main
 
 import (
-	"strings"
-
 	"github.com/veandco/go-sdl2/sdl"
 )
 
 type Search struct {
-	Input strings.Builder
+	Input TextInput
 
-	Cursor      InputCursor
 	Width       int32
 	LineHeight  int32
 	LineSpacing int32
@@ -19,17 +16,21 @@ type Search struct {
 }
 
 func CreateSearch(lineHeight int32, font *Font) (result Search) {
-	result.Cursor = CreateInputCursor(lineHeight, int32(font.CharacterWidth))
-	result.Width = 500
-	result.LineHeight = lineHeight
-	result.LineSpacing = (lineHeight - int32(font.Size)) / 2
-	result.Font = font
+	result.Input = CreateTextInput(lineHeight, int32(font.CharacterWidth))
+	result.Width = popupWidth
+	result.SetFont(lineHeight, font)
 
 	return
 }
 
+func (search *Search) SetFont(lineHeight int32, font *Font) {
+	search.Input.SetFont(lineHeight, int32(font.CharacterWidth))
+	search.LineHeight = lineHeight
+	search.LineSpacing = (lineHeight - int32(font.Size)) / 2
+	search.Font = font
+}
+
 func (search *Search) Open(closeCallback func(string)) {
-	search.Cursor.Column = 0
 	search.Input.Reset()
 
 	search.CloseCallback = closeCallback
@@ -40,41 +41,15 @@ func (search *Search) Close() {
 }
 
 func (search *Search) Tick(input Input) {
-	if input.Escape {
+	if input.Escape || input.TypedCharacter == '\n' {
 		search.Close()
 		return
 	}
 
-	if input.Backspace {
-		if input.Ctrl {
-			search.Cursor.Column = 0
-			search.Input.Reset()
-
-		} else if search.Cursor.Column > 0 {
-			search.Cursor.Column -= 1
-			str := search.Input.String()
-			search.Input.Reset()
-
-			str = str[:len(str)-1]
-			search.Input.WriteString(str)
-		}
-
-		return
-	}
-
-	if input.TypedCharacter != 0 {
-		if input.TypedCharacter == '\n' {
-			search.Close()
-		} else {
-			search.Input.WriteByte(input.TypedCharacter)
-			search.Cursor.Column += 1
-		}
-
-		return
-	}
+	search.Input.Tick(input)
 }
 
-func (search *Search) Render(renderer *sdl.Renderer, parentRect *sdl.Rect, theme *FileSearchTheme) {
+func (search *Search) Render(renderer Canvas, parentRect *sdl.Rect, theme *FileSearchTheme) {
 	inputRect := sdl.Rect{
 		X: parentRect.W - search.Width - 20,
 		Y: parentRect.Y + parentRect.H - search.LineHeight - 20,
@@ -95,22 +70,10 @@ func (search *Search) Render(renderer *sdl.Renderer, parentRect *sdl.Rect, theme
 	DrawRect(renderer, &borderRect, theme.BorderColor)
 	DrawRect(renderer, &inputRect, theme.InputBackgroundColor)
 	DrawText(renderer, search.Font, "Find:", &findRect, theme.ResultNameActiveColor)
-	search.Cursor.Render(renderer, sdl.Rect{
+	search.Input.Render(renderer, search.Font, sdl.Rect{
 		X: inputRect.X + 15 + findRect.W,
 		Y: inputRect.Y,
 		W: inputRect.W - findRect.W - 20,
 		H: inputRect.H,
-	}, theme.CursorColor)
-
-	query := search.Input.String()
-	if len(query) > 0 {
-		width := search.Font.GetStringWidth(query)
-		queryRect := sdl.Rect{
-			X: inputRect.X + 20 + findRect.W,
-			Y: inputRect.Y + (inputRect.H-int32(search.Font.Size))/2,
-			W: width,
-			H: int32(search.Font.Size),
-		}
-		DrawText(renderer, search.Font, query, &queryRect, theme.InputTextColor)
-	}
+	}, theme.InputTextColor, theme.InputSelectionColor, theme.CursorColor)
 }
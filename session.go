@@ -0,0 +1,176 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type SessionBuffer struct {
+	Filepath string
+	Line     int32
+	Column   int32
+	ScrollY  int32
+	ScrollX  int32
+}
+
+// Session is the state of the editor for one project, restored when the project is opened again
+type Session struct {
+	Path          string
+	Buffers       []SessionBuffer
+	SearchHistory []string
+	RecentFiles   []string
+	Commands      []string // Command palette history
+	Registers     map[byte]string
+	Marks         []NamedMark
+}
+
+func LoadSession(dir string, projectRoot string) (result Session) {
+	result.Path = filepath.Join(dir, pathToFileName(projectRoot)+".asession")
+	result.Registers = map[byte]string{}
+
+	data, err := ioutil.ReadFile(result.Path)
+	if err != nil {
+		return
+	}
+
+	for _, line := range strings.Split(string(data), "\n") {
+		split := strings.SplitN(strings.TrimSpace(line), " ", 2)
+		if len(split) != 2 {
+			continue
+		}
+
+		key := split[0]
+		value := split[1]
+
+		if key == "buffer" {
+			result.Buffers = append(result.Buffers, SessionBuffer{Filepath: value})
+			continue
+		}
+
+		if key == "search" {
+			unquoted, err := strconv.Unquote(value)
+			if err == nil {
+				result.SearchHistory = append(result.SearchHistory, unquoted)
+			}
+			continue
+		}
+
+		if key == "command" {
+			unquoted, err := strconv.Unquote(value)
+			if err == nil {
+				result.Commands = append(result.Commands, unquoted)
+			}
+			continue
+		}
+
+		if key == "recent" {
+			result.RecentFiles = append(result.RecentFiles, value)
+			continue
+		}
+
+		if key == "mark" {
+			parts := strings.SplitN(value, " ", 4)
+			if len(parts) == 4 && len(parts[0]) == 1 {
+				numbers := parseNumbers(strings.Join(parts[1:3], " "))
+				if len(numbers) == 2 {
+					position := Position{Filepath: parts[3], Line: numbers[0], Column: numbers[1]}
+					result.Marks = append(result.Marks, NamedMark{Name: parts[0][0], Position: position})
+				}
+			}
+			continue
+		}
+
+		if key == "register" {
+			parts := strings.SplitN(value, " ", 2)
+			unquoted, err := strconv.Unquote(parts[len(parts)-1])
+			if len(parts) == 2 && len(parts[0]) == 1 && err == nil {
+				result.Registers[parts[0][0]] = unquoted
+			}
+			continue
+		}
+
+		// Everything else describes the last buffer
+		if len(result.Buffers) == 0 {
+			continue
+		}
+
+		buffer := &result.Buffers[len(result.Buffers)-1]
+		numbers := parseNumbers(value)
+
+		switch key {
+		case "cursor":
+			if len(numbers) == 2 {
+				buffer.Line = numbers[0]
+				buffer.Column = numbers[1]
+			}
+		case "scroll":
+			// Sessions saved before the side scroll was kept only have the first number
+			if len(numbers) == 1 || len(numbers) == 2 {
+				buffer.ScrollY = numbers[0]
+			}
+			if len(numbers) == 2 {
+				buffer.ScrollX = numbers[1]
+			}
+		}
+	}
+
+	return
+}
+
+func (session *Session) Save() {
+	var lines []string
+
+	for _, buffer := range session.Buffers {
+		lines = append(lines, fmt.Sprintf("buffer %s", buffer.Filepath))
+		lines = append(lines, fmt.Sprintf("cursor %d %d", buffer.Line, buffer.Column))
+		lines = append(lines, fmt.Sprintf("scroll %d %d", buffer.ScrollY, buffer.ScrollX))
+	}
+
+	for _, query := range session.SearchHistory {
+		lines = append(lines, fmt.Sprintf("search %s", strconv.Quote(query)))
+	}
+
+	for _, command := range session.Commands {
+		lines = append(lines, fmt.Sprintf("command %s", strconv.Quote(command)))
+	}
+
+	for _, path := range session.RecentFiles {
+		lines = append(lines, fmt.Sprintf("recent %s", path))
+	}
+
+	for _, mark := range session.Marks {
+		position := mark.Position
+		lines = append(lines, fmt.Sprintf("mark %c %d %d %s", mark.Name, position.Line, position.Column, position.Filepath))
+	}
+
+	names := make([]int, 0, len(session.Registers))
+	for name := range session.Registers {
+		names = append(names, int(name))
+	}
+	sort.Ints(names)
+
+	for _, name := range names {
+		lines = append(lines, fmt.Sprintf("register %c %s", name, strconv.Quote(session.Registers[byte(name)])))
+	}
+
+	if CreateDirectory(filepath.Dir(session.Path)) {
+		SaveFile(session.Path, lines)
+	}
+}
+
+func parseNumbers(value string) (result []int32) {
+	for _, field := range strings.Fields(value) {
+		number, err := strconv.Atoi(field)
+		if err != nil {
+			return nil
+		}
+
+		result = append(result, int32(number))
+	}
+
+	return
+}
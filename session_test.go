@@ -0,0 +1,52 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSessionRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+
+	session := LoadSession(dir, "/projects/agurkas")
+	FailIfFalse(len(session.Buffers) == 0, "New session should not have any buffers", t)
+
+	session.Buffers = []SessionBuffer{{Filepath: "/projects/agurkas/my app.go", Line: 10, Column: 4, ScrollY: -36, ScrollX: -72}}
+	session.SearchHistory = []string{"func", "say \"hi\""}
+	session.Commands = []string{"task build", "encoding latin-1"}
+	session.RecentFiles = []string{"/projects/agurkas/my app.go", "/projects/agurkas/main.go"}
+	session.Registers['"'] = "line one\nline two"
+	session.Marks = []NamedMark{{Name: 'a', Position: Position{Filepath: "/projects/agurkas/my app.go", Line: 3, Column: 2}}, {Name: 'M', Position: Position{Filepath: "/projects/agurkas/main.go", Line: 7}}}
+	session.Save()
+
+	loaded := LoadSession(dir, "/projects/agurkas")
+	FailNowIfFalse(len(loaded.Buffers) == 1, "Expected 1 buffer in the loaded session", t)
+	FailIfFalse(loaded.Buffers[0] == session.Buffers[0], "Buffer state was not restored", t)
+	FailIfFalse(linesEqual(loaded.SearchHistory, session.SearchHistory), "Search history was not restored", t)
+	FailIfFalse(linesEqual(loaded.Commands, session.Commands), "Command history was not restored", t)
+	FailIfFalse(linesEqual(loaded.RecentFiles, session.RecentFiles), "Recent files were not restored", t)
+	FailIfFalse(loaded.Registers['"'] == "line one\nline two", "Registers were not restored", t)
+	FailIfFalse(len(loaded.Marks) == 2 && loaded.Marks[0] == session.Marks[0] && loaded.Marks[1] == session.Marks[1], "Marks were not restored", t)
+
+	data := "buffer /projects/agurkas/main.go\ncursor 1 2\nscroll -18\n"
+	FailNowIfFalse(os.WriteFile(loaded.Path, []byte(data), 0644) == nil, "Could not write the session", t)
+	old := LoadSession(dir, "/projects/agurkas")
+	FailIfFalse(len(old.Buffers) == 1 && old.Buffers[0].ScrollY == -18 && old.Buffers[0].ScrollX == 0, "Expected a session without the side scroll to load", t)
+
+	other := LoadSession(dir, "/projects/other")
+	FailIfFalse(len(other.Buffers) == 0, "Sessions of different projects should not be shared", t)
+}
+
+func TestSessionInUnwritableDirectory(t *testing.T) {
+	// A file where the sessions directory should be makes the directory impossible to create
+	file := filepath.Join(t.TempDir(), "config")
+	FailNowIfFalse(os.WriteFile(file, []byte{}, 0644) == nil, "Could not write the file", t)
+
+	session := LoadSession(file, "/projects/agurkas")
+	session.SearchHistory = []string{"func"}
+	session.Save()
+
+	loaded := LoadSession(file, "/projects/agurkas")
+	FailIfFalse(len(loaded.SearchHistory) == 0, "Expected nothing to be saved", t)
+}
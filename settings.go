@@ -0,0 +1,410 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Settings come in layers. The defaults are overridden by the settings file in the user config directory, that by
+// "set <name>: <value>" lines in the project file and that by :set for the open buffer. Every layer only has the
+// options it changes
+
+type Settings struct {
+	Font         string
+	BoldFont     string
+	FontSize     int32
+	LineHeight   int32
+	WindowWidth  int32
+	WindowHeight int32
+	Maximized    bool
+	Theme        string
+
+	GutterWidth      int32
+	ScrollOffset     int32
+	SideScrollOffset int32
+	IndentWidth      int32
+	Wrap             bool
+	WrapColumn       int32
+	Backup           bool
+}
+
+type SettingOption struct {
+	Name    string
+	Default string
+	Startup bool // Only read when the editor starts, so only the settings file can set it
+	Theme   bool // The value is the name of a theme or the path of a theme file
+	Font    bool // The value is a list of fonts to try, at least one of them has to exist
+	Min     int32
+	Max     int32
+
+	// One of these is set, depending on the type of the option
+	Int    func(settings *Settings) *int32
+	Bool   func(settings *Settings) *bool
+	String func(settings *Settings) *string
+}
+
+const (
+	minFontSize = 6
+	maxFontSize = 72
+)
+
+// The fonts that ship with the editor, used when none of the fonts in the settings can be loaded
+const (
+	bundledFont     = "./assets/fonts/consola.ttf"
+	bundledBoldFont = "./assets/fonts/consolab.ttf"
+)
+
+var settingOptions = []SettingOption{
+	{Name: "font", Default: bundledFont, Font: true, String: func(s *Settings) *string { return &s.Font }},
+	{Name: "boldfont", Default: bundledBoldFont, Font: true, String: func(s *Settings) *string { return &s.BoldFont }},
+	{Name: "fontsize", Default: "14", Min: minFontSize, Max: maxFontSize, Int: func(s *Settings) *int32 { return &s.FontSize }},
+	{Name: "lineheight", Default: "18", Min: 8, Max: 144, Int: func(s *Settings) *int32 { return &s.LineHeight }},
+	{Name: "windowwidth", Default: "800", Startup: true, Min: 200, Max: 16384, Int: func(s *Settings) *int32 { return &s.WindowWidth }},
+	{Name: "windowheight", Default: "600", Startup: true, Min: 200, Max: 16384, Int: func(s *Settings) *int32 { return &s.WindowHeight }},
+	{Name: "maximized", Default: "on", Startup: true, Bool: func(s *Settings) *bool { return &s.Maximized }},
+	{Name: "theme", Default: "default", Theme: true, String: func(s *Settings) *string { return &s.Theme }},
+	{Name: "gutterwidth", Default: "48", Min: 0, Max: 400, Int: func(s *Settings) *int32 { return &s.GutterWidth }},
+	{Name: "scrolloff", Default: "8", Min: 0, Max: 999, Int: func(s *Settings) *int32 { return &s.ScrollOffset }},
+	{Name: "sidescrolloff", Default: "5", Min: 0, Max: 999, Int: func(s *Settings) *int32 { return &s.SideScrollOffset }},
+	{Name: "indent", Default: "4", Min: 1, Max: 16, Int: func(s *Settings) *int32 { return &s.IndentWidth }},
+	{Name: "wrap", Default: "off", Bool: func(s *Settings) *bool { return &s.Wrap }},
+	{Name: "wrapcolumn", Default: "0", Min: 0, Max: 9999, Int: func(s *Settings) *int32 { return &s.WrapColumn }},
+	{Name: "backup", Default: "off", Bool: func(s *Settings) *bool { return &s.Backup }},
+}
+
+func DefaultSettings() (result Settings) {
+	for index := range settingOptions {
+		settingOptions[index].assign(&result, settingOptions[index].Default)
+	}
+
+	return
+}
+
+// UserSettingsPath returns where the settings file is, empty when there is no user config directory
+func UserSettingsPath() string {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+
+	return filepath.Join(configDir, "agurkas", "settings.asettings")
+}
+
+// ReadSettings reads the settings file over the defaults. A missing file leaves the defaults as they are
+func ReadSettings(path string) (result Settings, errors []ParseError) {
+	if path == "" {
+		return DefaultSettings(), nil
+	}
+
+	data, _, success := OpenFile(path)
+	if !success {
+		return DefaultSettings(), nil
+	}
+
+	return ParseSettings(string(data), DefaultSettings())
+}
+
+// Settings files consist of "name: value" lines. Lines starting with # are comments.
+//
+//	font: Cascadia Mono, C:/fonts/mono.ttf    fonts to try in order, names of installed fonts or paths
+//	fontsize: 16
+//	theme: light                              name of a theme in the themes folders or the path of a theme file
+//	wrap: on
+func ParseSettings(data string, base Settings) (result Settings, errors []ParseError) {
+	result = base
+
+	for index, line := range strings.Split(data, "\n") {
+		l := strings.TrimSpace(line)
+		if l == "" || strings.HasPrefix(l, "#") {
+			continue
+		}
+
+		name, value, ok := getKeyValue(l, ":")
+		if !ok {
+			errors = append(errors, ParseError{Line: index + 1, Message: fmt.Sprintf("expected \"name: value\", got \"%s\"", l)})
+			continue
+		}
+
+		err := result.Set(name, value)
+		if err != nil {
+			errors = append(errors, ParseError{Line: index + 1, Message: err.Error()})
+		}
+	}
+
+	return
+}
+
+// FormatSettings writes every option in the format of the settings file
+func FormatSettings(settings *Settings) (result []string) {
+	for _, option := range settingOptions {
+		value, _ := settings.Get(option.Name)
+		result = append(result, fmt.Sprintf("%s: %s", option.Name, value))
+	}
+
+	return
+}
+
+func findSettingOption(name string) *SettingOption {
+	for index := range settingOptions {
+		if settingOptions[index].Name == name {
+			return &settingOptions[index]
+		}
+	}
+
+	return nil
+}
+
+// Set checks the value and sets the option, the settings are left as they were when the value is not valid
+func (settings *Settings) Set(name string, value string) error {
+	option := findSettingOption(name)
+	if option == nil {
+		return fmt.Errorf("unknown setting \"%s\"", name)
+	}
+
+	if option.Theme {
+		if _, found := FindTheme(value); !found {
+			return fmt.Errorf("theme \"%s\" was not found", value)
+		}
+	}
+
+	if option.Font {
+		if _, found := FindFont(value); !found {
+			return fmt.Errorf("none of the fonts in \"%s\" were found", value)
+		}
+	}
+
+	return option.assign(settings, value)
+}
+
+// SetOverride sets an option from the project file or :set. Options read only at startup can't be overridden
+func (settings *Settings) SetOverride(name string, value string) error {
+	option := findSettingOption(name)
+	if option != nil && option.Startup {
+		return fmt.Errorf("%s can only be set in the settings file", name)
+	}
+
+	return settings.Set(name, value)
+}
+
+func (settings *Settings) Get(name string) (string, bool) {
+	option := findSettingOption(name)
+	if option == nil {
+		return "", false
+	}
+
+	switch {
+	case option.Int != nil:
+		return strconv.Itoa(int(*option.Int(settings))), true
+	case option.Bool != nil:
+		if *option.Bool(settings) {
+			return "on", true
+		}
+		return "off", true
+	}
+
+	return *option.String(settings), true
+}
+
+func (option *SettingOption) assign(settings *Settings, value string) error {
+	switch {
+	case option.Int != nil:
+		number, err := strconv.Atoi(value)
+		if err != nil || number < int(option.Min) || number > int(option.Max) {
+			return fmt.Errorf("%s must be a number between %d and %d, got \"%s\"", option.Name, option.Min, option.Max, value)
+		}
+
+		*option.Int(settings) = int32(number)
+	case option.Bool != nil:
+		on, ok := parseOnOff([]string{value})
+		if !ok {
+			return fmt.Errorf("%s must be on or off, got \"%s\"", option.Name, value)
+		}
+
+		*option.Bool(settings) = on
+	default:
+		if value == "" {
+			return fmt.Errorf("%s can't be empty", option.Name)
+		}
+
+		*option.String(settings) = value
+	}
+
+	return nil
+}
+
+// loadSettingsFonts loads the fonts of the settings in the size, which already has the zoom and the pixel density of the
+// display in it. The smaller font is used for the paths in the file search
+func loadSettingsFonts(settings *Settings, size int32) (regular14 Font, regular12 Font, bold14 Font, ok bool) {
+	regular14, ok = loadFontList(settings.Font, bundledFont, int(size))
+	if !ok {
+		return
+	}
+
+	regular12, ok = loadFontList(settings.Font, bundledFont, Max(int(size)-2, 4))
+	if !ok {
+		UnloadFonts(&regular14)
+		return
+	}
+
+	bold14, ok = loadFontList(settings.BoldFont, bundledBoldFont, int(size))
+	if !ok {
+		UnloadFonts(&regular14, &regular12)
+	}
+
+	return
+}
+
+// loadFontList loads the first font of the list that is found, or the fallback when that one can't be loaded
+func loadFontList(fonts string, fallback string, size int) (Font, bool) {
+	path, found := FindFont(fonts)
+	if found {
+		font, ok := TryLoadFont(path, size)
+		if ok {
+			return font, true
+		}
+	}
+
+	return TryLoadFont(ResolveAssetPath(fallback), size)
+}
+
+// reloadSettings reads the settings file again, it runs when the file is saved in the editor
+func (app *App) reloadSettings() {
+	settings, errors := ReadSettings(app.SettingsPath)
+	app.UserSettings = settings
+	app.updateSettings()
+
+	if len(errors) > 0 {
+		app.reportParseErrors(app.SettingsPath, errors)
+	}
+}
+
+// updateSettings puts the project and buffer overrides on top of the user settings. The overrides were checked when
+// they were set, so the errors are not reported again
+func (app *App) updateSettings() {
+	settings := app.UserSettings
+	for name, value := range app.Project.Settings {
+		settings.SetOverride(name, value)
+	}
+	for name, value := range app.BufferSettings {
+		settings.SetOverride(name, value)
+	}
+
+	app.applySettings(settings)
+}
+
+// applySettings brings the editor in line with the settings. Fonts and the theme are only loaded again when they change
+func (app *App) applySettings(settings Settings) {
+	old := app.Settings
+
+	size := app.fontSize(&settings)
+	loaded := false
+	if settings.Font != old.Font || settings.BoldFont != old.BoldFont || size != app.FontSize {
+		regular14, regular12, bold14, ok := loadSettingsFonts(&settings, size)
+		if ok {
+			// Widgets can point at copies of the fonts, those are unloaded with them
+			UnloadFonts(&app.RegularFont14, &app.RegularFont12, &app.BoldFont14, app.Buffer.Font, app.FileSearch.Font12)
+			app.RegularFont14 = regular14
+			app.RegularFont12 = regular12
+			app.BoldFont14 = bold14
+			app.FontSize = size
+			loaded = true
+		} else {
+			settings.Font, settings.BoldFont, settings.FontSize = old.Font, old.BoldFont, old.FontSize
+		}
+	}
+
+	lineHeight := app.scaleToFont(settings.LineHeight, &settings)
+	if loaded {
+		app.LineHeight = lineHeight
+		app.setFonts(&app.RegularFont14, &app.RegularFont12, &settings)
+	} else if lineHeight != app.LineHeight {
+		app.LineHeight = lineHeight
+		app.setFonts(app.Buffer.Font, app.FileSearch.Font12, &settings)
+	}
+
+	if settings.Theme != old.Theme {
+		app.Settings.Theme = settings.Theme
+		app.reloadTheme()
+	}
+
+	app.Settings = settings
+	app.applyBufferSettings()
+	app.Damaged = true
+}
+
+// setFonts points every widget at the fonts and sizes them for the line height and the font size
+func (app *App) setFonts(font14 *Font, font12 *Font, settings *Settings) {
+	app.Buffer.SetFont(app.LineHeight, font14)
+	app.FileSearch.SetFonts(app.LineHeight, font14, font12)
+	app.CommandPalette.SetFont(app.LineHeight, font14)
+	app.Search.SetFont(app.LineHeight, font14)
+	app.Prompt.SetFont(app.LineHeight, font14)
+
+	width := app.scaleToFont(popupWidth, settings)
+	app.FileSearch.Width = width
+	app.CommandPalette.Width = width
+	app.Search.Width = width
+	app.Prompt.Width = width
+}
+
+// fontSize returns the size of the fonts in pixels, zoomed and scaled for the display
+func (app *App) fontSize(settings *Settings) int32 {
+	zoomed := Clamp(int(settings.FontSize+app.Zoom), minFontSize, maxFontSize)
+	return int32(math.Round(float64(zoomed) * float64(app.Scale)))
+}
+
+// scaleToFont scales a size in the settings by how much larger the loaded fonts are than the font size in the settings
+func (app *App) scaleToFont(value int32, settings *Settings) int32 {
+	return int32(math.Round(float64(value) * float64(app.FontSize) / float64(settings.FontSize)))
+}
+
+// setZoom makes the text larger or smaller than the font size in the settings, by the number of points
+func (app *App) setZoom(zoom int32) {
+	zoom = int32(Clamp(int(app.Settings.FontSize+zoom), minFontSize, maxFontSize)) - app.Settings.FontSize
+	if zoom == app.Zoom {
+		return
+	}
+
+	app.Zoom = zoom
+	app.applySettings(app.Settings)
+}
+
+// SetScale sets how many pixels there are in a point of the window. Displays with a high pixel density have more than one
+func (app *App) SetScale(scale float32) {
+	if scale <= 0 || scale == app.Scale {
+		return
+	}
+
+	app.Scale = scale
+	app.applySettings(app.Settings)
+}
+
+func (app *App) applyBufferSettings() {
+	app.Buffer.GutterWidth = app.scaleToFont(app.Settings.GutterWidth, &app.Settings)
+	app.Buffer.ScrollOffset = app.Settings.ScrollOffset
+	app.Buffer.SideScrollOffset = app.Settings.SideScrollOffset
+	app.Buffer.IndentWidth = app.Settings.IndentWidth
+	app.Buffer.Wrap = app.Settings.Wrap
+	app.Buffer.WrapColumn = app.Settings.WrapColumn
+	app.Buffer.ScrollToCursor()
+}
+
+// setBufferSetting changes an option for the open file only
+func (app *App) setBufferSetting(name string, value string) bool {
+	settings := app.Settings
+	err := settings.SetOverride(name, value)
+	if err != nil {
+		log.Printf("%s", err)
+		return false
+	}
+
+	app.BufferSettings[name] = value
+	app.updateSettings()
+
+	return true
+}
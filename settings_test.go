@@ -0,0 +1,174 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/veandco/go-sdl2/ttf"
+)
+
+func TestParseSettings(t *testing.T) {
+	t.Run("Valid settings", func(t *testing.T) {
+		data := strings.Join([]string{
+			"# comment",
+			"fontsize: 16",
+			"",
+			"wrap: on",
+			"theme: ./themes/default.atheme",
+			"maximized: off",
+		}, "\n")
+
+		settings, errors := ParseSettings(data, DefaultSettings())
+		FailIfFalse(len(errors) == 0, fmt.Sprintf("Expected no errors, got %v", errors), t)
+		FailIfFalse(settings.FontSize == 16, "Incorrect font size", t)
+		FailIfFalse(settings.Wrap, "Expected wrapping to be on", t)
+		FailIfFalse(!settings.Maximized, "Expected the window not to be maximized", t)
+		FailIfFalse(settings.ScrollOffset == 8, "Options not in the file should keep their defaults", t)
+	})
+
+	t.Run("Errors have line numbers", func(t *testing.T) {
+		data := strings.Join([]string{
+			"fontsize: 200",
+			"wrap: maybe",
+			"colour: red",
+			"scrolloff 4",
+			"theme: missing",
+			"indent: 2",
+		}, "\n")
+
+		settings, errors := ParseSettings(data, DefaultSettings())
+		FailNowIfFalse(len(errors) == 5, fmt.Sprintf("Expected 5 errors, got %v", errors), t)
+		for index, err := range errors {
+			FailIfFalse(err.Line == index+1, fmt.Sprintf("Expected the error on line %d, got %d", index+1, err.Line), t)
+		}
+
+		FailIfFalse(settings.FontSize == 14 && !settings.Wrap, "Invalid values should leave the defaults", t)
+		FailIfFalse(settings.Theme == "default", "A missing theme should not be set", t)
+		FailIfFalse(settings.IndentWidth == 2, "Valid lines after errors should still be read", t)
+	})
+
+	t.Run("Formatted settings parse back", func(t *testing.T) {
+		settings := DefaultSettings()
+		settings.Set("gutterwidth", "60")
+		settings.Set("backup", "on")
+
+		parsed, errors := ParseSettings(strings.Join(FormatSettings(&settings), "\n"), DefaultSettings())
+		FailIfFalse(len(errors) == 0, fmt.Sprintf("Expected no errors, got %v", errors), t)
+		FailIfFalse(parsed == settings, "Expected the same settings back", t)
+	})
+
+	t.Run("Missing file", func(t *testing.T) {
+		settings, errors := ReadSettings(filepath.Join(t.TempDir(), "settings.asettings"))
+		FailIfFalse(len(errors) == 0 && settings == DefaultSettings(), "Expected the defaults", t)
+	})
+
+	t.Run("Startup options can't be overridden", func(t *testing.T) {
+		settings := DefaultSettings()
+		FailIfFalse(settings.SetOverride("windowwidth", "1024") != nil, "Expected an error", t)
+		FailIfFalse(settings.Set("windowwidth", "1024") == nil && settings.WindowWidth == 1024, "Expected the settings file to set it", t)
+	})
+}
+
+func TestProjectSettings(t *testing.T) {
+	root := t.TempDir()
+	data := strings.Join([]string{
+		"indent: 2",
+		"set wrap: on",
+		"set scrolloff: 3",
+		"set windowwidth: 900",
+		"set nothing: 1",
+	}, "\n")
+
+	project, errors := ParseProject(data, filepath.Join(root, "test.aproject"))
+	FailNowIfFalse(len(errors) == 2, fmt.Sprintf("Expected 2 errors, got %v", errors), t)
+	FailIfFalse(errors[0].Line == 4 && errors[1].Line == 5, "Incorrect error lines", t)
+	FailIfFalse(len(project.Settings) == 3, fmt.Sprintf("Expected 3 settings, got %v", project.Settings), t)
+
+	app := createTestApp("text")
+	app.Project = project
+	app.updateSettings()
+	FailIfFalse(app.Buffer.Wrap && app.Buffer.ScrollOffset == 3 && app.Buffer.IndentWidth == 2, "Expected the project settings on the buffer", t)
+	FailIfFalse(app.UserSettings.ScrollOffset == 8, "The user settings should not change", t)
+}
+
+// createSettingsTestApp makes an app that can open and save files
+func createSettingsTestApp(t *testing.T, text string) (result App) {
+	result = createTestApp(text)
+	result.registerCommands()
+	result.Watcher = CreateFileWatcher()
+	result.Swapper = CreateSwapper(t.TempDir())
+	t.Cleanup(func() {
+		result.Watcher.Close()
+		result.Swapper.Close()
+	})
+
+	return
+}
+
+func TestSettings(t *testing.T) {
+	t.Run("Set changes the open file", func(t *testing.T) {
+		app := createSettingsTestApp(t, "text")
+		app.runCommand("set gutterwidth 20")
+		app.runCommand("set indent 40")
+		FailIfFalse(app.Buffer.GutterWidth == 20, "Expected a narrower gutter", t)
+		FailIfFalse(app.Buffer.IndentWidth == 4, "An invalid value should not be set", t)
+
+		path := filepath.Join(t.TempDir(), "other.txt")
+		FailNowIfFalse(os.WriteFile(path, []byte("other"), 0644) == nil, "Could not write the file", t)
+		app.openSourceFile(path)
+		FailIfFalse(app.Buffer.GutterWidth == bufferGutterWidth, "Opening another file should drop the buffer settings", t)
+	})
+
+	t.Run("Buffer settings come before project settings", func(t *testing.T) {
+		app := createSettingsTestApp(t, "text")
+		app.Project.Settings = map[string]string{"scrolloff": "3", "sidescrolloff": "2"}
+		app.runCommand("scrolloff 1")
+		FailIfFalse(app.Buffer.ScrollOffset == 1 && app.Buffer.SideScrollOffset == 2, "Expected both layers to apply", t)
+	})
+
+	t.Run("Saving the settings file reloads it", func(t *testing.T) {
+		app := createSettingsTestApp(t, "")
+		app.SettingsPath = filepath.Join(t.TempDir(), "settings.asettings")
+		app.runCommand("settings")
+		FailNowIfFalse(app.Buffer.Filepath == app.SettingsPath, "Expected the settings file to be open", t)
+		FailIfFalse(app.Buffer.TotalLines == len(settingOptions), "Expected every option in the new file", t)
+
+		// Later lines win, so the changes go after the defaults
+		typeKeys(&app, "Goscrolloff: 2<CR>gutterwidth: 30<Esc>")
+		app.saveSourceFile()
+		FailIfFalse(app.Buffer.ScrollOffset == 2 && app.Buffer.GutterWidth == 30, "Expected the saved settings to apply", t)
+		FailIfFalse(app.UserSettings.ScrollOffset == 2, "Expected the user settings to change", t)
+	})
+}
+
+func TestZoom(t *testing.T) {
+	err := ttf.Init()
+	if err != nil {
+		t.Skip("SDL_ttf is not available")
+	}
+	defer ttf.Quit()
+
+	app := createTestApp("text")
+	app.Settings.LineHeight = 18
+	defer UnloadFonts(&app.RegularFont14, &app.RegularFont12, &app.BoldFont14)
+
+	app.setZoom(2)
+	FailIfFalse(app.FontSize == 16 && app.RegularFont14.Size == 16, fmt.Sprintf("Expected 16 pixel fonts, got %d", app.FontSize), t)
+	FailIfFalse(app.LineHeight == 21, fmt.Sprintf("Expected the line height to grow with the font, got %d", app.LineHeight), t)
+	FailIfFalse(app.Buffer.Cursor.Height == 21 && app.Buffer.Font == &app.RegularFont14, "Expected the buffer to use the new font", t)
+	FailIfFalse(app.Buffer.GutterWidth == 55 && app.CommandPalette.Width == 571, "Expected the widgets to grow with the font", t)
+
+	app.setZoom(100)
+	FailIfFalse(app.FontSize == maxFontSize, fmt.Sprintf("Expected the zoom to stop at %d, got %d", maxFontSize, app.FontSize), t)
+
+	typeKeys(&app, "<C-0><C-=><C-=><C-->")
+	FailIfFalse(app.Zoom == 1, fmt.Sprintf("Expected the keys to zoom in by 1, got %d", app.Zoom), t)
+
+	app.setZoom(0)
+	app.SetScale(2)
+	FailIfFalse(app.FontSize == 28 && app.LineHeight == 36, fmt.Sprintf("Expected twice the size, got %d and %d", app.FontSize, app.LineHeight), t)
+	FailIfFalse(app.Settings.FontSize == 14, "The settings should not change", t)
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+type DrawCommandType uint8
+
+const (
+	DrawCommand_Clear DrawCommandType = iota
+	DrawCommand_Rect
+	DrawCommand_Text
+	DrawCommand_Image
+)
+
+type DrawCommand struct {
+	Type  DrawCommandType
+	Rect  sdl.Rect
+	Color sdl.Color
+	Text  string
+}
+
+// SoftwareCanvas records what is drawn and draws it into an image without SDL. There are no fonts without SDL, so every
+// character that is not a space is drawn as a block filling most of its cell
+type SoftwareCanvas struct {
+	Image    *image.RGBA
+	Commands []DrawCommand // Everything drawn since the canvas was last cleared
+	Frames   int
+}
+
+func CreateSoftwareCanvas(width int32, height int32) (result *SoftwareCanvas) {
+	return &SoftwareCanvas{Image: image.NewRGBA(image.Rect(0, 0, int(width), int(height)))}
+}
+
+// Clear starts a new frame
+func (canvas *SoftwareCanvas) Clear(color sdl.Color) {
+	canvas.Commands = []DrawCommand{{Type: DrawCommand_Clear, Color: color}}
+	draw.Draw(canvas.Image, canvas.Image.Bounds(), image.NewUniform(toColor(color)), image.Point{}, draw.Src)
+}
+
+func (canvas *SoftwareCanvas) FillRect(rect *sdl.Rect, color sdl.Color) {
+	canvas.Commands = append(canvas.Commands, DrawCommand{Type: DrawCommand_Rect, Rect: *rect, Color: color})
+	canvas.fill(*rect, color)
+}
+
+func (canvas *SoftwareCanvas) DrawText(font *Font, text string, rect *sdl.Rect, color sdl.Color) {
+	canvas.Commands = append(canvas.Commands, DrawCommand{Type: DrawCommand_Text, Rect: *rect, Color: color, Text: text})
+
+	cell := sdl.Rect{X: rect.X, Y: rect.Y + rect.H/4, W: int32(font.CharacterWidth) - 1, H: rect.H - rect.H/4}
+	for index := 0; index < len(text); index += 1 {
+		if text[index] != ' ' {
+			canvas.fill(cell, color)
+		}
+
+		cell.X += int32(font.CharacterWidth)
+	}
+}
+
+func (canvas *SoftwareCanvas) DrawImage(img *Image, rect sdl.Rect, color sdl.Color) {
+	canvas.Commands = append(canvas.Commands, DrawCommand{Type: DrawCommand_Image, Rect: rect, Color: color})
+
+	// The same as the color and alpha mods of SDL, every pixel is multiplied by the color
+	tinted := image.NewNRGBA(img.Pixels.Bounds())
+	for index := 0; index < len(img.Pixels.Pix); index += 4 {
+		tinted.Pix[index] = uint8(uint16(img.Pixels.Pix[index]) * uint16(color.R) / 255)
+		tinted.Pix[index+1] = uint8(uint16(img.Pixels.Pix[index+1]) * uint16(color.G) / 255)
+		tinted.Pix[index+2] = uint8(uint16(img.Pixels.Pix[index+2]) * uint16(color.B) / 255)
+		tinted.Pix[index+3] = uint8(uint16(img.Pixels.Pix[index+3]) * uint16(color.A) / 255)
+	}
+
+	draw.Draw(canvas.Image, toRectangle(rect), tinted, image.Point{}, draw.Over)
+}
+
+func (canvas *SoftwareCanvas) Present() {
+	canvas.Frames += 1
+}
+
+// Texts returns the text drawn since the canvas was last cleared, in the order it was drawn
+func (canvas *SoftwareCanvas) Texts() (result []string) {
+	for _, command := range canvas.Commands {
+		if command.Type == DrawCommand_Text {
+			result = append(result, command.Text)
+		}
+	}
+
+	return
+}
+
+func (canvas *SoftwareCanvas) fill(rect sdl.Rect, color sdl.Color) {
+	draw.Draw(canvas.Image, toRectangle(rect), image.NewUniform(toColor(color)), image.Point{}, draw.Over)
+}
+
+func toRectangle(rect sdl.Rect) image.Rectangle {
+	return image.Rect(int(rect.X), int(rect.Y), int(rect.X+rect.W), int(rect.Y+rect.H))
+}
+
+func toColor(c sdl.Color) color.NRGBA {
+	return color.NRGBA{R: c.R, G: c.G, B: c.B, A: c.A}
+}
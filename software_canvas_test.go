@@ -0,0 +1,111 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+var updateGolden = flag.Bool("update", false, "write the golden images again instead of comparing with them")
+
+func createRenderTestApp(text string, width int32, height int32) (result App) {
+	result = createTestApp(text)
+	result.RegularFont14 = GetFakeFont()
+	result.RegularFont12 = GetFakeFont()
+	result.BoldFont14 = GetFakeFont()
+	result.Theme, _ = ParseTheme("./themes/default.atheme")
+	result.WindowRect = sdl.Rect{W: width, H: height}
+	result.StatusBar = CreateStatusBar(&result.WindowRect)
+	result.Buffer.Font = &result.RegularFont14
+	result.Resized(width, height)
+
+	return
+}
+
+func expectGoldenImage(name string, actual *image.RGBA, t *testing.T) {
+	path := filepath.Join("testdata", name+".png")
+
+	if *updateGolden {
+		var data bytes.Buffer
+		FailNowIfFalse(png.Encode(&data, actual) == nil, "Could not encode the image", t)
+		FailNowIfFalse(os.WriteFile(path, data.Bytes(), 0644) == nil, fmt.Sprintf("Could not write %s", path), t)
+		return
+	}
+
+	file, err := os.Open(path)
+	FailNowIfFalse(err == nil, fmt.Sprintf("Missing golden image %s, run the tests with -update to write it", path), t)
+	defer file.Close()
+
+	decoded, err := png.Decode(file)
+	FailNowIfFalse(err == nil, fmt.Sprintf("Could not decode %s", path), t)
+
+	expected := image.NewRGBA(decoded.Bounds())
+	for y := decoded.Bounds().Min.Y; y < decoded.Bounds().Max.Y; y += 1 {
+		for x := decoded.Bounds().Min.X; x < decoded.Bounds().Max.X; x += 1 {
+			expected.Set(x, y, decoded.At(x, y))
+		}
+	}
+
+	if expected.Bounds() != actual.Bounds() || !bytes.Equal(expected.Pix, actual.Pix) {
+		actualPath := filepath.Join(t.TempDir(), name+".png")
+		file, err := os.Create(actualPath)
+		if err == nil {
+			png.Encode(file, actual)
+			file.Close()
+		}
+
+		t.Errorf("The frame does not match %s, it was written to %s", path, actualPath)
+	}
+}
+
+func TestDrawList(t *testing.T) {
+	app := createRenderTestApp("one\n\ntwo", 400, 200)
+	canvas := CreateSoftwareCanvas(400, 200)
+
+	app.Render(canvas)
+
+	texts := canvas.Texts()
+	expected := []string{"1", "one", "1", "2", "two", "NORMAL"}
+	FailNowIfFalse(len(texts) >= len(expected), fmt.Sprintf("Expected at least %d texts, got %q", len(expected), texts), t)
+	for index, text := range expected {
+		FailIfFalse(texts[index] == text, fmt.Sprintf("Expected text %d to be %q, got %q", index, text, texts[index]), t)
+	}
+
+	FailIfFalse(canvas.Commands[0].Type == DrawCommand_Clear, "Expected the frame to start with a clear", t)
+	FailIfFalse(canvas.Frames == 1, "Expected the frame to be presented", t)
+
+	cursor := sdl.Rect{X: 53, Y: 0, W: app.Buffer.Cursor.WidthWide, H: app.Buffer.Cursor.Height}
+	found := false
+	for _, command := range canvas.Commands {
+		if command.Type == DrawCommand_Rect && command.Rect == cursor {
+			found = true
+		}
+	}
+	FailIfFalse(found, "Expected the cursor to be drawn at the start of the text", t)
+}
+
+func TestGoldenImages(t *testing.T) {
+	t.Run("Normal mode", func(t *testing.T) {
+		app := createRenderTestApp("package main\n\nfunc main() {\n    println(\"hi\")\n}", 400, 200)
+		canvas := CreateSoftwareCanvas(400, 200)
+
+		app.Render(canvas)
+		expectGoldenImage("normal_mode", canvas.Image, t)
+	})
+
+	t.Run("Insert mode with a long file", func(t *testing.T) {
+		app := createRenderTestApp(string(generateGoSource(20)), 400, 200)
+		canvas := CreateSoftwareCanvas(400, 200)
+
+		typeKeys(&app, "50Gi")
+		app.Render(canvas)
+		expectGoldenImage("insert_mode", canvas.Image, t)
+	})
+}
@@ -7,14 +7,24 @@ import (
 	"github.com/veandco/go-sdl2/sdl"
 )
 
+type StatusBarItem uint8
+
+// Parts of the status bar that do something when clicked
+const (
+	StatusBarItem_Mode StatusBarItem = iota
+	StatusBarItem_File
+	StatusBarItem_FileFormat
+)
+
 type StatusBar struct {
 	Rect          sdl.Rect
 	RemainingRect sdl.Rect
 	TriangeImage  Image
+	Items         map[StatusBarItem]sdl.Rect // Where the clickable parts were drawn in the last frame
 }
 
-func CreateStatusBar(renderer *sdl.Renderer, window *sdl.Rect) (result StatusBar) {
-	result.TriangeImage = LoadImage("./assets/images/status_bar_triangle.png", renderer)
+func CreateStatusBar(window *sdl.Rect) (result StatusBar) {
+	result.TriangeImage = LoadImage(ResolveAssetPath("./assets/images/status_bar_triangle.png"))
 	result.Update(window)
 	return
 }
@@ -29,18 +39,31 @@ func (bar *StatusBar) Update(window *sdl.Rect) {
 	bar.RemainingRect = bar.Rect
 }
 
-func (bar *StatusBar) Begin(renderer *sdl.Renderer, theme *StatusBarTheme) {
+func (bar *StatusBar) Begin(renderer Canvas, theme *StatusBarTheme) {
 	bar.RemainingRect = bar.Rect
+	bar.Items = map[StatusBarItem]sdl.Rect{}
 	DrawRect(renderer, &bar.Rect, theme.BackgroundColor)
 }
 
-func (bar *StatusBar) RenderMode(renderer *sdl.Renderer, mode Mode, font *Font, theme *StatusBarTheme) {
+// ItemAt returns the clickable part of the bar at the point
+func (bar *StatusBar) ItemAt(point sdl.Point) (StatusBarItem, bool) {
+	for item, rect := range bar.Items {
+		if point.InRect(&rect) {
+			return item, true
+		}
+	}
+
+	return 0, false
+}
+
+func (bar *StatusBar) RenderMode(renderer Canvas, mode Mode, font *Font, theme *StatusBarTheme) {
 	color := theme.GetColorForMode(mode)
 
 	width := font.GetStringWidth(string(mode))
 	bgrect := bar.getRectLeft(width + 16 + bar.TriangeImage.Width)
 	bgrect.W -= bar.TriangeImage.Width
 	DrawRect(renderer, &bgrect, color)
+	bar.Items[StatusBarItem_Mode] = bgrect
 	bar.TriangeImage.Render(renderer, sdl.Point{X: bgrect.X + bgrect.W, Y: bgrect.Y}, color)
 
 	textColor := theme.GetTextColorForMode(mode)
@@ -53,7 +76,7 @@ func (bar *StatusBar) RenderMode(renderer *sdl.Renderer, mode Mode, font *Font,
 	DrawText(renderer, font, string(mode), &txtrect, textColor)
 }
 
-func (bar *StatusBar) RenderSubmode(renderer *sdl.Renderer, submode Submode, font *Font, theme *StatusBarTheme) {
+func (bar *StatusBar) RenderSubmode(renderer Canvas, submode Submode, font *Font, theme *StatusBarTheme) {
 	if submode == Submode_None {
 		return
 	}
@@ -67,7 +90,7 @@ func (bar *StatusBar) RenderSubmode(renderer *sdl.Renderer, submode Submode, fon
 	DrawText(renderer, font, string(submode), &rect, theme.TextColor)
 }
 
-func (bar *StatusBar) RenderProject(renderer *sdl.Renderer, projectname string, filename string, dirty bool, font *Font, theme *StatusBarTheme) {
+func (bar *StatusBar) RenderProject(renderer Canvas, projectname string, filename string, dirty bool, font *Font, theme *StatusBarTheme) {
 	if filename == "" {
 		filename = "[untitled]"
 	}
@@ -92,10 +115,11 @@ func (bar *StatusBar) RenderProject(renderer *sdl.Renderer, projectname string,
 		color = theme.DirtyColor
 	}
 
+	bar.Items[StatusBarItem_File] = sdl.Rect{X: rect.X, Y: bar.Rect.Y, W: rect.W, H: bar.Rect.H}
 	DrawText(renderer, font, txt, &rect, color)
 }
 
-func (bar *StatusBar) RenderLineCount(renderer *sdl.Renderer, text string, font *Font, theme *StatusBarTheme) {
+func (bar *StatusBar) RenderLineCount(renderer Canvas, text string, font *Font, theme *StatusBarTheme) {
 	width := font.GetStringWidth(text)
 	rect := bar.getRectRight(width + 8)
 	rect.Y += (rect.H - int32(font.Size)) / 2
@@ -105,7 +129,28 @@ func (bar *StatusBar) RenderLineCount(renderer *sdl.Renderer, text string, font
 	DrawText(renderer, font, text, &rect, theme.TextColor)
 }
 
-func (bar *StatusBar) RenderCaps(renderer *sdl.Renderer, text string, font *Font, theme *StatusBarTheme) {
+func (bar *StatusBar) RenderFileFormat(renderer Canvas, text string, font *Font, theme *StatusBarTheme) {
+	width := font.GetStringWidth(text)
+	rect := bar.getRectRight(width + 8)
+	bar.Items[StatusBarItem_FileFormat] = rect
+	rect.Y += (rect.H - int32(font.Size)) / 2
+	rect.W = width
+	rect.H = int32(font.Size)
+
+	DrawText(renderer, font, text, &rect, theme.TextColor)
+}
+
+func (bar *StatusBar) RenderCaps(renderer Canvas, text string, font *Font, theme *StatusBarTheme) {
+	width := font.GetStringWidth(text)
+	rect := bar.getRectRight(width + 8)
+	rect.Y += (rect.H - int32(font.Size)) / 2
+	rect.W = width
+	rect.H = int32(font.Size)
+
+	DrawText(renderer, font, text, &rect, theme.DirtyColor)
+}
+
+func (bar *StatusBar) RenderMacro(renderer Canvas, text string, font *Font, theme *StatusBarTheme) {
 	width := font.GetStringWidth(text)
 	rect := bar.getRectRight(width + 8)
 	rect.Y += (rect.H - int32(font.Size)) / 2
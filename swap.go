@@ -0,0 +1,186 @@
+package main
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const swapExtension = ".aswap"
+
+// SwapFile holds the unsaved text of a buffer, so it can be recovered if the editor crashes
+type SwapFile struct {
+	Path     string // Location of the swap file itself
+	Filepath string // File that was being edited, empty for untitled buffers
+	Pid      int
+	Time     time.Time
+	Lines    []string
+}
+
+type swapRequest struct {
+	Filepath string
+	Lines    []string
+	Remove   bool
+}
+
+// Swapper writes swap files in the background, so typing never waits for the disk
+type Swapper struct {
+	Dir      string
+	LastSwap time.Time
+
+	requests chan swapRequest
+	done     chan struct{}
+}
+
+func CreateSwapper(dir string) (result Swapper) {
+	result.Dir = dir
+	result.requests = make(chan swapRequest, 16)
+	result.done = make(chan struct{})
+
+	os.MkdirAll(dir, 0755)
+
+	go runSwapper(dir, result.requests, result.done)
+
+	return
+}
+
+func (swapper *Swapper) Write(filepath string, lines []string) {
+	swapper.LastSwap = time.Now()
+	swapper.requests <- swapRequest{Filepath: filepath, Lines: lines}
+}
+
+func (swapper *Swapper) Remove(filepath string) {
+	swapper.requests <- swapRequest{Filepath: filepath, Remove: true}
+}
+
+// Close waits until every queued swap file is written
+func (swapper *Swapper) Close() {
+	close(swapper.requests)
+	<-swapper.done
+}
+
+// FindOrphanedSwapFiles returns swap files left behind by editors that are no longer running
+func FindOrphanedSwapFiles(dir string) (result []SwapFile) {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return
+	}
+
+	for _, file := range files {
+		if file.IsDir() || !strings.HasSuffix(file.Name(), swapExtension) {
+			continue
+		}
+
+		swap, ok := readSwapFile(filepath.Join(dir, file.Name()))
+		if !ok || swap.Pid == os.Getpid() || processAlive(swap.Pid) {
+			continue
+		}
+
+		result = append(result, swap)
+	}
+
+	return
+}
+
+func (swap *SwapFile) Discard() {
+	os.Remove(swap.Path)
+}
+
+func runSwapper(dir string, requests chan swapRequest, done chan struct{}) {
+	written := map[string][sha256.Size]byte{}
+
+	for request := range requests {
+		path := filepath.Join(dir, swapFileName(request.Filepath))
+
+		if request.Remove {
+			os.Remove(path)
+			delete(written, path)
+			continue
+		}
+
+		var sb strings.Builder
+		fmt.Fprintf(&sb, "path %s\n", request.Filepath)
+		fmt.Fprintf(&sb, "pid %d\n", os.Getpid())
+		fmt.Fprintf(&sb, "time %d\n", time.Now().Unix())
+		sb.WriteString("\n")
+		sb.WriteString(strings.Join(request.Lines, "\n"))
+
+		data := []byte(sb.String())
+		hash := sha256.Sum256([]byte(strings.Join(request.Lines, "\n")))
+		if written[path] == hash {
+			continue // Nothing changed since the last write
+		}
+
+		if writeFileAtomic(path, data) == nil {
+			written[path] = hash
+		}
+	}
+
+	close(done)
+}
+
+func readSwapFile(path string) (result SwapFile, ok bool) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return
+	}
+
+	text := string(data)
+	headerEnd := strings.Index(text, "\n\n")
+	if headerEnd == -1 {
+		return
+	}
+
+	result.Path = path
+
+	for _, line := range strings.Split(text[:headerEnd], "\n") {
+		split := strings.SplitN(line, " ", 2)
+		if len(split) != 2 {
+			continue
+		}
+
+		switch split[0] {
+		case "path":
+			result.Filepath = split[1]
+		case "pid":
+			result.Pid, _ = strconv.Atoi(split[1])
+		case "time":
+			seconds, _ := strconv.ParseInt(split[1], 10, 64)
+			result.Time = time.Unix(seconds, 0)
+		}
+	}
+
+	result.Lines = strings.Split(text[headerEnd+2:], "\n")
+
+	return result, true
+}
+
+func swapFileName(filepath string) string {
+	if filepath == "" {
+		return fmt.Sprintf("untitled-%d%s", os.Getpid(), swapExtension)
+	}
+
+	return pathToFileName(filepath) + swapExtension
+}
+
+func processAlive(pid int) bool {
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	defer process.Release()
+
+	// Finding the process on windows already fails if it does not exist, sending signals is not supported there
+	if runtime.GOOS == "windows" {
+		return true
+	}
+
+	return process.Signal(syscall.Signal(0)) == nil
+}
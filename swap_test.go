@@ -0,0 +1,40 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+func TestSwapper(t *testing.T) {
+	dir := t.TempDir()
+
+	swapper := CreateSwapper(dir)
+	swapper.Write("/projects/main.go", []string{"package main", "", "func main() {}"})
+	swapper.Write("/projects/other.go", []string{"package other"})
+	swapper.Remove("/projects/other.go")
+	swapper.Close()
+
+	files, _ := ioutil.ReadDir(dir)
+	FailNowIfFalse(len(files) == 1, fmt.Sprintf("Expected 1 swap file, got %d", len(files)), t)
+
+	swap, ok := readSwapFile(filepath.Join(dir, files[0].Name()))
+	FailNowIfFalse(ok, "Unable to read the swap file", t)
+	FailIfFalse(swap.Filepath == "/projects/main.go", "Incorrect file path in the swap file", t)
+	FailIfFalse(linesEqual(swap.Lines, []string{"package main", "", "func main() {}"}), "Incorrect text in the swap file", t)
+
+	orphans := FindOrphanedSwapFiles(dir)
+	FailIfFalse(len(orphans) == 0, "Swap files of the running editor should not be reported as orphaned", t)
+}
+
+func TestFindOrphanedSwapFiles(t *testing.T) {
+	dir := t.TempDir()
+	ioutil.WriteFile(filepath.Join(dir, "crashed.aswap"), []byte("path /projects/main.go\npid 999999999\ntime 0\n\nrecovered"), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "not_a_swap.txt"), []byte("path x\npid 999999999\n\ntext"), 0644)
+
+	orphans := FindOrphanedSwapFiles(dir)
+	FailNowIfFalse(len(orphans) == 1, fmt.Sprintf("Expected 1 orphaned swap file, got %d", len(orphans)), t)
+	FailIfFalse(orphans[0].Filepath == "/projects/main.go", "Incorrect file path of the orphaned swap file", t)
+	FailIfFalse(linesEqual(orphans[0].Lines, []string{"recovered"}), "Incorrect text of the orphaned swap file", t)
+}
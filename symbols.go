@@ -0,0 +1,396 @@
+package main
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+type Symbol struct {
+	Name      string
+	Container string // Receiver of a method, empty otherwise
+	Kind      string
+	Filepath  string
+	Line      int32 // 0-based
+	Column    int32 // 0-based
+}
+
+// FullName includes the container, so methods can be found by the name of their type
+func (symbol *Symbol) FullName() string {
+	if symbol.Container == "" {
+		return symbol.Name
+	}
+
+	return symbol.Container + "." + symbol.Name
+}
+
+type symbolPattern struct {
+	Kind    string
+	Pattern *regexp.Regexp // The first group is the name of the symbol
+}
+
+// Patterns for files that go/parser can't read, in the spirit of ctags. They only look at one line at a time
+var (
+	cPatterns = []symbolPattern{
+		{"function", regexp.MustCompile(`^[A-Za-z_][\w\s\*:&<>,]*?\b([A-Za-z_]\w*)\s*\([^;]*$`)},
+		{"struct", regexp.MustCompile(`^\s*(?:typedef\s+)?struct\s+(\w+)`)},
+		{"class", regexp.MustCompile(`^\s*class\s+(\w+)`)},
+		{"macro", regexp.MustCompile(`^#define\s+(\w+)`)},
+	}
+	jsPatterns = []symbolPattern{
+		{"function", regexp.MustCompile(`^\s*(?:export\s+)?(?:default\s+)?(?:async\s+)?function\*?\s+(\w+)`)},
+		{"class", regexp.MustCompile(`^\s*(?:export\s+)?(?:default\s+)?(?:abstract\s+)?class\s+(\w+)`)},
+		{"function", regexp.MustCompile(`^\s*(?:export\s+)?(?:const|let|var)\s+(\w+)\s*=\s*(?:async\s*)?(?:\([^)]*\)|\w+)\s*=>`)},
+		{"interface", regexp.MustCompile(`^\s*(?:export\s+)?interface\s+(\w+)`)},
+		{"type", regexp.MustCompile(`^\s*(?:export\s+)?type\s+(\w+)\s*=`)},
+	}
+	pythonPatterns = []symbolPattern{
+		{"function", regexp.MustCompile(`^\s*(?:async\s+)?def\s+(\w+)`)},
+		{"class", regexp.MustCompile(`^\s*class\s+(\w+)`)},
+	}
+	rustPatterns = []symbolPattern{
+		{"function", regexp.MustCompile(`^\s*(?:pub(?:\([^)]*\))?\s+)?(?:async\s+)?(?:unsafe\s+)?fn\s+(\w+)`)},
+		{"struct", regexp.MustCompile(`^\s*(?:pub(?:\([^)]*\))?\s+)?struct\s+(\w+)`)},
+		{"enum", regexp.MustCompile(`^\s*(?:pub(?:\([^)]*\))?\s+)?enum\s+(\w+)`)},
+		{"trait", regexp.MustCompile(`^\s*(?:pub(?:\([^)]*\))?\s+)?trait\s+(\w+)`)},
+	}
+	markdownPatterns = []symbolPattern{
+		{"heading", regexp.MustCompile(`^#{1,6}\s+(.+?)\s*#*$`)},
+	}
+)
+
+var symbolPatterns = map[string][]symbolPattern{
+	".c":   cPatterns,
+	".h":   cPatterns,
+	".cpp": cPatterns,
+	".hpp": cPatterns,
+	".cc":  cPatterns,
+	".js":  jsPatterns,
+	".jsx": jsPatterns,
+	".ts":  jsPatterns,
+	".tsx": jsPatterns,
+	".py":  pythonPatterns,
+	".rs":  rustPatterns,
+	".md":  markdownPatterns,
+}
+
+// Files larger than this are not indexed, they are most likely generated
+const maxSymbolFileSize = 1 << 20
+
+// HasSymbols reports whether symbols can be extracted from files with this name
+func HasSymbols(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	_, ok := symbolPatterns[ext]
+
+	return ok || ext == ".go"
+}
+
+// ExtractSymbols finds the declarations in the file. Go files are parsed, other languages are matched line by line
+func ExtractSymbols(path string, data []byte) []Symbol {
+	ext := strings.ToLower(filepath.Ext(path))
+	if ext == ".go" {
+		symbols, ok := extractGoSymbols(path, data)
+		if ok {
+			return symbols
+		}
+
+		// The parser gives up on the rest of the file after an error, so the lines it didn't get to are matched by patterns
+		known := map[int32]bool{}
+		for _, symbol := range symbols {
+			known[symbol.Line] = true
+		}
+
+		for _, symbol := range extractPatternSymbols(path, data, goFallbackPatterns) {
+			if !known[symbol.Line] {
+				symbols = append(symbols, symbol)
+			}
+		}
+		sort.SliceStable(symbols, func(i, j int) bool { return symbols[i].Line < symbols[j].Line })
+
+		return symbols
+	}
+
+	return extractPatternSymbols(path, data, symbolPatterns[ext])
+}
+
+var goFallbackPatterns = []symbolPattern{
+	{"func", regexp.MustCompile(`^func\s+(?:\([^)]*\)\s*)?(\w+)`)},
+	{"type", regexp.MustCompile(`^type\s+(\w+)`)},
+}
+
+func extractGoSymbols(path string, data []byte) (result []Symbol, ok bool) {
+	fileSet := token.NewFileSet()
+
+	// A broken file still gives back what was parsed, ok tells the caller that something may be missing
+	file, err := parser.ParseFile(fileSet, path, data, 0)
+	if file == nil {
+		return nil, false
+	}
+
+	add := func(name *ast.Ident, container string, kind string) {
+		if name == nil || name.Name == "_" {
+			return
+		}
+
+		position := fileSet.Position(name.Pos())
+		result = append(result, Symbol{
+			Name:      name.Name,
+			Container: container,
+			Kind:      kind,
+			Filepath:  path,
+			Line:      int32(position.Line - 1),
+			Column:    int32(position.Column - 1),
+		})
+	}
+
+	for _, decl := range file.Decls {
+		switch decl := decl.(type) {
+		case *ast.FuncDecl:
+			if decl.Recv != nil && len(decl.Recv.List) > 0 {
+				add(decl.Name, receiverTypeName(decl.Recv.List[0].Type), "method")
+			} else {
+				add(decl.Name, "", "func")
+			}
+		case *ast.GenDecl:
+			for _, spec := range decl.Specs {
+				switch spec := spec.(type) {
+				case *ast.TypeSpec:
+					kind := "type"
+					switch spec.Type.(type) {
+					case *ast.StructType:
+						kind = "struct"
+					case *ast.InterfaceType:
+						kind = "interface"
+					}
+					add(spec.Name, "", kind)
+				case *ast.ValueSpec:
+					for _, name := range spec.Names {
+						add(name, "", decl.Tok.String())
+					}
+				}
+			}
+		}
+	}
+
+	return result, err == nil
+}
+
+func receiverTypeName(expr ast.Expr) string {
+	switch expr := expr.(type) {
+	case *ast.StarExpr:
+		return receiverTypeName(expr.X)
+	case *ast.Ident:
+		return expr.Name
+	}
+
+	return ""
+}
+
+func extractPatternSymbols(path string, data []byte, patterns []symbolPattern) (result []Symbol) {
+	if len(patterns) == 0 {
+		return
+	}
+
+	for index, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimRight(line, "\r")
+
+		for _, pattern := range patterns {
+			match := pattern.Pattern.FindStringSubmatchIndex(line)
+			if match == nil {
+				continue
+			}
+
+			result = append(result, Symbol{
+				Name:     line[match[2]:match[3]],
+				Kind:     pattern.Kind,
+				Filepath: path,
+				Line:     int32(index),
+				Column:   int32(match[2]),
+			})
+			break
+		}
+	}
+
+	return
+}
+
+// SymbolIndex keeps the symbols of every project file. Files are read and parsed in the background
+type SymbolIndex struct {
+	mutex   sync.Mutex
+	files   map[string][]Symbol
+	pending []string
+	changed bool // Symbols were added or removed since the last call to Changed
+
+	wake     chan struct{}
+	quit     chan struct{}
+	finished chan struct{}
+}
+
+func CreateSymbolIndex() *SymbolIndex {
+	result := &SymbolIndex{
+		files:    map[string][]Symbol{},
+		wake:     make(chan struct{}, 1),
+		quit:     make(chan struct{}),
+		finished: make(chan struct{}),
+	}
+
+	go result.run()
+
+	return result
+}
+
+// Sync queues the files that are not indexed yet and forgets the ones that are gone
+func (index *SymbolIndex) Sync(files []string) {
+	current := make(map[string]bool, len(files))
+	for _, path := range files {
+		current[path] = true
+	}
+
+	index.mutex.Lock()
+	for path := range index.files {
+		if !current[path] {
+			delete(index.files, path)
+			index.changed = true
+		}
+	}
+
+	for _, path := range files {
+		if _, ok := index.files[path]; !ok && HasSymbols(path) {
+			index.files[path] = nil // Marks the file as known until it is parsed
+			index.pending = append(index.pending, path)
+		}
+	}
+	index.mutex.Unlock()
+
+	index.signal()
+}
+
+// Update reads the file again in the background
+func (index *SymbolIndex) Update(path string) {
+	if !HasSymbols(path) {
+		return
+	}
+
+	index.mutex.Lock()
+	index.pending = append(index.pending, path)
+	index.mutex.Unlock()
+
+	index.signal()
+}
+
+// Set replaces the symbols of a file with ones extracted from its text, used when the text is already in memory
+func (index *SymbolIndex) Set(path string, data []byte) {
+	if !HasSymbols(path) {
+		return
+	}
+
+	symbols := ExtractSymbols(path, data)
+
+	index.mutex.Lock()
+	index.files[path] = symbols
+	index.changed = true
+	index.mutex.Unlock()
+}
+
+// Symbols returns every indexed symbol, ordered by file and line
+func (index *SymbolIndex) Symbols() (result []Symbol) {
+	index.mutex.Lock()
+	paths := make([]string, 0, len(index.files))
+	for path := range index.files {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		result = append(result, index.files[path]...)
+	}
+	index.mutex.Unlock()
+
+	return
+}
+
+// Changed reports whether symbols were added or removed since it was last called
+func (index *SymbolIndex) Changed() (result bool) {
+	index.mutex.Lock()
+	result = index.changed
+	index.changed = false
+	index.mutex.Unlock()
+
+	return
+}
+
+func (index *SymbolIndex) Close() {
+	close(index.quit)
+	<-index.finished
+}
+
+func (index *SymbolIndex) signal() {
+	select {
+	case index.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (index *SymbolIndex) run() {
+	defer close(index.finished)
+
+	for {
+		select {
+		case <-index.quit:
+			return
+		case <-index.wake:
+		}
+
+		for {
+			index.mutex.Lock()
+			if len(index.pending) == 0 {
+				index.mutex.Unlock()
+				break
+			}
+			path := index.pending[0]
+			index.pending = index.pending[1:]
+			index.mutex.Unlock()
+
+			symbols, ok := readSymbols(path)
+
+			// Files that left the project while they were waiting are not added back
+			index.mutex.Lock()
+			if _, known := index.files[path]; known {
+				if ok {
+					index.files[path] = symbols
+				} else {
+					delete(index.files, path)
+				}
+				index.changed = true
+			}
+			index.mutex.Unlock()
+
+			select {
+			case <-index.quit:
+				return
+			default:
+			}
+		}
+	}
+}
+
+func readSymbols(path string) ([]Symbol, bool) {
+	info, err := os.Stat(path)
+	if err != nil || info.IsDir() || info.Size() > maxSymbolFileSize {
+		return nil, false
+	}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, false
+	}
+
+	return ExtractSymbols(path, data), true
+}
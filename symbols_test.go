@@ -0,0 +1,178 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+func findSymbol(symbols []Symbol, fullName string) (Symbol, bool) {
+	for _, symbol := range symbols {
+		if symbol.FullName() == fullName {
+			return symbol, true
+		}
+	}
+
+	return Symbol{}, false
+}
+
+func TestExtractSymbols(t *testing.T) {
+	t.Run("Go declarations", func(t *testing.T) {
+		source := "package main\n\nconst Version = \"1\"\n\nvar (\n\tcount int\n\t_ = 2\n)\n\ntype Buffer struct{}\n\ntype Reader interface{}\n\ntype Mode int\n\nfunc main() {}\n\nfunc (buffer *Buffer) Render() {}\n"
+		symbols := ExtractSymbols("/p/main.go", []byte(source))
+
+		expected := map[string]string{"Version": "const", "count": "var", "Buffer": "struct", "Reader": "interface", "Mode": "type", "main": "func", "Buffer.Render": "method"}
+		FailIfFalse(len(symbols) == len(expected), fmt.Sprintf("Expected %d symbols, got %d", len(expected), len(symbols)), t)
+		for name, kind := range expected {
+			symbol, ok := findSymbol(symbols, name)
+			FailIfFalse(ok && symbol.Kind == kind, fmt.Sprintf("Expected %s to be a %s", name, kind), t)
+		}
+
+		render, _ := findSymbol(symbols, "Buffer.Render")
+		FailIfFalse(render.Line == 17 && render.Column == 22, fmt.Sprintf("Expected Render at 17:22, got %d:%d", render.Line, render.Column), t)
+	})
+
+	t.Run("Broken Go files use the patterns", func(t *testing.T) {
+		source := "package main\n\nfunc (app *App) Tick( {\n\ntype Mode int\n"
+		symbols := ExtractSymbols("/p/app.go", []byte(source))
+
+		_, hasTick := findSymbol(symbols, "App.Tick")
+		_, hasMode := findSymbol(symbols, "Mode")
+		FailIfFalse(hasTick && hasMode, "Expected App.Tick and Mode to be found in a file that does not parse", t)
+	})
+
+	t.Run("Other languages", func(t *testing.T) {
+		cases := []struct {
+			path   string
+			source string
+			name   string
+			kind   string
+		}{
+			{"/p/a.py", "import os\n\nclass Parser:\n    def parse(self):\n        pass\n", "parse", "function"},
+			{"/p/a.py", "class Parser:\n    pass\n", "Parser", "class"},
+			{"/p/a.ts", "export const render = (props) => {\n}\n", "render", "function"},
+			{"/p/a.rs", "pub(crate) fn draw_text() {}\n", "draw_text", "function"},
+			{"/p/a.c", "static int count_lines(const char *text)\n{\n", "count_lines", "function"},
+			{"/p/README.md", "# agurkas-editor\n", "agurkas-editor", "heading"},
+		}
+
+		for _, c := range cases {
+			symbol, ok := findSymbol(ExtractSymbols(c.path, []byte(c.source)), c.name)
+			FailIfFalse(ok && symbol.Kind == c.kind, fmt.Sprintf("Expected %s to be found as a %s in %s", c.name, c.kind, c.path), t)
+		}
+
+		FailIfFalse(len(ExtractSymbols("/p/notes.txt", []byte("def nothing():\n"))) == 0, "Files without patterns should not have symbols", t)
+	})
+}
+
+func waitForSymbols(index *SymbolIndex, count int) []Symbol {
+	for i := 0; i < 200; i += 1 {
+		symbols := index.Symbols()
+		if len(symbols) == count {
+			return symbols
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	return index.Symbols()
+}
+
+func TestSymbolIndex(t *testing.T) {
+	dir := t.TempDir()
+	first := filepath.Join(dir, "first.go")
+	second := filepath.Join(dir, "second.py")
+	ioutil.WriteFile(first, []byte("package main\n\nfunc First() {}\n"), 0644)
+	ioutil.WriteFile(second, []byte("def second():\n    pass\n"), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "notes.txt"), []byte("def nothing():\n"), 0644)
+
+	index := CreateSymbolIndex()
+	defer index.Close()
+
+	t.Run("Files are indexed in the background", func(t *testing.T) {
+		index.Sync([]string{first, second, filepath.Join(dir, "notes.txt")})
+
+		symbols := waitForSymbols(index, 2)
+		FailNowIfFalse(len(symbols) == 2, fmt.Sprintf("Expected 2 symbols, got %d", len(symbols)), t)
+		FailIfFalse(symbols[0].Name == "First" && symbols[1].Name == "second", "Expected symbols ordered by file", t)
+		FailIfFalse(index.Changed() && !index.Changed(), "Expected the change to be reported once", t)
+	})
+
+	t.Run("Saved files replace their symbols", func(t *testing.T) {
+		index.Set(first, []byte("package main\n\nfunc Renamed() {}\n\nfunc Added() {}\n"))
+
+		symbols := index.Symbols()
+		_, hasRenamed := findSymbol(symbols, "Renamed")
+		_, hasFirst := findSymbol(symbols, "First")
+		FailIfFalse(len(symbols) == 3 && hasRenamed && !hasFirst, "Expected the symbols of the saved text", t)
+	})
+
+	t.Run("Removed files are forgotten", func(t *testing.T) {
+		index.Sync([]string{second})
+
+		symbols := index.Symbols()
+		FailIfFalse(len(symbols) == 1 && symbols[0].Name == "second", "Expected only the symbols of the remaining file", t)
+	})
+}
+
+func TestOpenSymbolSearch(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "main.go")
+
+	fakeFont := GetFakeFont()
+	app := App{Buffer: CreateBuffer(16, &fakeFont, sdl.Rect{W: 800, H: 600})}
+	app.Symbols = CreateSymbolIndex()
+	defer app.Symbols.Close()
+	app.Project.Root = dir
+	app.Project.Files = []string{path}
+
+	app.openFileSearch("#first")
+	FailIfFalse(len(app.FileSearch.FoundEntries) == 0, "Expected no symbols before the file is indexed", t)
+
+	app.Symbols.Set(path, []byte("package main\n\nfunc First() {}\n"))
+	app.syncProjectFiles()
+	FailNowIfFalse(len(app.FileSearch.FoundEntries) == 1, fmt.Sprintf("Expected the indexed symbol in the open search, got %d", len(app.FileSearch.FoundEntries)), t)
+	FailIfFalse(app.FileSearch.FileEntries[app.FileSearch.FoundEntries[0].Index].Name == "First", "Expected the First symbol", t)
+}
+
+func TestFileSearchSymbols(t *testing.T) {
+	symbols := []Symbol{
+		{Name: "Render", Container: "Buffer", Kind: "method", Filepath: "/p/buffer.go", Line: 9, Column: 22},
+		{Name: "Update", Container: "Buffer", Kind: "method", Filepath: "/p/buffer.go", Line: 30, Column: 22},
+		{Name: "main", Kind: "func", Filepath: "/p/main.go", Line: 3, Column: 5},
+	}
+
+	fs := FileSearch{VisibleCount: 8}
+	fs.Symbols = func(scope byte) []FileSearchEntry {
+		return SymbolsToFileSearchEntries(symbols, "/p")
+	}
+
+	var picked *FileSearchEntry
+	fs.OpenWithQuery(PathsToFileSearchEntries([]string{"/p/buffer.go", "/p/main.go"}, "/p"), "#bufren", func(entry *FileSearchEntry) {
+		picked = entry
+	})
+
+	FailNowIfFalse(len(fs.FoundEntries) == 1, fmt.Sprintf("Expected 1 symbol, got %d", len(fs.FoundEntries)), t)
+	fs.Submit()
+	FailNowIfFalse(picked != nil, "Expected a symbol to be picked", t)
+	FailIfFalse(picked.FullPath == "/p/buffer.go" && picked.Line == 9 && picked.Column == 22, "Expected the location of Buffer.Render", t)
+	FailIfFalse(picked.Detail == "method  buffer.go:10", fmt.Sprintf("Unexpected detail %q", picked.Detail), t)
+
+	fs.SearchQuery.SetText("#buffer")
+	fs.updateSearchResults()
+	fs.moveSelection(1)
+	selected := fs.FileEntries[fs.FoundEntries[fs.SelectionIndex].Index]
+	fs.SetEntries(PathsToFileSearchEntries([]string{"/p/buffer.go", "/p/main.go", "/p/new.go"}, "/p"))
+	kept := fs.FileEntries[fs.FoundEntries[fs.SelectionIndex].Index]
+	FailIfFalse(kept == selected, fmt.Sprintf("Expected %s to stay selected while indexing, got %s", selected.Name, kept.Name), t)
+
+	fs.SearchQuery.SetText("main")
+	fs.updateSearchResults()
+	FailIfFalse(len(fs.FoundEntries) == 1 && fs.FileEntries[fs.FoundEntries[0].Index].Detail == "", "Expected files again without a prefix", t)
+
+	entries := SymbolsToFileSearchEntries([]Symbol{{Name: "Load", Kind: "func", Filepath: "/p/..config/load.go"}}, "/p")
+	FailIfFalse(entries[0].Detail == "func  ..config/load.go:1", fmt.Sprintf("Expected the location relative to the root, got %q", entries[0].Detail), t)
+}
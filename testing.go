@@ -1,6 +1,10 @@
 package main
 
-import "testing"
+import (
+	"testing"
+
+	"github.com/veandco/go-sdl2/sdl"
+)
 
 func FailIfFalse(value bool, message string, t *testing.T) {
 	if !value {
@@ -13,3 +17,39 @@ func FailNowIfFalse(value bool, message string, t *testing.T) {
 		t.Fatal(message)
 	}
 }
+
+func GetFakeFont() Font {
+	return Font{
+		Data:           nil,
+		Size:           14,
+		CharacterWidth: 8,
+	}
+}
+
+func createTestApp(text string) (result App) {
+	fakeFont := GetFakeFont()
+	result.Buffer = CreateBuffer(16, &fakeFont, sdl.Rect{W: 800, H: 600})
+	result.Buffer.SetData([]byte(text), "")
+	result.Registers = map[byte]string{}
+	result.Commands = map[string]func(app *App, args []string){}
+	result.Keymap = CreateKeymap()
+	result.Keymap.Load("./default_keymap.akeys")
+	result.LineHeight = 16
+	result.FontSize = 14
+	result.Scale = 1
+	result.Settings = DefaultSettings()
+	result.Settings.LineHeight = result.LineHeight
+	result.UserSettings = result.Settings
+	result.BufferSettings = map[string]string{}
+
+	result.startNormalMode()
+	result.Submode = Submode_None
+
+	return
+}
+
+func typeKeys(app *App, keys string) {
+	for _, input := range DecodeKeys(keys) {
+		app.handleInput(input)
+	}
+}
@@ -0,0 +1,273 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+// TextInput is a single line of editable text, shared by every prompt. The owner handles submitting and closing, everything else goes through Tick
+type TextInput struct {
+	Text   string
+	Cursor int // Byte offset into the text
+	Anchor int // Other end of the selection, equal to the cursor when nothing is selected
+
+	History      []string // Oldest first
+	historyIndex int      // len(History) while editing a new entry
+	draft        string   // The new entry, kept while browsing the history
+
+	cursor InputCursor
+}
+
+const textInputHistorySize = 50
+
+func CreateTextInput(height int32, advance int32) (result TextInput) {
+	result.cursor = CreateInputCursor(height, advance)
+
+	return
+}
+
+// SetFont sizes the cursor for a font with the advance and a line of the height
+func (ti *TextInput) SetFont(height int32, advance int32) {
+	ti.cursor.Height = height
+	ti.cursor.Advance = advance
+}
+
+func (ti *TextInput) String() string {
+	return ti.Text
+}
+
+func (ti *TextInput) Len() int {
+	return len(ti.Text)
+}
+
+// Reset empties the input and starts a new history entry
+func (ti *TextInput) Reset() {
+	ti.SetText("")
+	ti.historyIndex = len(ti.History)
+	ti.draft = ""
+}
+
+// SetText replaces the text and puts the cursor at the end
+func (ti *TextInput) SetText(text string) {
+	ti.Text = text
+	ti.Cursor = len(text)
+	ti.Anchor = ti.Cursor
+}
+
+func (ti *TextInput) AddHistory(text string) {
+	if text == "" {
+		return
+	}
+
+	history := []string{}
+	for _, item := range ti.History {
+		if item != text {
+			history = append(history, item)
+		}
+	}
+
+	history = append(history, text)
+	if len(history) > textInputHistorySize {
+		history = history[len(history)-textInputHistorySize:]
+	}
+
+	ti.History = history
+	ti.historyIndex = len(ti.History)
+}
+
+func (ti *TextInput) HasSelection() bool {
+	return ti.Cursor != ti.Anchor
+}
+
+// Selection returns the selected range as byte offsets, start <= end
+func (ti *TextInput) Selection() (start int, end int) {
+	return Min(ti.Cursor, ti.Anchor), Max(ti.Cursor, ti.Anchor)
+}
+
+func (ti *TextInput) SelectedText() string {
+	start, end := ti.Selection()
+	return ti.Text[start:end]
+}
+
+// InsertText replaces the selection with the text. Line breaks and tabs become spaces, the input is a single line
+func (ti *TextInput) InsertText(text string) {
+	text = strings.NewReplacer("\r\n", " ", "\n", " ", "\r", " ", "\t", " ").Replace(text)
+
+	start, end := ti.Selection()
+	ti.Text = ti.Text[:start] + text + ti.Text[end:]
+	ti.Cursor = start + len(text)
+	ti.Anchor = ti.Cursor
+}
+
+// Tick applies editing keys and typed characters and returns true if the text changed
+func (ti *TextInput) Tick(input Input) bool {
+	before := ti.Text
+
+	if input.Up {
+		ti.recallHistory(-1)
+	} else if input.Down {
+		ti.recallHistory(1)
+	} else if input.Left {
+		ti.moveCursor(ti.previousPosition(input.Ctrl), input.Shift)
+	} else if input.Right {
+		ti.moveCursor(ti.nextPosition(input.Ctrl), input.Shift)
+	} else if input.Home {
+		ti.moveCursor(0, input.Shift)
+	} else if input.End {
+		ti.moveCursor(len(ti.Text), input.Shift)
+	} else if input.Backspace {
+		if !ti.HasSelection() {
+			ti.Anchor = ti.previousPosition(input.Ctrl)
+		}
+		ti.InsertText("")
+	} else if input.Delete {
+		if !ti.HasSelection() {
+			ti.Anchor = ti.nextPosition(input.Ctrl)
+		}
+		ti.InsertText("")
+	} else if input.Ctrl {
+		switch input.TypedCharacter {
+		case 'a':
+			ti.Anchor = 0
+			ti.Cursor = len(ti.Text)
+		case 'w':
+			if !ti.HasSelection() {
+				ti.Anchor = ti.previousPosition(true)
+			}
+			ti.InsertText("")
+		case 'c':
+			if ti.HasSelection() {
+				sdl.SetClipboardText(ti.SelectedText())
+			}
+		case 'x':
+			if ti.HasSelection() {
+				sdl.SetClipboardText(ti.SelectedText())
+				ti.InsertText("")
+			}
+		case 'v':
+			text, err := sdl.GetClipboardText()
+			if err == nil {
+				ti.InsertText(text)
+			}
+		}
+	} else if input.TypedCharacter != 0 && input.TypedCharacter != '\n' {
+		ti.InsertText(string(input.TypedCharacter))
+	}
+
+	if ti.Text != before && ti.historyIndex == len(ti.History) {
+		ti.draft = ti.Text
+	}
+
+	return ti.Text != before
+}
+
+// Render draws the text, the selection and the cursor. The text starts 5 pixels into the rect, like in every other input
+func (ti *TextInput) Render(renderer Canvas, font *Font, rect sdl.Rect, textColor sdl.Color, selectionColor sdl.Color, cursorColor sdl.Color) {
+	textY := rect.Y + (rect.H-int32(font.Size))/2
+
+	if ti.HasSelection() {
+		start, end := ti.Selection()
+		selectionRect := sdl.Rect{
+			X: rect.X + 5 + font.GetStringWidth(ti.Text[:start]),
+			Y: rect.Y + 5,
+			W: font.GetStringWidth(ti.Text[start:end]),
+			H: ti.cursor.Height,
+		}
+		DrawRect(renderer, &selectionRect, selectionColor)
+	}
+
+	ti.cursor.Column = int32(len(ti.Text[:ti.Cursor]))
+	ti.cursor.Render(renderer, rect, cursorColor)
+
+	if len(ti.Text) > 0 {
+		textRect := sdl.Rect{
+			X: rect.X + 5,
+			Y: textY,
+			W: font.GetStringWidth(ti.Text),
+			H: int32(font.Size),
+		}
+		DrawText(renderer, font, ti.Text, &textRect, textColor)
+	}
+}
+
+func (ti *TextInput) moveCursor(position int, extendSelection bool) {
+	// Moving without shift collapses the selection to the side the cursor moved towards
+	if !extendSelection && ti.HasSelection() {
+		start, end := ti.Selection()
+		if position < ti.Cursor {
+			position = start
+		} else {
+			position = end
+		}
+	}
+
+	ti.Cursor = position
+	if !extendSelection {
+		ti.Anchor = position
+	}
+}
+
+func (ti *TextInput) previousPosition(wordWise bool) int {
+	if ti.Cursor == 0 {
+		return 0
+	}
+
+	position := ti.Cursor - 1
+	if !wordWise {
+		return position
+	}
+
+	for position > 0 && isWhitespace(ti.Text[position]) {
+		position -= 1
+	}
+
+	word := isInputWordCharacter(ti.Text[position])
+	for position > 0 && !isWhitespace(ti.Text[position-1]) && isInputWordCharacter(ti.Text[position-1]) == word {
+		position -= 1
+	}
+
+	return position
+}
+
+func (ti *TextInput) nextPosition(wordWise bool) int {
+	if ti.Cursor >= len(ti.Text) {
+		return len(ti.Text)
+	}
+
+	if !wordWise {
+		return ti.Cursor + 1
+	}
+
+	position := ti.Cursor
+	word := isInputWordCharacter(ti.Text[position])
+	for position < len(ti.Text) && !isWhitespace(ti.Text[position]) && isInputWordCharacter(ti.Text[position]) == word {
+		position += 1
+	}
+
+	for position < len(ti.Text) && isWhitespace(ti.Text[position]) {
+		position += 1
+	}
+
+	return position
+}
+
+// Unlike in the buffer, dots and slashes split words, so paths can be edited a segment at a time
+func isInputWordCharacter(char byte) bool {
+	return isAlpha(char) || char >= '0' && char <= '9' || char == '_'
+}
+
+// recallHistory moves through the history, direction -1 goes to older entries
+func (ti *TextInput) recallHistory(direction int) {
+	index := ti.historyIndex + direction
+	if index < 0 || index > len(ti.History) {
+		return
+	}
+
+	ti.historyIndex = index
+	if index == len(ti.History) {
+		ti.SetText(ti.draft)
+	} else {
+		ti.SetText(ti.History[index])
+	}
+}
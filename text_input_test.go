@@ -0,0 +1,92 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+)
+
+func typeText(ti *TextInput, text string) {
+	for i := 0; i < len(text); i += 1 {
+		ti.Tick(Input{TypedCharacter: text[i]})
+	}
+}
+
+func TestTextInput(t *testing.T) {
+	t.Run("Cursor movement and insertion", func(t *testing.T) {
+		ti := TextInput{}
+		typeText(&ti, "hello world")
+
+		ti.Tick(Input{Left: true, Ctrl: true})
+		FailIfFalse(ti.Cursor == 6, fmt.Sprintf("Expected the cursor at the start of the last word, got %d", ti.Cursor), t)
+
+		typeText(&ti, "big ")
+		FailIfFalse(ti.String() == "hello big world", fmt.Sprintf("Incorrect text %q", ti.String()), t)
+
+		ti.Tick(Input{Home: true})
+		ti.Tick(Input{Right: true})
+		ti.Tick(Input{Delete: true})
+		FailIfFalse(ti.String() == "hllo big world", fmt.Sprintf("Incorrect text after delete %q", ti.String()), t)
+
+		ti.Tick(Input{End: true})
+		FailIfFalse(ti.Cursor == ti.Len(), "Expected the cursor at the end", t)
+	})
+
+	t.Run("Word deletion", func(t *testing.T) {
+		ti := TextInput{}
+		ti.SetText("src/main.go  ")
+
+		ti.Tick(Input{Backspace: true, Ctrl: true})
+		FailIfFalse(ti.String() == "src/main.", fmt.Sprintf("Expected the last word and the spaces to be deleted, got %q", ti.String()), t)
+
+		ti.Tick(Input{TypedCharacter: 'w', Ctrl: true})
+		FailIfFalse(ti.String() == "src/main", fmt.Sprintf("Expected punctuation to be deleted as a word, got %q", ti.String()), t)
+
+		ti.Tick(Input{Home: true})
+		ti.Tick(Input{Delete: true, Ctrl: true})
+		FailIfFalse(ti.String() == "/main", fmt.Sprintf("Expected the first word to be deleted, got %q", ti.String()), t)
+
+		changed := ti.Tick(Input{Backspace: true})
+		FailIfFalse(!changed && ti.String() == "/main", "Backspace at the start should do nothing", t)
+	})
+
+	t.Run("Selection", func(t *testing.T) {
+		ti := TextInput{}
+		ti.SetText("one two three")
+
+		ti.Tick(Input{Left: true, Ctrl: true, Shift: true})
+		ti.Tick(Input{Left: true, Ctrl: true, Shift: true})
+		FailIfFalse(ti.SelectedText() == "two three", fmt.Sprintf("Incorrect selection %q", ti.SelectedText()), t)
+
+		typeText(&ti, "2")
+		FailIfFalse(ti.String() == "one 2" && !ti.HasSelection(), fmt.Sprintf("Typing should replace the selection, got %q", ti.String()), t)
+
+		ti.Tick(Input{TypedCharacter: 'a', Ctrl: true})
+		ti.InsertText("pasted\nline")
+		FailIfFalse(ti.String() == "pasted line", fmt.Sprintf("Pasting should replace everything on one line, got %q", ti.String()), t)
+
+		ti.Tick(Input{Left: true, Shift: true})
+		ti.Tick(Input{Left: true})
+		FailIfFalse(!ti.HasSelection() && ti.Cursor == ti.Len()-1, "Moving without shift should collapse the selection", t)
+	})
+
+	t.Run("History", func(t *testing.T) {
+		ti := TextInput{}
+		ti.AddHistory("first")
+		ti.AddHistory("second")
+		ti.AddHistory("first")
+		FailIfFalse(linesEqual(ti.History, []string{"second", "first"}), fmt.Sprintf("Expected duplicates to move to the end, got %v", ti.History), t)
+
+		ti.Reset()
+		typeText(&ti, "draft")
+
+		ti.Tick(Input{Up: true})
+		FailIfFalse(ti.String() == "first", fmt.Sprintf("Expected the newest entry, got %q", ti.String()), t)
+		ti.Tick(Input{Up: true})
+		ti.Tick(Input{Up: true})
+		FailIfFalse(ti.String() == "second", fmt.Sprintf("Expected to stop at the oldest entry, got %q", ti.String()), t)
+
+		ti.Tick(Input{Down: true})
+		ti.Tick(Input{Down: true})
+		FailIfFalse(ti.String() == "draft", fmt.Sprintf("Expected the draft back, got %q", ti.String()), t)
+	})
+}
@@ -1,12 +1,22 @@
 package main
 
 import (
+	_ "embed"
+	"fmt"
 	"log"
+	"os"
+	"path/filepath"
+	"sort"
 	"strings"
 
 	"github.com/veandco/go-sdl2/sdl"
 )
 
+// The default theme is built in as well, so the editor has colors when the themes folder is missing
+//
+//go:embed themes/default.atheme
+var defaultThemeData []byte
+
 type StatusBarTheme struct {
 	BackgroundColor sdl.Color
 
@@ -40,6 +50,8 @@ type GutterTheme struct {
 	LineNumberInactiveColor  sdl.Color
 	LineNumberActiveColor    sdl.Color
 	LineNumberMatchModeColor bool
+
+	MarkColor sdl.Color
 }
 
 type FileSearchTheme struct {
@@ -47,6 +59,7 @@ type FileSearchTheme struct {
 	BorderColor          sdl.Color
 	CursorColor          sdl.Color
 	InputTextColor       sdl.Color
+	InputSelectionColor  sdl.Color
 
 	ResultBackgroundColor sdl.Color
 	ResultActiveColor     sdl.Color
@@ -54,6 +67,7 @@ type FileSearchTheme struct {
 	ResultNameActiveColor sdl.Color
 	ResultPathColor       sdl.Color
 	ResultPathActiveColor sdl.Color
+	ResultMatchColor      sdl.Color
 }
 
 type SyntaxTheme struct {
@@ -65,45 +79,233 @@ type SyntaxTheme struct {
 	CommentColor  sdl.Color
 }
 
+type DiffTheme struct {
+	InsertColor  sdl.Color
+	DeleteColor  sdl.Color
+	ContextColor sdl.Color
+}
+
 type Theme struct {
+	Files []string // The file of the theme and the files of its bases, saving one of them reloads the theme
+
 	StatusBar  StatusBarTheme
 	Buffer     BufferTheme
 	Gutter     GutterTheme
 	FileSearch FileSearchTheme
 	Syntax     SyntaxTheme
+	Diff       DiffTheme
 }
 
-func ParseTheme(path string) (result Theme) {
-	data, _, success := OpenFile(path)
-	if !success {
-		return
+// Theme files consist of "key value" lines. Lines starting with # are comments. A theme can start from another theme
+// with base and change only some of its colors.
+//
+//	base default                          name of a theme in the themes folders or a path
+//	buffer_bg_color #141518
+//	buffer_selection_color #1d374c80      the last two digits are the opacity
+func ParseTheme(path string) (result Theme, errors []ParseError) {
+	result, errors, _ = parseTheme(path, nil)
+	return
+}
+
+// DefaultTheme returns the default theme that is built into the editor
+func DefaultTheme() (result Theme) {
+	result, _ = parseThemeData(defaultThemeData, "", nil)
+	result.Files = nil
+
+	return
+}
+
+// parseTheme reads the theme, bases are the files of the themes that are being read and are based on this one. ok is
+// false when the file can't be read
+func parseTheme(path string, bases []string) (result Theme, errors []ParseError, ok bool) {
+	// Saved files are compared with the files of the theme, so the files are kept the same way every time
+	if absolute, err := filepath.Abs(path); err == nil && path != "" {
+		path = absolute
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		result.Files = []string{path}
+		return result, []ParseError{{Line: 1, Message: fmt.Sprintf("could not read the theme \"%s\"", path)}}, false
 	}
 
-	split := strings.Split(string(data), "\n")
+	result, errors = parseThemeData(data, path, bases)
+	return result, errors, true
+}
+
+func parseThemeData(data []byte, path string, bases []string) (result Theme, errors []ParseError) {
+	result.Files = []string{path}
+
+	hasColors := false
+	for index, line := range strings.Split(string(data), "\n") {
+		lineNumber := index + 1
 
-	for _, line := range split {
 		l := strings.TrimSpace(line)
-		if l == "" {
+		if l == "" || strings.HasPrefix(l, "#") {
+			continue
+		}
+
+		key, value, ok := getKeyValue(l, " ")
+		if !ok {
+			errors = append(errors, ParseError{Line: lineNumber, Message: fmt.Sprintf("expected \"key value\", got \"%s\"", l)})
+			continue
+		}
+
+		if key == "base" {
+			if hasColors {
+				errors = append(errors, ParseError{Line: lineNumber, Message: "base has to come before the colors"})
+				continue
+			}
+
+			basePath, found := findBaseTheme(value, path)
+			if !found {
+				errors = append(errors, ParseError{Line: lineNumber, Message: fmt.Sprintf("theme \"%s\" was not found", value)})
+				continue
+			}
+
+			if basePath == path || isStringInArray(bases, basePath) {
+				errors = append(errors, ParseError{Line: lineNumber, Message: fmt.Sprintf("theme \"%s\" is based on this theme", value)})
+				continue
+			}
+
+			base, baseErrors, _ := parseTheme(basePath, append(bases, path))
+			for _, err := range baseErrors {
+				errors = append(errors, ParseError{Line: lineNumber, Message: fmt.Sprintf("%s %s", GetFileNameFromPath(basePath), err.Error())})
+			}
+
+			result = base
+			result.Files = append([]string{path}, base.Files...)
 			continue
 		}
 
-		key, value := getKeyValue(l, " ")
-		if strings.HasPrefix(key, "statusbar") {
-			parseStatusBar(key, value, &result.StatusBar)
-		} else if strings.HasPrefix(key, "buffer") {
-			parseBuffer(key, value, &result.Buffer)
-		} else if strings.HasPrefix(key, "gutter") {
-			parseGutter(key, value, &result.Gutter)
-		} else if strings.HasPrefix(key, "fs") {
-			parseFileSearch(key, value, &result.FileSearch)
-		} else if strings.HasPrefix(key, "syntax") {
-			parseSyntax(key, value, &result.Syntax)
+		hasColors = true
+
+		var err error
+		switch {
+		case strings.HasPrefix(key, "statusbar"):
+			err = parseStatusBar(key, value, &result.StatusBar)
+		case strings.HasPrefix(key, "buffer"):
+			err = parseBuffer(key, value, &result.Buffer)
+		case strings.HasPrefix(key, "gutter"):
+			err = parseGutter(key, value, &result.Gutter)
+		case strings.HasPrefix(key, "fs"):
+			err = parseFileSearch(key, value, &result.FileSearch)
+		case strings.HasPrefix(key, "syntax"):
+			err = parseSyntax(key, value, &result.Syntax)
+		case strings.HasPrefix(key, "diff"):
+			err = parseDiff(key, value, &result.Diff)
+		default:
+			err = fmt.Errorf("unknown key \"%s\"", key)
+		}
+
+		if err != nil {
+			errors = append(errors, ParseError{Line: lineNumber, Message: err.Error()})
+		}
+	}
+
+	return
+}
+
+// FindTheme returns the file of the theme. Names are looked up in the themes folder of the user config directory and
+// then in the one next to the executable
+func FindTheme(theme string) (string, bool) {
+	if isThemePath(theme) {
+		path := ResolveAssetPath(theme)
+		return path, fileExists(path)
+	}
+
+	for _, dir := range themeDirectories() {
+		path := filepath.Join(dir, theme+".atheme")
+		if fileExists(path) {
+			return path, true
+		}
+	}
+
+	return "", false
+}
+
+// ThemeEntries lists the themes in the themes folders. Themes of the user hide the ones with the same name that come
+// with the editor
+func ThemeEntries() (result []FileSearchEntry) {
+	found := map[string]bool{}
+	for _, dir := range themeDirectories() {
+		paths, _ := filepath.Glob(filepath.Join(dir, "*.atheme"))
+		for _, path := range paths {
+			name := strings.TrimSuffix(filepath.Base(path), ".atheme")
+			if found[name] {
+				continue
+			}
+
+			found[name] = true
+			result = append(result, FileSearchEntry{Name: name, FullPath: path, MatchPath: name})
 		}
 	}
 
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+
 	return
 }
 
+// findBaseTheme finds the base of the theme at the path. Paths are relative to the theme that has the base. A theme
+// that is named like its base, like a default.atheme of the user based on default, gets the one from the next themes
+// folder
+func findBaseTheme(base string, path string) (string, bool) {
+	if isThemePath(base) && !filepath.IsAbs(base) {
+		basePath := filepath.Join(filepath.Dir(path), base)
+		return basePath, fileExists(basePath)
+	}
+
+	if isThemePath(base) {
+		return FindTheme(base)
+	}
+
+	info, err := os.Stat(path)
+	for _, dir := range themeDirectories() {
+		basePath := filepath.Join(dir, base+".atheme")
+		baseInfo, baseErr := os.Stat(basePath)
+		if baseErr != nil || baseInfo.IsDir() || (err == nil && os.SameFile(info, baseInfo)) {
+			continue
+		}
+
+		return basePath, true
+	}
+
+	return "", false
+}
+
+func isThemePath(theme string) bool {
+	return strings.HasSuffix(theme, ".atheme") || strings.ContainsAny(theme, "/\\")
+}
+
+func themeDirectories() (result []string) {
+	configDir, err := os.UserConfigDir()
+	if err == nil {
+		result = append(result, filepath.Join(configDir, "agurkas", "themes"))
+	}
+
+	return append(result, ResolveAssetPath("./themes"))
+}
+
+func setColor(color *sdl.Color, value string) error {
+	parsed, ok := ParseColor(value)
+	if !ok {
+		return fmt.Errorf("invalid color \"%s\", expected #rrggbb or #rrggbbaa", value)
+	}
+
+	*color = parsed
+	return nil
+}
+
+func setFlag(flag *bool, value string) error {
+	parsed, ok := parseOnOff([]string{value})
+	if !ok {
+		return fmt.Errorf("expected true or false, got \"%s\"", value)
+	}
+
+	*flag = parsed
+	return nil
+}
+
 func (theme *Theme) GetColorForMode(mode Mode) sdl.Color {
 	switch mode {
 	case Mode_Normal:
@@ -149,113 +351,185 @@ func (theme *StatusBarTheme) GetTextColorForMode(mode Mode) sdl.Color {
 	return sdl.Color{}
 }
 
-func parseStatusBar(key string, value string, theme *StatusBarTheme) {
+func parseStatusBar(key string, value string, theme *StatusBarTheme) error {
 	switch key {
 	case "statusbar_bg_color":
-		theme.BackgroundColor = hexStringToColor(value)
+		return setColor(&theme.BackgroundColor, value)
 	case "statusbar_normal_color":
-		theme.NormalColor = hexStringToColor(value)
+		return setColor(&theme.NormalColor, value)
 	case "statusbar_normal_txt_color":
-		theme.NormalTextColor = hexStringToColor(value)
+		return setColor(&theme.NormalTextColor, value)
 	case "statusbar_insert_color":
-		theme.InsertColor = hexStringToColor(value)
+		return setColor(&theme.InsertColor, value)
 	case "statusbar_insert_txt_color":
-		theme.InsertTextColor = hexStringToColor(value)
+		return setColor(&theme.InsertTextColor, value)
 	case "statusbar_visual_color":
-		theme.VisualColor = hexStringToColor(value)
+		return setColor(&theme.VisualColor, value)
 	case "statusbar_visual_txt_color":
-		theme.VisualTextColor = hexStringToColor(value)
+		return setColor(&theme.VisualTextColor, value)
 	case "statusbar_vline_color":
-		theme.VisualLineColor = hexStringToColor(value)
+		return setColor(&theme.VisualLineColor, value)
 	case "statusbar_vline_txt_color":
-		theme.VisualLineTextColor = hexStringToColor(value)
+		return setColor(&theme.VisualLineTextColor, value)
 	case "statusbar_txt_color":
-		theme.TextColor = hexStringToColor(value)
+		return setColor(&theme.TextColor, value)
 	case "statusbar_dirty_color":
-		theme.DirtyColor = hexStringToColor(value)
+		return setColor(&theme.DirtyColor, value)
 	default:
-		log.Printf("Unsupported property for status bar theme: %s = %s", key, value)
+		return fmt.Errorf("unknown key \"%s\"", key)
 	}
 }
 
-func parseBuffer(key string, value string, theme *BufferTheme) {
+func parseBuffer(key string, value string, theme *BufferTheme) error {
 	switch key {
 	case "buffer_bg_color":
-		theme.BackgroundColor = hexStringToColor(value)
+		return setColor(&theme.BackgroundColor, value)
 	case "buffer_line_highlight_color":
-		theme.LineHighlightColor = hexStringToColor(value)
+		return setColor(&theme.LineHighlightColor, value)
 	case "buffer_selection_color":
-		theme.SelectionColor = hexStringToColor(value)
+		return setColor(&theme.SelectionColor, value)
 	case "buffer_txt_color":
-		theme.TextColor = hexStringToColor(value)
+		return setColor(&theme.TextColor, value)
 	case "buffer_cursor_color_match_mode":
-		theme.CursorColorMatchModeColor = stringToBool(value)
+		return setFlag(&theme.CursorColorMatchModeColor, value)
 	case "buffer_cursor_color":
-		theme.CursorColor = hexStringToColor(value)
+		return setColor(&theme.CursorColor, value)
 	default:
-		log.Printf("Unsupported property for buffer theme: %s = %s", key, value)
+		return fmt.Errorf("unknown key \"%s\"", key)
 	}
 }
 
-func parseGutter(key string, value string, theme *GutterTheme) {
+func parseGutter(key string, value string, theme *GutterTheme) error {
 	switch key {
 	case "gutter_bg_color":
-		theme.BackgroundColor = hexStringToColor(value)
+		return setColor(&theme.BackgroundColor, value)
 	case "gutter_line_highlight_color":
-		theme.LineHighlightColor = hexStringToColor(value)
+		return setColor(&theme.LineHighlightColor, value)
 	case "gutter_line_number_inactive_color":
-		theme.LineNumberInactiveColor = hexStringToColor(value)
+		return setColor(&theme.LineNumberInactiveColor, value)
 	case "gutter_line_number_color_match_mode":
-		theme.LineNumberMatchModeColor = stringToBool(value)
+		return setFlag(&theme.LineNumberMatchModeColor, value)
 	case "gutter_line_number_active_color":
-		theme.LineNumberActiveColor = hexStringToColor(value)
+		return setColor(&theme.LineNumberActiveColor, value)
+	case "gutter_mark_color":
+		return setColor(&theme.MarkColor, value)
 	default:
-		log.Printf("Unsupported property for gutter theme: %s = %s", key, value)
+		return fmt.Errorf("unknown key \"%s\"", key)
 	}
 }
 
-func parseFileSearch(key string, value string, theme *FileSearchTheme) {
+func parseFileSearch(key string, value string, theme *FileSearchTheme) error {
 	switch key {
 	case "fs_input_bg_color":
-		theme.InputBackgroundColor = hexStringToColor(value)
+		return setColor(&theme.InputBackgroundColor, value)
 	case "fs_border_color":
-		theme.BorderColor = hexStringToColor(value)
+		return setColor(&theme.BorderColor, value)
 	case "fs_input_txt_color":
-		theme.InputTextColor = hexStringToColor(value)
+		return setColor(&theme.InputTextColor, value)
+	case "fs_input_selection_color":
+		return setColor(&theme.InputSelectionColor, value)
 	case "fs_cursor_color":
-		theme.CursorColor = hexStringToColor(value)
+		return setColor(&theme.CursorColor, value)
 	case "fs_result_bg_color":
-		theme.ResultBackgroundColor = hexStringToColor(value)
+		return setColor(&theme.ResultBackgroundColor, value)
 	case "fs_result_active_bg_color":
-		theme.ResultActiveColor = hexStringToColor(value)
+		return setColor(&theme.ResultActiveColor, value)
 	case "fs_result_name_color":
-		theme.ResultNameColor = hexStringToColor(value)
+		return setColor(&theme.ResultNameColor, value)
 	case "fs_result_name_active_color":
-		theme.ResultNameActiveColor = hexStringToColor(value)
+		return setColor(&theme.ResultNameActiveColor, value)
 	case "fs_result_path_color":
-		theme.ResultPathColor = hexStringToColor(value)
+		return setColor(&theme.ResultPathColor, value)
 	case "fs_result_path_active_color":
-		theme.ResultPathActiveColor = hexStringToColor(value)
+		return setColor(&theme.ResultPathActiveColor, value)
+	case "fs_result_match_color":
+		return setColor(&theme.ResultMatchColor, value)
 	default:
-		log.Printf("Unsupported property for filesearch theme: %s = %s", key, value)
+		return fmt.Errorf("unknown key \"%s\"", key)
 	}
 }
 
-func parseSyntax(key string, value string, theme *SyntaxTheme) {
+func parseSyntax(key string, value string, theme *SyntaxTheme) error {
 	switch key {
 	case "syntax_base_color":
-		theme.BaseColor = hexStringToColor(value)
+		return setColor(&theme.BaseColor, value)
 	case "syntax_keyword_color":
-		theme.KeywordColor = hexStringToColor(value)
+		return setColor(&theme.KeywordColor, value)
 	case "syntax_type_color":
-		theme.TypeColor = hexStringToColor(value)
+		return setColor(&theme.TypeColor, value)
 	case "syntax_operator_color":
-		theme.OperatorColor = hexStringToColor(value)
+		return setColor(&theme.OperatorColor, value)
 	case "syntax_string_color":
-		theme.StringColor = hexStringToColor(value)
+		return setColor(&theme.StringColor, value)
 	case "syntax_comment_color":
-		theme.CommentColor = hexStringToColor(value)
+		return setColor(&theme.CommentColor, value)
+	default:
+		return fmt.Errorf("unknown key \"%s\"", key)
+	}
+}
+
+func parseDiff(key string, value string, theme *DiffTheme) error {
+	switch key {
+	case "diff_insert_color":
+		return setColor(&theme.InsertColor, value)
+	case "diff_delete_color":
+		return setColor(&theme.DeleteColor, value)
+	case "diff_context_color":
+		return setColor(&theme.ContextColor, value)
 	default:
-		log.Printf("Unsupported property for syntax theme: %s = %s", key, value)
+		return fmt.Errorf("unknown key \"%s\"", key)
+	}
+}
+
+// loadTheme reads the theme of the settings, every color of the editor comes from it. A theme that can't be read keeps
+// the current theme, or the default theme that is built in when there is none yet
+func (app *App) loadTheme() (path string, errors []ParseError) {
+	path, found := FindTheme(app.Settings.Theme)
+
+	ok := false
+	if found {
+		var theme Theme
+		theme, errors, ok = parseTheme(path, nil)
+		if ok {
+			app.Theme = theme
+		}
+	} else {
+		log.Printf("theme \"%s\" was not found", app.Settings.Theme)
+		path = ""
+	}
+
+	if !ok && len(app.Theme.Files) == 0 {
+		app.Theme = DefaultTheme()
+	}
+
+	app.updateCursorColor()
+	app.Damaged = true
+
+	return
+}
+
+// reloadTheme reads the theme again, it runs when the theme or one of its bases is saved in the editor
+func (app *App) reloadTheme() {
+	path, errors := app.loadTheme()
+	if len(errors) > 0 {
+		app.reportParseErrors(path, errors)
+	}
+}
+
+// setTheme switches the theme until the editor is closed, the settings file keeps the theme for the next time
+func (app *App) setTheme(theme string) {
+	settings := app.UserSettings
+	err := settings.Set("theme", theme)
+	if err != nil {
+		log.Printf("%s", err)
+		return
 	}
+
+	app.UserSettings = settings
+	app.updateSettings()
+}
+
+func (app *App) isThemeFile(path string) bool {
+	absolute, err := filepath.Abs(path)
+	return err == nil && isStringInArray(app.Theme.Files, absolute)
 }
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+func writeTheme(t *testing.T, dir string, name string, lines ...string) string {
+	path := filepath.Join(dir, name)
+	FailNowIfFalse(os.WriteFile(path, []byte(strings.Join(lines, "\n")), 0644) == nil, fmt.Sprintf("Could not write %s", name), t)
+
+	return path
+}
+
+func TestParseColor(t *testing.T) {
+	tests := []struct {
+		Value string
+		Color sdl.Color
+		Ok    bool
+	}{
+		{"#5aa9e6", sdl.Color{R: 90, G: 169, B: 230, A: 255}, true},
+		{"#5AA9E680", sdl.Color{R: 90, G: 169, B: 230, A: 128}, true},
+		{"#fff", sdl.Color{}, false},
+		{"5aa9e6", sdl.Color{}, false},
+		{"#5aa9eg", sdl.Color{}, false},
+		{"#5aa9e68", sdl.Color{}, false},
+		{"", sdl.Color{}, false},
+	}
+
+	for _, test := range tests {
+		color, ok := ParseColor(test.Value)
+		FailIfFalse(ok == test.Ok && color == test.Color, fmt.Sprintf("%q: expected %v %v, got %v %v", test.Value, test.Color, test.Ok, color, ok), t)
+	}
+}
+
+func TestParseTheme(t *testing.T) {
+	t.Run("Errors have line numbers", func(t *testing.T) {
+		path := writeTheme(t, t.TempDir(), "broken.atheme",
+			"# comment",
+			"buffer_bg_color #141518",
+			"buffer_txt_color #fff",
+			"buffer_cursor_color_match_mode maybe",
+			"buffer_nothing #ffffff",
+			"colors",
+			"base default",
+			"window_bg_color #000000",
+		)
+
+		theme, errors := ParseTheme(path)
+		lines := []int{}
+		for _, err := range errors {
+			lines = append(lines, err.Line)
+		}
+		FailIfFalse(fmt.Sprint(lines) == "[3 4 5 6 7 8]", fmt.Sprintf("Expected errors on lines 3 to 8, got %v", errors), t)
+		FailIfFalse(theme.Buffer.BackgroundColor == sdl.Color{R: 20, G: 21, B: 24, A: 255}, "Expected the valid lines to be read", t)
+	})
+
+	t.Run("Base", func(t *testing.T) {
+		dir := t.TempDir()
+		writeTheme(t, dir, "dark.atheme", "buffer_bg_color #000000", "buffer_txt_color #ffffff")
+		path := writeTheme(t, dir, "dim.atheme", "base dark.atheme", "buffer_txt_color #80808080")
+
+		theme, errors := ParseTheme(path)
+		FailIfFalse(len(errors) == 0, fmt.Sprintf("Expected no errors, got %v", errors), t)
+		FailIfFalse(theme.Buffer.BackgroundColor == sdl.Color{A: 255}, "Expected the color of the base", t)
+		FailIfFalse(theme.Buffer.TextColor == sdl.Color{R: 128, G: 128, B: 128, A: 128}, "Expected the color of the theme", t)
+		FailIfFalse(len(theme.Files) == 2 && strings.HasSuffix(theme.Files[1], "dark.atheme"), fmt.Sprintf("Expected both files, got %v", theme.Files), t)
+	})
+
+	t.Run("Theme of the user based on the one it hides", func(t *testing.T) {
+		if runtime.GOOS != "linux" {
+			t.Skip("The config folder is only moved with XDG_CONFIG_HOME on linux")
+		}
+
+		config := t.TempDir()
+		t.Setenv("XDG_CONFIG_HOME", config)
+		dir := filepath.Join(config, "agurkas", "themes")
+		FailNowIfFalse(os.MkdirAll(dir, 0755) == nil, "Could not create the themes folder", t)
+		path := writeTheme(t, dir, "default.atheme", "base default", "buffer_txt_color #80808080")
+
+		found, ok := FindTheme("default")
+		FailIfFalse(ok && found == path, fmt.Sprintf("Expected the theme of the user, got %q", found), t)
+
+		theme, errors := ParseTheme(path)
+		FailIfFalse(len(errors) == 0, fmt.Sprintf("Expected no errors, got %v", errors), t)
+		FailIfFalse(len(theme.Files) == 2 && theme.Files[1] != path, fmt.Sprintf("Expected the default theme as the base, got %v", theme.Files), t)
+		FailIfFalse(theme.Buffer.BackgroundColor == DefaultTheme().Buffer.BackgroundColor, "Expected the colors of the default theme", t)
+		FailIfFalse(theme.Buffer.TextColor == sdl.Color{R: 128, G: 128, B: 128, A: 128}, "Expected the color of the theme", t)
+	})
+
+	t.Run("Errors in the base are reported on the base line", func(t *testing.T) {
+		dir := t.TempDir()
+		writeTheme(t, dir, "a.atheme", "base b.atheme")
+		writeTheme(t, dir, "b.atheme", "base a.atheme")
+		path := writeTheme(t, dir, "c.atheme", "", "base a.atheme", "base missing")
+
+		_, errors := ParseTheme(path)
+		FailNowIfFalse(len(errors) == 2, fmt.Sprintf("Expected 2 errors, got %v", errors), t)
+		FailIfFalse(errors[0].Line == 2 && strings.Contains(errors[0].Message, "based on this theme"), fmt.Sprintf("Expected the loop on line 2, got %v", errors[0]), t)
+		FailIfFalse(errors[1].Line == 3 && strings.Contains(errors[1].Message, "not found"), fmt.Sprintf("Expected the missing base on line 3, got %v", errors[1]), t)
+	})
+
+	t.Run("Built in default", func(t *testing.T) {
+		theme, _ := ParseTheme("./themes/default.atheme")
+		builtIn := DefaultTheme()
+		same := builtIn.StatusBar == theme.StatusBar && builtIn.Buffer == theme.Buffer && builtIn.Gutter == theme.Gutter &&
+			builtIn.FileSearch == theme.FileSearch && builtIn.Syntax == theme.Syntax && builtIn.Diff == theme.Diff
+		FailIfFalse(same, "Expected the built in theme to be the default theme", t)
+	})
+
+	t.Run("Themes that come with the editor", func(t *testing.T) {
+		entries := ThemeEntries()
+		FailIfFalse(len(entries) >= 2, "Expected the default and light themes", t)
+
+		for _, entry := range entries {
+			_, errors := ParseTheme(entry.FullPath)
+			FailIfFalse(len(errors) == 0, fmt.Sprintf("Expected no errors in %s, got %v", entry.Name, errors), t)
+		}
+	})
+}
+
+func TestTheme(t *testing.T) {
+	t.Run("Switching themes", func(t *testing.T) {
+		app := createSettingsTestApp(t, "text")
+		light, _ := ParseTheme("./themes/light.atheme")
+
+		app.runCommand("theme light")
+		FailIfFalse(app.Settings.Theme == "light", "Expected the light theme to be set", t)
+		FailIfFalse(app.Theme.Buffer.BackgroundColor == light.Buffer.BackgroundColor, "Expected the colors of the light theme", t)
+
+		app.runCommand("theme nothing")
+		FailIfFalse(app.Settings.Theme == "light", "An unknown theme should not be set", t)
+	})
+
+	t.Run("Saving the theme reloads it", func(t *testing.T) {
+		app := createSettingsTestApp(t, "")
+		dir := t.TempDir()
+		writeTheme(t, dir, "base.atheme", "buffer_bg_color #000000")
+		path := writeTheme(t, dir, "mine.atheme", "base base.atheme")
+		app.setTheme(path)
+		FailNowIfFalse(app.Theme.Buffer.BackgroundColor == sdl.Color{A: 255}, "Expected the color of the base", t)
+
+		app.openSourceFile(filepath.Join(dir, "base.atheme"))
+		typeKeys(&app, "A80<Esc>")
+		app.saveSourceFile()
+		FailIfFalse(app.Theme.Buffer.BackgroundColor == sdl.Color{A: 128}, fmt.Sprintf("Expected the saved color, got %v", app.Theme.Buffer.BackgroundColor), t)
+	})
+
+	t.Run("A theme that can't be read keeps the current one", func(t *testing.T) {
+		app := createSettingsTestApp(t, "")
+		app.Theme = Theme{}
+		app.Settings.Theme = "nothing"
+		app.loadTheme()
+		FailIfFalse(app.Theme.Buffer.BackgroundColor == DefaultTheme().Buffer.BackgroundColor, "Expected the built in theme without a theme", t)
+
+		path := writeTheme(t, t.TempDir(), "mine.atheme", "buffer_bg_color #000000")
+		app.setTheme(path)
+		FailNowIfFalse(app.Theme.Buffer.BackgroundColor == sdl.Color{A: 255}, "Expected the color of the theme", t)
+
+		FailNowIfFalse(os.Remove(path) == nil, "Could not remove the theme", t)
+		app.reloadTheme()
+		FailIfFalse(app.Theme.Buffer.BackgroundColor == sdl.Color{A: 255}, "Expected the theme to be kept", t)
+		FailIfFalse(!app.PromptOpen, "A missing theme has no file to edit", t)
+	})
+
+	t.Run("Errors without a file can't be edited", func(t *testing.T) {
+		app := createSettingsTestApp(t, "")
+		app.reportParseErrors("", []ParseError{{Line: 1, Message: "broken"}})
+		FailNowIfFalse(app.PromptOpen, "Expected the errors in a prompt", t)
+		for _, choice := range app.Prompt.Choices {
+			FailIfFalse(choice.Key != 'e', "Expected no choice to edit the file", t)
+		}
+	})
+
+	t.Run("Line highlight comes from the theme", func(t *testing.T) {
+		app := createRenderTestApp("one\ntwo", 400, 200)
+		app.Theme.Buffer.LineHighlightColor = sdl.Color{R: 1, G: 2, B: 3, A: 255}
+
+		canvas := CreateSoftwareCanvas(400, 200)
+		app.Render(canvas)
+
+		found := false
+		for _, command := range canvas.Commands {
+			found = found || (command.Type == DrawCommand_Rect && command.Color == app.Theme.Buffer.LineHighlightColor)
+		}
+		FailIfFalse(found, "Expected the line to be highlighted with the color of the theme", t)
+	})
+}
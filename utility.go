@@ -1,12 +1,22 @@
 package main
 
 import (
+	"fmt"
 	"log"
-	"strings"
 
 	"github.com/veandco/go-sdl2/sdl"
 )
 
+// ParseError describes a problem found while reading one of the editor's own file formats
+type ParseError struct {
+	Line    int
+	Message string
+}
+
+func (err ParseError) Error() string {
+	return fmt.Sprintf("line %d: %s", err.Line, err.Message)
+}
+
 func checkError(err error) {
 	if err != nil {
 		log.Fatal(err)
@@ -22,17 +32,6 @@ func expandRect(rect sdl.Rect, amount int32) sdl.Rect {
 	}
 }
 
-func cleanString(text string) string {
-	var sb strings.Builder
-	for _, symbol := range text {
-		if isAlpha(byte(symbol)) {
-			sb.WriteByte(byte(symbol))
-		}
-	}
-
-	return strings.ToLower(sb.String())
-}
-
 func isAlpha(char byte) bool {
 	return char >= 'a' && char <= 'z' || char >= 'A' && char <= 'Z'
 }
@@ -104,17 +103,31 @@ func findSubstrAll(text string, substr string) (result []int) {
 	return
 }
 
-func hexStringToColor(color string) (result sdl.Color) {
+// ParseColor reads colors written as #rrggbb, or #rrggbbaa with the opacity in the last two digits
+func ParseColor(value string) (result sdl.Color, ok bool) {
+	if len(value) != 7 && len(value) != 9 || value[0] != '#' {
+		return result, false
+	}
+
+	for index := 1; index < len(value); index += 1 {
+		if !isHexDigit(value[index]) {
+			return result, false
+		}
+	}
+
+	result.R = hexToByte(value[1])<<4 + hexToByte(value[2])
+	result.G = hexToByte(value[3])<<4 + hexToByte(value[4])
+	result.B = hexToByte(value[5])<<4 + hexToByte(value[6])
 	result.A = 255
-	result.R = hexToByte(color[1])<<4 + hexToByte(color[2])
-	result.G = hexToByte(color[3])<<4 + hexToByte(color[4])
-	result.B = hexToByte(color[5])<<4 + hexToByte(color[6])
+	if len(value) == 9 {
+		result.A = hexToByte(value[7])<<4 + hexToByte(value[8])
+	}
 
-	return
+	return result, true
 }
 
-func stringToBool(str string) bool {
-	return str == "true"
+func isHexDigit(char byte) bool {
+	return (char >= '0' && char <= '9') || (char >= 'a' && char <= 'f') || (char >= 'A' && char <= 'F')
 }
 
 func hexToByte(b byte) byte {
@@ -0,0 +1,157 @@
+package main
+
+// The view of the buffer is measured in display rows and columns. ScrollY and ScrollX keep where it starts in pixels, so
+// the rest of the editor and the session can stay in pixels
+
+// ClampScroll returns the first row or column of a view size long, moved as little as possible so the cursor is at least
+// offset away from both ends of it. The view never starts before 0
+func ClampScroll(first int32, cursor int32, size int32, offset int32) int32 {
+	offset = clampOffset(size, offset)
+	size = int32(Max(int(size), 1))
+
+	if cursor-offset < first {
+		first = cursor - offset
+	} else if cursor+offset > first+size-1 {
+		first = cursor + offset - size + 1
+	}
+
+	return int32(Max(int(first), 0))
+}
+
+// clampOffset makes the offset fit in the view, a view of 5 rows can keep at most 2 of them on both sides of the cursor
+func clampOffset(size int32, offset int32) int32 {
+	return int32(Clamp(int(offset), 0, Max(int(size-1)/2, 0)))
+}
+
+// textColumns returns how many columns fit next to the gutter, with padding on both sides of the text
+func (buffer *Buffer) textColumns() int32 {
+	width := (buffer.Rect.W - buffer.GutterWidth - 10) / int32(buffer.Font.CharacterWidth)
+	return int32(Max(int(width), 1))
+}
+
+func (buffer *Buffer) visibleRows() int32 {
+	return int32(Max(int(buffer.Rect.H/buffer.Cursor.Height), 1))
+}
+
+func (buffer *Buffer) firstRow() int32 {
+	return -buffer.ScrollY / buffer.Cursor.Height
+}
+
+// firstColumn returns the first column in view. Wrapped lines always fit, so they are never scrolled to the side
+func (buffer *Buffer) firstColumn() int32 {
+	if buffer.wrapWidth() > 0 {
+		return 0
+	}
+
+	return -buffer.ScrollX / int32(buffer.Font.CharacterWidth)
+}
+
+func (buffer *Buffer) setFirstRow(row int32) {
+	last := buffer.displayRow(int32(buffer.TotalLines)-1) + int32(len(buffer.lineSegments(int32(buffer.TotalLines)-1))) - 1
+	buffer.ScrollY = -int32(Clamp(int(row), 0, int(last))) * buffer.Cursor.Height
+}
+
+// ScrollToCursor moves the view as little as possible to have the cursor in it, away from the edges by the scroll offsets
+func (buffer *Buffer) ScrollToCursor() {
+	row, column := buffer.cursorDisplay()
+
+	buffer.ScrollY = -ClampScroll(buffer.firstRow(), row, buffer.visibleRows(), buffer.ScrollOffset) * buffer.Cursor.Height
+
+	if buffer.wrapWidth() > 0 {
+		buffer.ScrollX = 0
+	} else {
+		buffer.ScrollX = -ClampScroll(buffer.firstColumn(), column, buffer.textColumns(), buffer.SideScrollOffset) * int32(buffer.Font.CharacterWidth)
+	}
+}
+
+// ScrollRows moves the view by rows without moving the cursor, unless the cursor would end up too close to an edge
+func (buffer *Buffer) ScrollRows(rows int32) {
+	buffer.setFirstRow(buffer.firstRow() + rows)
+	buffer.keepCursorInView()
+}
+
+// keepCursorInView moves the cursor the least it takes to be away from the edges of the view by the scroll offset
+func (buffer *Buffer) keepCursorInView() {
+	first := buffer.firstRow()
+	size := buffer.visibleRows()
+	offset := clampOffset(size, buffer.ScrollOffset)
+
+	row, _ := buffer.cursorDisplay()
+	if row < first+offset {
+		buffer.moveToRow(first + offset)
+	} else if row > first+size-1-offset {
+		buffer.moveToRow(first + size - 1 - offset)
+	}
+}
+
+// ScrollHalfPage moves both the view and the cursor by half of the view
+func (buffer *Buffer) ScrollHalfPage(direction Direction) {
+	amount := int32(Max(int(buffer.visibleRows()/2), 1))
+	if direction == Direction_Up {
+		amount = -amount
+	}
+
+	// Moving the cursor scrolls on its own, so the view is set after it
+	first := buffer.firstRow()
+	if direction == Direction_Up {
+		buffer.MoveUpByLines(int(-amount))
+	} else {
+		buffer.MoveDownByLines(int(amount))
+	}
+
+	buffer.setFirstRow(first + amount)
+	buffer.keepCursorInView()
+}
+
+// ScrollCursorTo puts the row of the cursor at the top, in the middle or at the bottom of the view, like zt, zz and zb
+func (buffer *Buffer) ScrollCursorTo(position byte) {
+	row, _ := buffer.cursorDisplay()
+	size := buffer.visibleRows()
+	offset := clampOffset(size, buffer.ScrollOffset)
+
+	switch position {
+	case 't':
+		buffer.setFirstRow(row - offset)
+	case 'z':
+		buffer.setFirstRow(row - size/2)
+	case 'b':
+		buffer.setFirstRow(row - size + 1 + offset)
+	}
+}
+
+func (buffer *Buffer) moveToRow(row int32) {
+	line, _ := buffer.lineAtRow(row)
+	line = int32(Min(int(line), buffer.TotalLines-1))
+
+	if line != buffer.Cursor.Line {
+		buffer.moveToLine(line)
+	}
+}
+
+// PositionAt returns the line and column of the character drawn at the pixel. Pixels past the end of a row or of the
+// text give the nearest character
+func (buffer *Buffer) PositionAt(x int32, y int32) (line int32, column int32) {
+	row := int32(Max(int((y-buffer.ScrollY)/buffer.Cursor.Height), 0))
+
+	line, lineRow := buffer.lineAtRow(row)
+	if line >= int32(buffer.TotalLines) {
+		line = int32(buffer.TotalLines) - 1
+		lineRow = buffer.displayRow(line)
+		row = lineRow + int32(len(buffer.lineSegments(line))) - 1
+	}
+
+	segments := buffer.lineSegments(line)
+	index := Clamp(int(row-lineRow), 0, len(segments)-1)
+	segment := segments[index]
+
+	last := segment.End
+	if index < len(segments)-1 {
+		last = segment.End - 1 // The end of the segment is already on the next row
+	}
+
+	// Clicks on the gutter land on the first column
+	displayColumn := (x - buffer.GutterWidth - 5) / int32(buffer.Font.CharacterWidth)
+	column = int32(Clamp(int(segment.Start+buffer.firstColumn()+displayColumn-segment.Indent), int(segment.Start), int(last)))
+
+	return
+}
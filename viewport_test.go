@@ -0,0 +1,115 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestClampScroll(t *testing.T) {
+	tests := []struct {
+		Name     string
+		First    int32
+		Cursor   int32
+		Size     int32
+		Offset   int32
+		Expected int32
+	}{
+		{"Cursor in the middle stays", 10, 20, 20, 3, 10},
+		{"Cursor close to the bottom", 10, 28, 20, 3, 12},
+		{"Cursor below the view", 10, 50, 20, 3, 34},
+		{"Cursor close to the top", 10, 11, 20, 3, 8},
+		{"Never before the start", 10, 1, 20, 3, 0},
+		{"Offset larger than half the view", 0, 10, 5, 8, 8},
+		{"No room at all", 3, 7, 0, 2, 7},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			result := ClampScroll(test.First, test.Cursor, test.Size, test.Offset)
+			FailIfFalse(result == test.Expected, fmt.Sprintf("Expected %d, got %d", test.Expected, result), t)
+		})
+	}
+}
+
+func createScrollTestApp() (result App) {
+	lines := []string{}
+	for index := 0; index < 100; index += 1 {
+		lines = append(lines, fmt.Sprintf("line %d", index))
+	}
+
+	result = createTestApp(strings.Join(lines, "\n"))
+	result.Buffer.Rect.H = 20 * result.Buffer.Cursor.Height
+	result.Buffer.ScrollOffset = 3
+
+	return
+}
+
+func TestViewport(t *testing.T) {
+	t.Run("Moving keeps the scroll offset", func(t *testing.T) {
+		app := createScrollTestApp()
+
+		typeKeys(&app, "30G")
+		FailIfFalse(app.Buffer.firstRow() == 13, fmt.Sprintf("Expected the view to start at row 13, got %d", app.Buffer.firstRow()), t)
+
+		typeKeys(&app, "10k")
+		FailIfFalse(app.Buffer.firstRow() == 13, "Moving inside the view should not scroll", t)
+
+		typeKeys(&app, "5k")
+		FailIfFalse(app.Buffer.firstRow() == 11, fmt.Sprintf("Expected the view to start at row 11, got %d", app.Buffer.firstRow()), t)
+	})
+
+	t.Run("zt zz and zb", func(t *testing.T) {
+		app := createScrollTestApp()
+		typeKeys(&app, "50G")
+
+		typeKeys(&app, "zt")
+		FailIfFalse(app.Buffer.firstRow() == 46, fmt.Sprintf("zt: expected row 46, got %d", app.Buffer.firstRow()), t)
+
+		typeKeys(&app, "zz")
+		FailIfFalse(app.Buffer.firstRow() == 39, fmt.Sprintf("zz: expected row 39, got %d", app.Buffer.firstRow()), t)
+
+		typeKeys(&app, "zb")
+		FailIfFalse(app.Buffer.firstRow() == 33, fmt.Sprintf("zb: expected row 33, got %d", app.Buffer.firstRow()), t)
+		FailIfFalse(app.Buffer.Cursor.Line == 49, "Scrolling with z should not move the cursor", t)
+	})
+
+	t.Run("Ctrl+E and Ctrl+Y drag the cursor along", func(t *testing.T) {
+		app := createScrollTestApp()
+
+		typeKeys(&app, "5<C-e>")
+		FailIfFalse(app.Buffer.firstRow() == 5 && app.Buffer.Cursor.Line == 8, fmt.Sprintf("Expected row 5 with the cursor on 8, got %d and %d", app.Buffer.firstRow(), app.Buffer.Cursor.Line), t)
+
+		typeKeys(&app, "<C-y>")
+		FailIfFalse(app.Buffer.firstRow() == 4 && app.Buffer.Cursor.Line == 8, "Ctrl+Y should not move a cursor that is in view", t)
+
+		typeKeys(&app, "10<C-y>")
+		FailIfFalse(app.Buffer.firstRow() == 0, "Ctrl+Y should stop at the start", t)
+	})
+
+	t.Run("Ctrl+D and Ctrl+U move half of the view", func(t *testing.T) {
+		app := createScrollTestApp()
+
+		typeKeys(&app, "<C-d>")
+		FailIfFalse(app.Buffer.firstRow() == 10 && app.Buffer.Cursor.Line == 13, fmt.Sprintf("Expected row 10 with the cursor on 13, got %d and %d", app.Buffer.firstRow(), app.Buffer.Cursor.Line), t)
+
+		typeKeys(&app, "<C-d><C-u>")
+		FailIfFalse(app.Buffer.firstRow() == 10 && app.Buffer.Cursor.Line == 13, "Ctrl+U should undo Ctrl+D", t)
+	})
+
+	t.Run("Long lines scroll sideways", func(t *testing.T) {
+		app := createTestApp(strings.Repeat("abcdefghij", 20) + "\nshort")
+		columns := app.Buffer.textColumns()
+
+		typeKeys(&app, "$")
+		first := app.Buffer.firstColumn()
+		FailIfFalse(first == 200+5-columns+1, fmt.Sprintf("Expected the view to start at column %d, got %d", 200+5-columns+1, first), t)
+
+		typeKeys(&app, "j")
+		FailIfFalse(app.Buffer.firstColumn() == 0, fmt.Sprintf("Expected the view back at column 0, got %d", app.Buffer.firstColumn()), t)
+
+		app.Buffer.Wrap = true
+		typeKeys(&app, "k$")
+		FailIfFalse(app.Buffer.ScrollX == 0, "Wrapped lines should never scroll sideways", t)
+	})
+}
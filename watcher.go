@@ -0,0 +1,213 @@
+package main
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+type WatchEventType uint8
+
+const (
+	WatchEvent_Modified WatchEventType = iota
+	WatchEvent_Created
+	WatchEvent_Removed
+	WatchEvent_Overflow // Events were lost, so any file may have changed. The path is empty
+)
+
+type WatchEvent struct {
+	Path string
+	Type WatchEventType
+}
+
+type FileWatcher interface {
+	// WatchFile reports modifications of a single file, even if it gets replaced by renaming another file over it
+	WatchFile(path string)
+	UnwatchFile(path string)
+	// WatchTree reports files being created and removed anywhere under the root. Only one tree can be watched at a time
+	WatchTree(root string, exclude func(path string) bool)
+	Events() <-chan WatchEvent
+	Close()
+}
+
+// CreateFileWatcher uses the native notification mechanism of the platform if there is one and falls back to polling otherwise
+func CreateFileWatcher() FileWatcher {
+	watcher, err := createNativeWatcher()
+	if err != nil {
+		log.Printf("Native file watching is not available (%s), falling back to polling", err)
+		return createPollingWatcher(2 * time.Second)
+	}
+
+	return watcher
+}
+
+type polledFile struct {
+	ModTime time.Time
+	Size    int64
+	Exists  bool
+}
+
+type pollingWatcher struct {
+	mutex    sync.Mutex
+	events   chan WatchEvent
+	done     chan struct{}
+	interval time.Duration
+
+	files       map[string]polledFile
+	treeRoot    string
+	treeExclude func(path string) bool
+	treeFiles   map[string]bool
+}
+
+func createPollingWatcher(interval time.Duration) *pollingWatcher {
+	result := &pollingWatcher{
+		events:   make(chan WatchEvent, 256),
+		done:     make(chan struct{}),
+		interval: interval,
+		files:    map[string]polledFile{},
+	}
+
+	go result.run()
+
+	return result
+}
+
+func (watcher *pollingWatcher) WatchFile(path string) {
+	watcher.mutex.Lock()
+	defer watcher.mutex.Unlock()
+
+	watcher.files[path] = statPolledFile(path)
+}
+
+func (watcher *pollingWatcher) UnwatchFile(path string) {
+	watcher.mutex.Lock()
+	defer watcher.mutex.Unlock()
+
+	delete(watcher.files, path)
+}
+
+func (watcher *pollingWatcher) WatchTree(root string, exclude func(path string) bool) {
+	files := walkTree(root, exclude)
+
+	watcher.mutex.Lock()
+	defer watcher.mutex.Unlock()
+
+	watcher.treeRoot = root
+	watcher.treeExclude = exclude
+	watcher.treeFiles = files
+}
+
+func (watcher *pollingWatcher) Events() <-chan WatchEvent {
+	return watcher.events
+}
+
+func (watcher *pollingWatcher) Close() {
+	close(watcher.done)
+}
+
+func (watcher *pollingWatcher) run() {
+	ticker := time.NewTicker(watcher.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-watcher.done:
+			return
+		case <-ticker.C:
+			watcher.poll()
+		}
+	}
+}
+
+func (watcher *pollingWatcher) poll() {
+	watcher.mutex.Lock()
+	root := watcher.treeRoot
+	exclude := watcher.treeExclude
+	for path, previous := range watcher.files {
+		current := statPolledFile(path)
+		if current == previous {
+			continue
+		}
+
+		watcher.files[path] = current
+		if !current.Exists {
+			watcher.send(WatchEvent{Path: path, Type: WatchEvent_Removed})
+		} else {
+			watcher.send(WatchEvent{Path: path, Type: WatchEvent_Modified})
+		}
+	}
+	watcher.mutex.Unlock()
+
+	if root == "" {
+		return
+	}
+
+	// Walking the tree can take a while, so it is done without holding the lock
+	files := walkTree(root, exclude)
+
+	watcher.mutex.Lock()
+	defer watcher.mutex.Unlock()
+
+	if watcher.treeRoot != root {
+		return // Tree was changed while walking
+	}
+
+	for path := range files {
+		if !watcher.treeFiles[path] {
+			watcher.send(WatchEvent{Path: path, Type: WatchEvent_Created})
+		}
+	}
+
+	for path := range watcher.treeFiles {
+		if !files[path] {
+			watcher.send(WatchEvent{Path: path, Type: WatchEvent_Removed})
+		}
+	}
+
+	watcher.treeFiles = files
+}
+
+func (watcher *pollingWatcher) send(event WatchEvent) {
+	select {
+	case watcher.events <- event:
+	default:
+		// Nobody is reading the events fast enough, dropping them is better than blocking the watcher
+	}
+}
+
+func statPolledFile(path string) polledFile {
+	info, err := os.Stat(path)
+	if err != nil {
+		return polledFile{}
+	}
+
+	return polledFile{ModTime: info.ModTime(), Size: info.Size(), Exists: true}
+}
+
+func walkTree(root string, exclude func(path string) bool) map[string]bool {
+	result := map[string]bool{}
+
+	filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return nil // Skip anything that cannot be read
+		}
+
+		if path != root && exclude != nil && exclude(path) {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+
+			return nil
+		}
+
+		if !info.IsDir() {
+			result[path] = true
+		}
+
+		return nil
+	})
+
+	return result
+}
@@ -0,0 +1,266 @@
+//go:build linux
+// +build linux
+
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"unsafe"
+)
+
+const (
+	inotifyFileMask = syscall.IN_CLOSE_WRITE | syscall.IN_MOVED_TO | syscall.IN_CREATE | syscall.IN_DELETE | syscall.IN_MOVED_FROM
+	inotifyTreeMask = syscall.IN_CREATE | syscall.IN_DELETE | syscall.IN_MOVED_TO | syscall.IN_MOVED_FROM | syscall.IN_DELETE_SELF
+)
+
+type inotifyWatcher struct {
+	mutex  sync.Mutex
+	file   *os.File
+	fd     int
+	events chan WatchEvent
+
+	// Files are watched through their directories, otherwise renaming a new file over them (atomic save) would lose the watch
+	dirs        map[int]string // Watch descriptor -> directory
+	descriptors map[string]int // Directory -> watch descriptor
+	files       map[string]bool
+	fileDirs    map[string]int // Directory -> number of watched files in it
+
+	treeRoot    string
+	treeExclude func(path string) bool
+	treeDirs    map[string]bool
+}
+
+func createNativeWatcher() (FileWatcher, error) {
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC | syscall.IN_NONBLOCK)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &inotifyWatcher{
+		file:        os.NewFile(uintptr(fd), "inotify"), // Non-blocking descriptor, so reads go through the runtime poller and Close unblocks them
+		fd:          fd,
+		events:      make(chan WatchEvent, 256),
+		dirs:        map[int]string{},
+		descriptors: map[string]int{},
+		files:       map[string]bool{},
+		fileDirs:    map[string]int{},
+		treeDirs:    map[string]bool{},
+	}
+
+	go result.run()
+
+	return result, nil
+}
+
+func (watcher *inotifyWatcher) WatchFile(path string) {
+	watcher.mutex.Lock()
+	defer watcher.mutex.Unlock()
+
+	if watcher.files[path] {
+		return
+	}
+
+	dir := filepath.Dir(path)
+	watcher.files[path] = true
+	watcher.fileDirs[dir] += 1
+	watcher.addWatch(dir)
+}
+
+func (watcher *inotifyWatcher) UnwatchFile(path string) {
+	watcher.mutex.Lock()
+	defer watcher.mutex.Unlock()
+
+	if !watcher.files[path] {
+		return
+	}
+
+	dir := filepath.Dir(path)
+	delete(watcher.files, path)
+	watcher.fileDirs[dir] -= 1
+	if watcher.fileDirs[dir] == 0 {
+		delete(watcher.fileDirs, dir)
+		watcher.maybeRemoveWatch(dir)
+	}
+}
+
+func (watcher *inotifyWatcher) WatchTree(root string, exclude func(path string) bool) {
+	watcher.mutex.Lock()
+	defer watcher.mutex.Unlock()
+
+	oldDirs := watcher.treeDirs
+	watcher.treeRoot = root
+	watcher.treeExclude = exclude
+	watcher.treeDirs = map[string]bool{}
+	for dir := range oldDirs {
+		watcher.maybeRemoveWatch(dir)
+	}
+
+	if root != "" {
+		watcher.addTree(root)
+	}
+}
+
+func (watcher *inotifyWatcher) Events() <-chan WatchEvent {
+	return watcher.events
+}
+
+func (watcher *inotifyWatcher) Close() {
+	watcher.file.Close()
+}
+
+func (watcher *inotifyWatcher) run() {
+	buffer := make([]byte, 64*(syscall.SizeofInotifyEvent+syscall.NAME_MAX+1))
+
+	for {
+		count, err := watcher.file.Read(buffer)
+		if err != nil {
+			return // Watcher was closed
+		}
+
+		offset := 0
+		for offset+syscall.SizeofInotifyEvent <= count {
+			raw := (*syscall.InotifyEvent)(unsafe.Pointer(&buffer[offset]))
+			nameBytes := buffer[offset+syscall.SizeofInotifyEvent : offset+syscall.SizeofInotifyEvent+int(raw.Len)]
+			name := string(bytes.TrimRight(nameBytes, "\x00"))
+
+			watcher.handleEvent(int(raw.Wd), raw.Mask, name)
+
+			offset += syscall.SizeofInotifyEvent + int(raw.Len)
+		}
+	}
+}
+
+func (watcher *inotifyWatcher) handleEvent(wd int, mask uint32, name string) {
+	watcher.mutex.Lock()
+	defer watcher.mutex.Unlock()
+
+	// The kernel queue was full, this event has no watch descriptor and stands for everything that was dropped
+	if mask&syscall.IN_Q_OVERFLOW != 0 {
+		watcher.send(WatchEvent{Type: WatchEvent_Overflow})
+		return
+	}
+
+	dir, ok := watcher.dirs[wd]
+	if !ok {
+		return
+	}
+
+	if mask&syscall.IN_IGNORED != 0 {
+		delete(watcher.dirs, wd)
+		delete(watcher.descriptors, dir)
+		delete(watcher.treeDirs, dir)
+		return
+	}
+
+	if name == "" {
+		return
+	}
+
+	path := filepath.Join(dir, name)
+	isDir := mask&syscall.IN_ISDIR != 0
+	created := mask&(syscall.IN_CREATE|syscall.IN_MOVED_TO) != 0
+	removed := mask&(syscall.IN_DELETE|syscall.IN_MOVED_FROM) != 0
+
+	if watcher.files[path] {
+		if removed {
+			watcher.send(WatchEvent{Path: path, Type: WatchEvent_Removed})
+		} else if mask&(syscall.IN_CLOSE_WRITE|syscall.IN_MOVED_TO) != 0 {
+			watcher.send(WatchEvent{Path: path, Type: WatchEvent_Modified})
+		}
+	}
+
+	if !watcher.treeDirs[dir] || (watcher.treeExclude != nil && watcher.treeExclude(path)) {
+		return
+	}
+
+	if isDir {
+		if created {
+			watcher.addTree(path)
+			for file := range walkTree(path, watcher.treeExclude) {
+				watcher.send(WatchEvent{Path: file, Type: WatchEvent_Created})
+			}
+		} else if removed {
+			// Files inside a removed directory do not get their own events
+			prefix := path + string(filepath.Separator)
+			for treeDir := range watcher.treeDirs {
+				if treeDir == path || strings.HasPrefix(treeDir, prefix) {
+					delete(watcher.treeDirs, treeDir)
+					watcher.maybeRemoveWatch(treeDir)
+				}
+			}
+
+			watcher.send(WatchEvent{Path: path, Type: WatchEvent_Removed})
+		}
+
+		return
+	}
+
+	if created {
+		watcher.send(WatchEvent{Path: path, Type: WatchEvent_Created})
+	} else if removed {
+		watcher.send(WatchEvent{Path: path, Type: WatchEvent_Removed})
+	}
+}
+
+// addTree must be called with the mutex held
+func (watcher *inotifyWatcher) addTree(root string) {
+	filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil || !info.IsDir() {
+			return nil
+		}
+
+		if path != root && watcher.treeExclude != nil && watcher.treeExclude(path) {
+			return filepath.SkipDir
+		}
+
+		// Symlinked directories are reported as files by Walk, so there is no risk of looping forever
+		watcher.treeDirs[path] = true
+		watcher.addWatch(path)
+
+		return nil
+	})
+}
+
+// addWatch must be called with the mutex held
+func (watcher *inotifyWatcher) addWatch(dir string) {
+	if _, ok := watcher.descriptors[dir]; ok {
+		return
+	}
+
+	wd, err := syscall.InotifyAddWatch(watcher.fd, dir, inotifyFileMask|inotifyTreeMask)
+	if err != nil {
+		return // Unreadable directories are simply not watched
+	}
+
+	watcher.dirs[wd] = dir
+	watcher.descriptors[dir] = wd
+}
+
+// maybeRemoveWatch must be called with the mutex held
+func (watcher *inotifyWatcher) maybeRemoveWatch(dir string) {
+	if watcher.fileDirs[dir] > 0 || watcher.treeDirs[dir] {
+		return
+	}
+
+	wd, ok := watcher.descriptors[dir]
+	if !ok {
+		return
+	}
+
+	syscall.InotifyRmWatch(watcher.fd, uint32(wd))
+	delete(watcher.dirs, wd)
+	delete(watcher.descriptors, dir)
+}
+
+func (watcher *inotifyWatcher) send(event WatchEvent) {
+	select {
+	case watcher.events <- event:
+	default:
+		// Nobody is reading the events fast enough, dropping them is better than blocking the watcher
+	}
+}
@@ -0,0 +1,16 @@
+package main
+
+import (
+	"syscall"
+	"testing"
+)
+
+func TestInotifyOverflow(t *testing.T) {
+	watcher, err := createNativeWatcher()
+	FailNowIfFalse(err == nil, "Could not create the watcher", t)
+	defer watcher.Close()
+
+	// Overflows come with no watch descriptor
+	watcher.(*inotifyWatcher).handleEvent(-1, syscall.IN_Q_OVERFLOW, "")
+	expectEvent(t, watcher, "", WatchEvent_Overflow, "Expected an overflow event")
+}
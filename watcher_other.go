@@ -0,0 +1,10 @@
+//go:build !linux
+// +build !linux
+
+package main
+
+import "errors"
+
+func createNativeWatcher() (FileWatcher, error) {
+	return nil, errors.New("not implemented for this platform")
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+// waitForEvent reads events until one for the path of the type arrives and returns the events that came before it
+func waitForEvent(watcher FileWatcher, path string, eventType WatchEventType) (before []WatchEvent, ok bool) {
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case event := <-watcher.Events():
+			if event.Path == path && event.Type == eventType {
+				return before, true
+			}
+			before = append(before, event)
+		case <-timeout:
+			return before, false
+		}
+	}
+}
+
+func expectEvent(t *testing.T, watcher FileWatcher, path string, eventType WatchEventType, message string) []WatchEvent {
+	before, ok := waitForEvent(watcher, path, eventType)
+	FailIfFalse(ok, message, t)
+
+	return before
+}
+
+func writeWatchedFile(t *testing.T, path string, text string) {
+	FailNowIfFalse(os.WriteFile(path, []byte(text), 0644) == nil, fmt.Sprintf("Could not write %s", path), t)
+}
+
+func TestFileWatcher(t *testing.T) {
+	backends := map[string]func() (FileWatcher, error){
+		"Polling": func() (FileWatcher, error) { return createPollingWatcher(20 * time.Millisecond), nil },
+		"Native":  createNativeWatcher,
+	}
+
+	for name, create := range backends {
+		t.Run(name, func(t *testing.T) {
+			watcher, err := create()
+			if err != nil {
+				t.Skipf("%s watcher is not available: %s", name, err)
+			}
+			defer watcher.Close()
+
+			dir := t.TempDir()
+			path := filepath.Join(dir, "watched.txt")
+			writeWatchedFile(t, path, "one")
+			watcher.WatchFile(path)
+
+			writeWatchedFile(t, path, "one two")
+			expectEvent(t, watcher, path, WatchEvent_Modified, "Expected the file to be modified")
+
+			// Saving atomically renames a new file over the watched one
+			temp := filepath.Join(dir, "watched.txt.tmp")
+			writeWatchedFile(t, temp, "one two three")
+			FailNowIfFalse(os.Rename(temp, path) == nil, "Could not rename the file", t)
+			expectEvent(t, watcher, path, WatchEvent_Modified, "Expected the renamed file to be modified")
+
+			FailNowIfFalse(os.Remove(path) == nil, "Could not remove the file", t)
+			expectEvent(t, watcher, path, WatchEvent_Removed, "Expected the file to be removed")
+
+			root := filepath.Join(dir, "tree")
+			FailNowIfFalse(os.MkdirAll(filepath.Join(root, "skipped"), 0755) == nil, "Could not create the tree", t)
+			watcher.WatchTree(root, func(path string) bool { return filepath.Base(path) == "skipped" })
+
+			created := filepath.Join(root, "sub", "new.txt")
+			FailNowIfFalse(os.MkdirAll(filepath.Dir(created), 0755) == nil, "Could not create the directory", t)
+			writeWatchedFile(t, created, "new")
+			expectEvent(t, watcher, created, WatchEvent_Created, "Expected the file in the new directory to be created")
+
+			skipped := filepath.Join(root, "skipped", "file.txt")
+			writeWatchedFile(t, skipped, "skipped")
+			FailNowIfFalse(os.Remove(created) == nil, "Could not remove the file", t)
+			for _, event := range expectEvent(t, watcher, created, WatchEvent_Removed, "Expected the file in the tree to be removed") {
+				FailIfFalse(event.Path != skipped, "Expected no events from excluded directories", t)
+			}
+		})
+	}
+}
+
+type fakeWatcher struct {
+	events chan WatchEvent
+}
+
+func (watcher *fakeWatcher) WatchFile(path string)                                 {}
+func (watcher *fakeWatcher) UnwatchFile(path string)                               {}
+func (watcher *fakeWatcher) WatchTree(root string, exclude func(path string) bool) {}
+func (watcher *fakeWatcher) Events() <-chan WatchEvent                             { return watcher.events }
+func (watcher *fakeWatcher) Close()                                                {}
+
+func TestWatcherOverflow(t *testing.T) {
+	root := createFixture(t, map[string]string{"main.go": "package main"})
+	path := filepath.Join(root, "main.go")
+
+	fakeFont := GetFakeFont()
+	watcher := &fakeWatcher{events: make(chan WatchEvent, 1)}
+	app := App{Watcher: watcher, Buffer: CreateBuffer(16, &fakeFont, sdl.Rect{W: 800, H: 600})}
+	app.Project, _ = ParseProject("", filepath.Join(root, "test.aproject"))
+	defer app.Project.Close()
+	app.Project.WaitForIndex()
+	app.openSourceFile(path)
+
+	writeWatchedFile(t, path, "package changed")
+	writeWatchedFile(t, filepath.Join(root, "new.go"), "package main")
+
+	watcher.events <- WatchEvent{Type: WatchEvent_Overflow}
+	app.handleWatchEvents()
+	app.Project.WaitForIndex()
+
+	FailIfFalse(app.Buffer.LineText(0) == "package changed", fmt.Sprintf("Expected the open file to be read again, got %q", app.Buffer.LineText(0)), t)
+	FailIfFalse(len(app.Project.Files) == 2, fmt.Sprintf("Expected the index to be built again, got %v", app.Project.Files), t)
+}
@@ -0,0 +1,200 @@
+package main
+
+import "sort"
+
+// A wrapped line is drawn over several display rows, one for every segment. Rows after the first are moved right by the
+// indentation of the line, so wrapped code stays readable
+
+type WrapSegment struct {
+	Start  int32 // Columns of the line the segment covers, the end is not included
+	End    int32
+	Indent int32 // Columns the segment is drawn to the right by
+}
+
+// WrapLine splits the line into segments no wider than width columns, breaking after spaces where it can.
+// A width of 0 or less leaves the line in one segment
+func WrapLine(line string, width int32) (result []WrapSegment) {
+	size := int32(len(line))
+	if width <= 0 || size <= width {
+		return []WrapSegment{{Start: 0, End: size}}
+	}
+
+	indent := int32(0)
+	for indent < size && line[indent] == ' ' {
+		indent += 1
+	}
+
+	// Deeply indented lines would have no room left on the continuation rows
+	if indent > width/2 {
+		indent = 0
+	}
+
+	start := int32(0)
+	segmentIndent := int32(0)
+	for {
+		available := width - segmentIndent
+		if size-start <= available {
+			result = append(result, WrapSegment{Start: start, End: size, Indent: segmentIndent})
+			return
+		}
+
+		end := start + available
+		for breakAt := end; breakAt > start+1; breakAt -= 1 {
+			if line[breakAt-1] == ' ' && line[breakAt] != ' ' {
+				end = breakAt
+				break
+			}
+		}
+
+		result = append(result, WrapSegment{Start: start, End: end, Indent: segmentIndent})
+		start = end
+		segmentIndent = indent
+	}
+}
+
+// WrapRows remembers the display row every line starts on while lines are wrapped, so finding the row of a line doesn't
+// split every line above it again. Starts are worked out as they are needed, edits forget the starts after the edited line
+// and a change to the wrap width forgets all of them
+type WrapRows struct {
+	Width  int32
+	Starts []int32 // Starts[i] is the row line i starts on, known for the lines in the list
+}
+
+// Forget drops the starts of the lines after the line, whose rows may have changed
+func (rows *WrapRows) Forget(line int32) {
+	keep := Max(int(line)+1, 1)
+	if len(rows.Starts) > keep {
+		rows.Starts = rows.Starts[:keep]
+	}
+}
+
+// wrapWidth returns the column lines are wrapped at, 0 when they are not wrapped
+func (buffer *Buffer) wrapWidth() int32 {
+	if !buffer.Wrap {
+		return 0
+	}
+
+	if buffer.WrapColumn > 0 {
+		return buffer.WrapColumn
+	}
+
+	return buffer.textColumns()
+}
+
+func (buffer *Buffer) lineSegments(line int32) []WrapSegment {
+	width := buffer.wrapWidth()
+
+	// Lines that fit are not read at all
+	size := buffer.lineSize(line)
+	if width == 0 || size <= width {
+		return []WrapSegment{{Start: 0, End: size}}
+	}
+
+	return WrapLine(buffer.LineText(line), width)
+}
+
+// displayRow returns the display row the first segment of the line is drawn on
+func (buffer *Buffer) displayRow(line int32) int32 {
+	if buffer.wrapWidth() == 0 {
+		return line
+	}
+
+	buffer.knowRowsUntil(func(last int32, start int32) bool { return last >= line })
+	return buffer.Rows.Starts[line]
+}
+
+// lineAtRow returns the line drawn on the display row and the display row the line starts on. Rows past the end give
+// the line after the last one
+func (buffer *Buffer) lineAtRow(row int32) (line int32, lineRow int32) {
+	if buffer.wrapWidth() == 0 {
+		return row, row
+	}
+
+	total := int32(buffer.TotalLines)
+	buffer.knowRowsUntil(func(last int32, start int32) bool { return last >= total || start > row })
+
+	starts := buffer.Rows.Starts[:Min(len(buffer.Rows.Starts), buffer.TotalLines+1)]
+	line = int32(Max(sort.Search(len(starts), func(index int) bool { return starts[index] > row })-1, 0))
+
+	return line, starts[line]
+}
+
+// knowRowsUntil works out the starts of the lines after the known ones until done is true for the last known line and
+// its start. The line after the last line is known too, it starts where the text ends
+func (buffer *Buffer) knowRowsUntil(done func(last int32, start int32) bool) {
+	rows := &buffer.Rows
+	width := buffer.wrapWidth()
+	if rows.Width != width || len(rows.Starts) == 0 {
+		rows.Width = width
+		rows.Starts = append(rows.Starts[:0], 0)
+	}
+
+	last := int32(len(rows.Starts)) - 1
+	for !done(last, rows.Starts[last]) && last < int32(buffer.TotalLines) {
+		rows.Starts = append(rows.Starts, rows.Starts[last]+int32(len(buffer.lineSegments(last))))
+		last += 1
+	}
+}
+
+func segmentAt(segments []WrapSegment, column int32) (index int) {
+	for index+1 < len(segments) && segments[index+1].Start <= column {
+		index += 1
+	}
+
+	return
+}
+
+// cursorDisplay returns the display row and column the cursor is drawn at
+func (buffer *Buffer) cursorDisplay() (row int32, column int32) {
+	segments := buffer.lineSegments(buffer.Cursor.Line)
+	index := segmentAt(segments, buffer.Cursor.Column)
+	segment := segments[index]
+
+	return buffer.displayRow(buffer.Cursor.Line) + int32(index), buffer.Cursor.Column - segment.Start + segment.Indent
+}
+
+// MoveDownDisplayLine moves to the next display row, which is the next line when lines are not wrapped
+func (buffer *Buffer) MoveDownDisplayLine() {
+	segments := buffer.lineSegments(buffer.Cursor.Line)
+	index := segmentAt(segments, buffer.Cursor.Column)
+
+	if index+1 < len(segments) {
+		buffer.moveToSegment(buffer.Cursor.Line, segments, index+1)
+	} else if buffer.Cursor.Line < int32(buffer.TotalLines)-1 {
+		buffer.moveToSegment(buffer.Cursor.Line+1, buffer.lineSegments(buffer.Cursor.Line+1), 0)
+	}
+
+	buffer.ScrollToCursor()
+}
+
+// MoveUpDisplayLine moves to the previous display row, which is the previous line when lines are not wrapped
+func (buffer *Buffer) MoveUpDisplayLine() {
+	segments := buffer.lineSegments(buffer.Cursor.Line)
+	index := segmentAt(segments, buffer.Cursor.Column)
+
+	if index > 0 {
+		buffer.moveToSegment(buffer.Cursor.Line, segments, index-1)
+	} else if buffer.Cursor.Line > 0 {
+		segments = buffer.lineSegments(buffer.Cursor.Line - 1)
+		buffer.moveToSegment(buffer.Cursor.Line-1, segments, len(segments)-1)
+	}
+
+	buffer.ScrollToCursor()
+}
+
+// moveToSegment keeps the display column the cursor is on, or the one it was on before a shorter row got in the way
+func (buffer *Buffer) moveToSegment(line int32, segments []WrapSegment, index int) {
+	current := buffer.lineSegments(buffer.Cursor.Line)
+	currentSegment := current[segmentAt(current, buffer.Cursor.Column)]
+	endColumn := int32(Max(int(buffer.Cursor.Column-currentSegment.Start+currentSegment.Indent), int(buffer.Cursor.LastColumn)))
+
+	segment := segments[index]
+	last := segment.End
+	if index < len(segments)-1 {
+		last = segment.End - 1 // The end of the segment is already on the next row
+	}
+
+	column := Clamp(int(segment.Start+endColumn-segment.Indent), int(segment.Start), int(last))
+	buffer.moveGapTo(buffer.lineStart(line) + column)
+	buffer.Cursor.LastColumn = endColumn
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/veandco/go-sdl2/sdl"
+)
+
+func TestWrapLine(t *testing.T) {
+	tests := []struct {
+		Name     string
+		Line     string
+		Width    int32
+		Expected []WrapSegment
+	}{
+		{"Short lines are not wrapped", "short", 10, []WrapSegment{{0, 5, 0}}},
+		{"No width", "a long line that is not wrapped", 0, []WrapSegment{{0, 31, 0}}},
+		{"Breaks after words", "one two three four", 10, []WrapSegment{{0, 8, 0}, {8, 18, 0}}},
+		{"Long words are cut", "abcdefghijkl", 5, []WrapSegment{{0, 5, 0}, {5, 10, 0}, {10, 12, 0}}},
+		{"Continuation rows are indented", "    one two three", 10, []WrapSegment{{0, 8, 0}, {8, 12, 4}, {12, 17, 4}}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			result := WrapLine(test.Line, test.Width)
+			FailNowIfFalse(len(result) == len(test.Expected), fmt.Sprintf("Expected %d segments, got %v", len(test.Expected), result), t)
+			for index, segment := range result {
+				FailIfFalse(segment == test.Expected[index], fmt.Sprintf("Expected segment %d to be %v, got %v", index, test.Expected[index], segment), t)
+			}
+		})
+	}
+}
+
+func TestWrappedBuffer(t *testing.T) {
+	app := createTestApp("first\none two three four five\nlast")
+	app.Buffer.Wrap = true
+	app.Buffer.WrapColumn = 10
+
+	t.Run("Lines start on display rows", func(t *testing.T) {
+		FailIfFalse(app.Buffer.displayRow(1) == 1 && app.Buffer.displayRow(2) == 4, "Expected the wrapped line to take three rows", t)
+
+		line, row := app.Buffer.lineAtRow(3)
+		FailIfFalse(line == 1 && row == 1, fmt.Sprintf("Expected row 3 to be on line 1 starting at row 1, got %d and %d", line, row), t)
+	})
+
+	t.Run("gj and gk move by display row", func(t *testing.T) {
+		typeKeys(&app, "jll")
+		typeKeys(&app, "gj")
+		FailIfFalse(app.Buffer.Cursor.Line == 1 && app.Buffer.Cursor.Column == 10, fmt.Sprintf("Expected 1:10, got %d:%d", app.Buffer.Cursor.Line, app.Buffer.Cursor.Column), t)
+
+		row, column := app.Buffer.cursorDisplay()
+		FailIfFalse(row == 2 && column == 2, fmt.Sprintf("Expected the cursor on row 2 column 2, got %d and %d", row, column), t)
+
+		typeKeys(&app, "2gj")
+		FailIfFalse(app.Buffer.Cursor.Line == 2 && app.Buffer.Cursor.Column == 2, fmt.Sprintf("Expected 2:2, got %d:%d", app.Buffer.Cursor.Line, app.Buffer.Cursor.Column), t)
+
+		typeKeys(&app, "gk")
+		FailIfFalse(app.Buffer.Cursor.Line == 1 && app.Buffer.Cursor.Column == 16, fmt.Sprintf("Expected 1:16, got %d:%d", app.Buffer.Cursor.Line, app.Buffer.Cursor.Column), t)
+	})
+
+	t.Run("Segments are drawn on their own rows", func(t *testing.T) {
+		canvas := CreateSoftwareCanvas(800, 600)
+		theme, _ := ParseTheme("./themes/default.atheme")
+		app.Buffer.Render(canvas, Mode_Normal, &theme)
+
+		rows := map[string]int32{}
+		for _, command := range canvas.Commands {
+			if command.Type == DrawCommand_Text {
+				rows[command.Text] = command.Rect.Y / app.Buffer.Cursor.Height
+			}
+		}
+
+		FailIfFalse(rows["one two "] == 1 && rows["three "] == 2 && rows["four five"] == 3 && rows["last"] == 4, fmt.Sprintf("Unexpected rows %v", rows), t)
+	})
+
+	t.Run("Selection follows the segments", func(t *testing.T) {
+		app.Buffer.MoveToPosition(1, 4)
+		app.Buffer.StartSelection()
+		app.Buffer.MoveToPosition(1, 12)
+
+		canvas := CreateSoftwareCanvas(800, 600)
+		app.Buffer.renderSelection(canvas, 0, app.Buffer.GetSelection(), sdl.Color{})
+		FailNowIfFalse(len(canvas.Commands) == 2, fmt.Sprintf("Expected the selection on two rows, got %d rects", len(canvas.Commands)), t)
+
+		width := int32(app.Buffer.Font.CharacterWidth)
+		first, second := canvas.Commands[0].Rect, canvas.Commands[1].Rect
+		FailIfFalse(first.X == 4*width && first.W == 4*width, fmt.Sprintf("Unexpected first rect %v", first), t)
+		FailIfFalse(second.X == 0 && second.W == 4*width && second.Y == 2*app.Buffer.Cursor.Height, fmt.Sprintf("Unexpected second rect %v", second), t)
+	})
+
+	t.Run("Rows follow edits and the width", func(t *testing.T) {
+		app := createTestApp("first\none two three four five\nlast")
+		app.Buffer.Wrap = true
+		app.Buffer.WrapColumn = 10
+
+		expectRows := func(expected []int32, message string) {
+			for line, row := range expected {
+				FailIfFalse(app.Buffer.displayRow(int32(line)) == row, fmt.Sprintf("%s: expected line %d on row %d, got %d", message, line, row, app.Buffer.displayRow(int32(line))), t)
+			}
+		}
+
+		expectRows([]int32{0, 1, 4}, "Before the edit")
+
+		typeKeys(&app, "ggA one two three<Esc>")
+		expectRows([]int32{0, 2, 5}, "After wrapping the first line")
+
+		typeKeys(&app, "jJ")
+		expectRows([]int32{0, 2}, "After joining the lines")
+		line, row := app.Buffer.lineAtRow(5)
+		FailIfFalse(line == 1 && row == 2, fmt.Sprintf("Expected row 5 on line 1 from row 2, got %d and %d", line, row), t)
+		line, _ = app.Buffer.lineAtRow(100)
+		FailIfFalse(line == 2, fmt.Sprintf("Expected rows past the end after the last line, got %d", line), t)
+
+		app.Buffer.WrapColumn = 40
+		expectRows([]int32{0, 1}, "After widening")
+	})
+}